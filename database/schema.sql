@@ -13,6 +13,10 @@
 CREATE SCHEMA IF NOT EXISTS public
 ;
 
+-- Trigram matching for user search (GET /users/search)
+CREATE EXTENSION IF NOT EXISTS pg_trgm
+;
+
 CREATE TABLE public.users (
     id SERIAL PRIMARY KEY,
     username VARCHAR(50) NOT NULL UNIQUE,
@@ -20,10 +24,19 @@ CREATE TABLE public.users (
     nickname VARCHAR(50),
     user_type VARCHAR(50) NOT NULL DEFAULT 'UTYPE_USER',
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    message_count INT DEFAULT 0
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    message_count INT DEFAULT 0,
+    -- Incremented on every update, used for optimistic concurrency (ETag / If-Match)
+    version INT NOT NULL DEFAULT 1,
+    -- Blob key of the largest avatar size; the others are derived from it
+    avatar_key VARCHAR(255)
 )
 ;
 
+CREATE INDEX users_username_trgm_idx ON public.users USING GIN (username gin_trgm_ops);
+CREATE INDEX users_nickname_trgm_idx ON public.users USING GIN (nickname gin_trgm_ops);
+CREATE INDEX users_email_trgm_idx ON public.users USING GIN (email gin_trgm_ops);
+
 CREATE TABLE public.user_types (
     id SERIAL PRIMARY KEY,
     type_key VARCHAR(50) NOT NULL,
@@ -31,6 +44,17 @@ CREATE TABLE public.user_types (
 )
 ;
 
+-- Conversations messages are posted in. Messages that name no conversation are posted in
+-- the first one, General, which is created with the schema.
+CREATE TABLE public.conversations (
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+)
+;
+
+INSERT INTO public.conversations (title) VALUES ('General');
+
 /*
     Uncertain on the best way to handle this, but for now, we'll just create a table that stores messages
     and link them to users via a foreign key. This will allow us to easily query messages by user and 
@@ -43,10 +67,275 @@ CREATE TABLE public.messages (
     id SERIAL PRIMARY KEY,
     user_id INTEGER NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
     content TEXT NOT NULL,
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    -- Set on ephemeral messages, which are hidden once expired and then removed by the retention sweeper
+    expires_at TIMESTAMP WITH TIME ZONE,
+    -- Set on replies to the message they reply to. Replies outlive their parent as ordinary messages.
+    parent_id INTEGER REFERENCES public.messages(id) ON DELETE SET NULL,
+    -- Replies are always in their parent's conversation
+    conversation_id INTEGER NOT NULL DEFAULT 1 REFERENCES public.conversations(id) ON DELETE CASCADE,
+    -- Set while moderation holds the message for review; held messages are hidden
+    held BOOLEAN NOT NULL DEFAULT false,
+    -- Set when a moderator approves a held message, which makes it unread again for readers already past it
+    approved_at TIMESTAMP WITH TIME ZONE
+)
+;
+
+CREATE INDEX messages_conversation_idx ON public.messages (conversation_id, id);
+CREATE INDEX messages_parent_idx ON public.messages (parent_id, id) WHERE parent_id IS NOT NULL;
+
+CREATE INDEX messages_created_at_idx ON public.messages (created_at);
+CREATE INDEX messages_expires_at_idx ON public.messages (expires_at) WHERE expires_at IS NOT NULL;
+
+-- Messages held for review by moderation (GET /moderation/queue), with why each was held.
+-- Approving a message clears its held flag and removes it from the queue; rejecting deletes it.
+CREATE TABLE public.moderation_queue (
+    message_id INTEGER PRIMARY KEY REFERENCES public.messages(id) ON DELETE CASCADE,
+    reasons TEXT[] NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+)
+;
+
+-- Files attached to messages. The files themselves are in blob storage under storage_key.
+-- When a message is deleted its attachments are detached (message_id set to NULL), and a
+-- background job deletes their blobs and rows.
+CREATE TABLE public.message_attachments (
+    id SERIAL PRIMARY KEY,
+    message_id INTEGER REFERENCES public.messages(id) ON DELETE SET NULL,
+    -- Set instead of message_id once the retention sweeper archives the message
+    -- (the foreign key is added with public.archived_messages)
+    archived_message_id INTEGER,
+    storage_key TEXT NOT NULL UNIQUE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+)
+;
+
+CREATE INDEX message_attachments_message_idx ON public.message_attachments (message_id);
+CREATE INDEX message_attachments_detached_idx ON public.message_attachments (id) WHERE message_id IS NULL AND archived_message_id IS NULL;
+
+-- Users @mentioned in messages, resolved by username when the message is created
+CREATE TABLE public.message_mentions (
+    message_id INTEGER NOT NULL REFERENCES public.messages(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    PRIMARY KEY (message_id, user_id)
+)
+;
+
+CREATE INDEX message_mentions_user_idx ON public.message_mentions (user_id, message_id);
+
+-- #tags in messages, lowercased
+CREATE TABLE public.message_tags (
+    message_id INTEGER NOT NULL REFERENCES public.messages(id) ON DELETE CASCADE,
+    tag VARCHAR(64) NOT NULL,
+    PRIMARY KEY (message_id, tag)
+)
+;
+
+CREATE INDEX message_tags_tag_idx ON public.message_tags (tag, message_id);
+
+-- How far each user has read each conversation (POST /conversations/:conversation_id/read).
+-- Unread counts are the messages by others after the marker. One row per user per
+-- conversation, not per message read.
+CREATE TABLE public.message_read_markers (
+    user_id INTEGER NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    conversation_id INTEGER NOT NULL REFERENCES public.conversations(id) ON DELETE CASCADE,
+    last_read_message_id INTEGER NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, conversation_id)
+)
+;
+
+-- One row per user per emoji per message (POST /messages/:message_id/reactions)
+CREATE TABLE public.message_reactions (
+    message_id INTEGER NOT NULL REFERENCES public.messages(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    emoji VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (message_id, user_id, emoji)
+)
+;
+
+CREATE INDEX message_reactions_user_idx ON public.message_reactions (user_id);
+
+/*
+    Message retention (GET /retention-policies). A policy with a NULL user_type and
+    conversation_id is the global default; one naming a user type overrides it for messages by
+    users of that type, and one naming a conversation overrides both for messages in that
+    conversation. Messages older than max_age_days are deleted (purge) or moved to
+    archived_messages (archive) by the retention sweeper. Without any policy messages are kept
+    forever.
+*/
+CREATE TABLE public.retention_policies (
+    id SERIAL PRIMARY KEY,
+    user_type VARCHAR(50),
+    conversation_id INTEGER REFERENCES public.conversations(id) ON DELETE CASCADE,
+    max_age_days INT NOT NULL CHECK (max_age_days > 0),
+    action VARCHAR(10) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (user_type IS NULL OR conversation_id IS NULL),
+    UNIQUE NULLS NOT DISTINCT (user_type, conversation_id)
+)
+;
+
+CREATE TABLE public.archived_messages (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    parent_id INTEGER,
+    conversation_id INTEGER NOT NULL,
+    held BOOLEAN NOT NULL DEFAULT false,
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+)
+;
+
+CREATE INDEX archived_messages_user_idx ON public.archived_messages (user_id);
+
+ALTER TABLE public.message_attachments
+    ADD FOREIGN KEY (archived_message_id) REFERENCES public.archived_messages(id) ON DELETE SET NULL;
+
+CREATE INDEX message_attachments_archived_message_idx ON public.message_attachments (archived_message_id) WHERE archived_message_id IS NOT NULL;
+
+
+/*
+    Audit trail of changes to users, user types and messages (GET /audit).
+    before/after hold only the fields that changed, so a create has no before
+    and a delete has no after. actor_id is not a foreign key so the trail
+    outlives the users it mentions.
+*/
+CREATE TABLE public.audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id INTEGER,
+    action VARCHAR(50) NOT NULL,
+    target_type VARCHAR(50) NOT NULL,
+    target_id INTEGER NOT NULL,
+    before JSONB,
+    after JSONB,
+    ip VARCHAR(45),
+    request_id VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+)
+;
+
+CREATE INDEX audit_events_target_idx ON public.audit_events (target_type, target_id);
+CREATE INDEX audit_events_actor_idx ON public.audit_events (actor_id);
+CREATE INDEX audit_events_created_at_idx ON public.audit_events (created_at);
+
+
+/*
+    Transactional outbox: domain events (user.created, user.updated, message.created) are
+    inserted in the same transaction as the change that caused them. The webhook dispatcher
+    fans each event out to one webhook_deliveries row per matching webhook and marks it
+    dispatched, then delivers those rows with retries.
+*/
+CREATE TABLE public.outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    dispatched_at TIMESTAMP WITH TIME ZONE
+)
+;
+
+CREATE INDEX outbox_events_undispatched_idx ON public.outbox_events (id) WHERE dispatched_at IS NULL;
+
+-- An empty event_types array subscribes to every event type.
+CREATE TABLE public.webhooks (
+    id SERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
 )
 ;
 
+-- status is pending until delivered (succeeded) or out of attempts (dead).
+CREATE TABLE public.webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id INTEGER NOT NULL REFERENCES public.webhooks(id) ON DELETE CASCADE,
+    event_id BIGINT NOT NULL REFERENCES public.outbox_events(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_status_code INT,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (webhook_id, event_id)
+)
+;
+
+CREATE INDEX webhook_deliveries_due_idx ON public.webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_dead_idx ON public.webhook_deliveries (id) WHERE status = 'dead';
+CREATE INDEX webhook_deliveries_webhook_idx ON public.webhook_deliveries (webhook_id, id);
+
+-- One row per delivery attempt, for GET /webhooks/:webhook_id/deliveries.
+CREATE TABLE public.webhook_delivery_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    delivery_id BIGINT NOT NULL REFERENCES public.webhook_deliveries(id) ON DELETE CASCADE,
+    status_code INT,
+    latency_ms INT NOT NULL,
+    error TEXT,
+    attempted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+)
+;
+
+CREATE INDEX webhook_delivery_attempts_delivery_idx ON public.webhook_delivery_attempts (delivery_id);
+
+
+/*
+    Background jobs, run by `server worker`. Workers dequeue with FOR UPDATE SKIP LOCKED so
+    any number can share the table. unique_key deduplicates enqueues, e.g. one row per cron
+    job per scheduled time however many workers are running the scheduler.
+    status is queued -> running -> succeeded, or back to queued for a retry, or failed
+    once max_attempts is reached.
+*/
+CREATE TABLE public.jobs (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(100) NOT NULL,
+    args JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5,
+    run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_at TIMESTAMP WITH TIME ZONE,
+    locked_by VARCHAR(100),
+    last_error TEXT,
+    unique_key VARCHAR(200) UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+)
+;
+
+CREATE INDEX jobs_due_idx ON public.jobs (run_at) WHERE status = 'queued';
+CREATE INDEX jobs_running_idx ON public.jobs (locked_at) WHERE status = 'running';
+
+
+/*
+    Right-to-erasure requests (POST /users/:user_id/erase). A request starts unconfirmed with a
+    short-lived confirmation token, of which only the SHA-256 hash is kept, and the user is only
+    erased once the token is sent back. message_policy is redact or delete. user_id is not a
+    foreign key so the record outlives the user.
+*/
+CREATE TABLE public.erasure_requests (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    message_policy VARCHAR(10) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    requested_by INTEGER,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    confirmed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+)
+;
+
+CREATE INDEX erasure_requests_user_idx ON public.erasure_requests (user_id);
+
 
 /* * * * * * * * * * * * * * * * * * * * * *
  *
@@ -0,0 +1,116 @@
+// Package cache provides a read-through cache with pluggable storage: an
+// in-process LRU (NewLRU) or a Redis-compatible server (NewRedis).
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"hash/fnv"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// generationStripes is how many invalidation counters keys are spread over.
+const generationStripes = 64
+
+// Store is the storage backend of a Cache.
+type Store interface {
+	// Get returns the value for key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Cache stores JSON-encoded values in a Store and counts hits and misses.
+// Hit and miss counts are published through expvar as <name>_hits and <name>_misses.
+type Cache struct {
+	store  Store
+	ttl    time.Duration
+	hits   *expvar.Int
+	misses *expvar.Int
+
+	// generations counts the invalidations of each stripe of keys, so a load
+	// can tell whether its key was invalidated while it ran.
+	generations [generationStripes]atomic.Uint64
+}
+
+// New returns a cache named name that keeps entries in store for ttl.
+func New(name string, store Store, ttl time.Duration) *Cache {
+	return &Cache{
+		store:  store,
+		ttl:    ttl,
+		hits:   counter(name + "_hits"),
+		misses: counter(name + "_misses"),
+	}
+}
+
+// counter returns the expvar.Int registered under name, creating it if needed,
+// so caches can be recreated (e.g. in tests) without expvar panicking.
+func counter(name string) *expvar.Int {
+	if v, ok := expvar.Get(name).(*expvar.Int); ok {
+		return v
+	}
+	return expvar.NewInt(name)
+}
+
+// Stats returns the number of cache hits and misses so far.
+func (c *Cache) Stats() (hits, misses int64) {
+	return c.hits.Value(), c.misses.Value()
+}
+
+// generation returns the invalidation counter of key's stripe.
+func (c *Cache) generation(key string) *atomic.Uint64 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &c.generations[h.Sum32()%generationStripes]
+}
+
+// Invalidate removes keys from the cache. Loads of these keys already under
+// way do not cache their result.
+func (c *Cache) Invalidate(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		c.generation(key).Add(1)
+	}
+	if err := c.store.Delete(ctx, keys...); err != nil {
+		log.Printf("cache: failed to invalidate %v: %v", keys, err)
+	}
+}
+
+// GetOrLoad returns the cached value for key, or calls load and caches its
+// result. Store failures are logged and treated as misses so the cache never
+// makes a lookup fail.
+//
+// A load that may have read data from before a write is not left in the
+// cache once the write's Invalidate has run, however the two interleave.
+func GetOrLoad[T any](ctx context.Context, c *Cache, key string, load func() (T, error)) (T, error) {
+	if data, ok, err := c.store.Get(ctx, key); err != nil {
+		log.Printf("cache: failed to get %s: %v", key, err)
+	} else if ok {
+		var value T
+		if err := json.Unmarshal(data, &value); err == nil {
+			c.hits.Add(1)
+			return value, nil
+		}
+	}
+	c.misses.Add(1)
+
+	// The generation is read before loading: an Invalidate that has not
+	// bumped it by the time the value is stored will delete it itself.
+	generation := c.generation(key)
+	before := generation.Load()
+	value, err := load()
+	if err != nil || generation.Load() != before {
+		return value, err
+	}
+
+	if data, err := json.Marshal(value); err == nil {
+		if err := c.store.Set(ctx, key, data, c.ttl); err != nil {
+			log.Printf("cache: failed to set %s: %v", key, err)
+		} else if generation.Load() != before {
+			c.Invalidate(ctx, key)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type item struct {
+	Name string `json:"name"`
+}
+
+func TestGetOrLoad(t *testing.T) {
+	c := New("test_get_or_load", NewLRU(10), time.Minute)
+	ctx := context.Background()
+
+	loads := 0
+	load := func() (item, error) {
+		loads++
+		return item{Name: "liam"}, nil
+	}
+
+	v, err := GetOrLoad(ctx, c, "user:1", load)
+	assert.NoError(t, err)
+	assert.Equal(t, "liam", v.Name)
+
+	v, _ = GetOrLoad(ctx, c, "user:1", load)
+	assert.Equal(t, "liam", v.Name)
+	assert.Equal(t, 1, loads)
+
+	hits, misses := c.Stats()
+	assert.Equal(t, int64(1), hits)
+	assert.Equal(t, int64(1), misses)
+
+	c.Invalidate(ctx, "user:1")
+	GetOrLoad(ctx, c, "user:1", load)
+	assert.Equal(t, 2, loads)
+}
+
+func TestGetOrLoadDoesNotCacheErrors(t *testing.T) {
+	c := New("test_errors", NewLRU(10), time.Minute)
+	ctx := context.Background()
+
+	_, err := GetOrLoad(ctx, c, "k", func() (item, error) { return item{}, errors.New("db down") })
+	assert.EqualError(t, err, "db down")
+
+	v, err := GetOrLoad(ctx, c, "k", func() (item, error) { return item{Name: "ok"}, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", v.Name)
+}
+
+// racingStore runs beforeSet ahead of each Set, standing in for a write
+// invalidating the key between a load and caching its result.
+type racingStore struct {
+	Store
+	beforeSet func()
+}
+
+func (s racingStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.beforeSet()
+	return s.Store.Set(ctx, key, value, ttl)
+}
+
+func TestGetOrLoadDoesNotCacheLoadsRacingInvalidate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("invalidated during load", func(t *testing.T) {
+		c := New("test_race_load", NewLRU(10), time.Minute)
+		v, err := GetOrLoad(ctx, c, "user:1", func() (item, error) {
+			c.Invalidate(ctx, "user:1")
+			return item{Name: "stale"}, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "stale", v.Name)
+
+		v, _ = GetOrLoad(ctx, c, "user:1", func() (item, error) { return item{Name: "fresh"}, nil })
+		assert.Equal(t, "fresh", v.Name)
+	})
+
+	t.Run("invalidated before set", func(t *testing.T) {
+		var c *Cache
+		invalidate := true
+		c = New("test_race_set", racingStore{Store: NewLRU(10), beforeSet: func() {
+			if invalidate {
+				invalidate = false
+				c.Invalidate(ctx, "user:1")
+			}
+		}}, time.Minute)
+
+		GetOrLoad(ctx, c, "user:1", func() (item, error) { return item{Name: "stale"}, nil })
+
+		v, _ := GetOrLoad(ctx, c, "user:1", func() (item, error) { return item{Name: "fresh"}, nil })
+		assert.Equal(t, "fresh", v.Name)
+	})
+
+	t.Run("other keys are still cached", func(t *testing.T) {
+		c := New("test_race_other", NewLRU(10), time.Minute)
+		GetOrLoad(ctx, c, "user:2", func() (item, error) {
+			c.Invalidate(ctx, "user:1")
+			return item{Name: "liam"}, nil
+		})
+
+		v, _ := GetOrLoad(ctx, c, "user:2", func() (item, error) { return item{Name: "reloaded"}, nil })
+		assert.Equal(t, "liam", v.Name)
+	})
+}
+
+func TestLRUEvictionAndTTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	lru := NewLRU(2)
+	lru.now = func() time.Time { return now }
+
+	lru.Set(ctx, "a", []byte("1"), time.Minute)
+	lru.Set(ctx, "b", []byte("2"), time.Minute)
+	lru.Get(ctx, "a") // b is now least recently used
+	lru.Set(ctx, "c", []byte("3"), time.Minute)
+
+	_, ok, _ := lru.Get(ctx, "b")
+	assert.False(t, ok)
+	_, ok, _ = lru.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, 2, lru.Len())
+
+	now = now.Add(time.Minute)
+	_, ok, _ = lru.Get(ctx, "a")
+	assert.False(t, ok)
+}
+
+func TestRedisStore(t *testing.T) {
+	server := miniredis.RunT(t)
+	store, err := NewRedisFromURL("redis://"+server.Addr(), "test:")
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	assert.NoError(t, store.Set(ctx, "users", []byte("[]"), time.Minute))
+	assert.True(t, server.Exists("test:users"))
+
+	v, ok, err := store.Get(ctx, "users")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "[]", string(v))
+
+	server.FastForward(time.Minute)
+	_, ok, _ = store.Get(ctx, "users")
+	assert.False(t, ok)
+
+	store.Set(ctx, "users", []byte("[]"), time.Minute)
+	assert.NoError(t, store.Delete(ctx, "users", "missing"))
+	_, ok, _ = store.Get(ctx, "users")
+	assert.False(t, ok)
+}
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU is an in-process Store that evicts the least recently used entry once
+// it holds capacity entries. Entries also expire after their TTL.
+type LRU struct {
+	capacity int
+	now      func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // front is most recently used
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewLRU returns an LRU store holding at most capacity entries.
+func NewLRU(capacity int) *LRU {
+	return &LRU{
+		capacity: capacity,
+		now:      time.Now,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+	}
+}
+
+func (l *LRU) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, ok := l.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := elem.Value.(*lruEntry)
+	if !entry.expiresAt.IsZero() && !l.now().Before(entry.expiresAt) {
+		l.remove(elem)
+		return nil, false, nil
+	}
+
+	l.order.MoveToFront(elem)
+	return entry.value, true, nil
+}
+
+func (l *LRU) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = l.now().Add(ttl)
+	}
+
+	if elem, ok := l.entries[key]; ok {
+		elem.Value = &lruEntry{key: key, value: value, expiresAt: expiresAt}
+		l.order.MoveToFront(elem)
+		return nil
+	}
+
+	l.entries[key] = l.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for l.order.Len() > l.capacity {
+		l.remove(l.order.Back())
+	}
+	return nil
+}
+
+func (l *LRU) Delete(ctx context.Context, keys ...string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		if elem, ok := l.entries[key]; ok {
+			l.remove(elem)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of entries, including expired ones not yet evicted.
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.order.Len()
+}
+
+func (l *LRU) remove(elem *list.Element) {
+	l.order.Remove(elem)
+	delete(l.entries, elem.Value.(*lruEntry).key)
+}
@@ -0,0 +1,252 @@
+// Package client is a Go client for the users and messages API.
+//
+// It talks to the /v1 routes and reuses the request and response types from
+// package handlers, so it stays in sync with the server.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"main/handlers"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultBackoff    = 200 * time.Millisecond
+	maxBackoff        = 5 * time.Second
+)
+
+// Client is a typed API client. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithToken sets the bearer token sent in the Authorization header.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithHTTPClient sets the underlying HTTP client (default http.DefaultClient).
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithRetries sets how many times idempotent requests are retried after a
+// network error, 429 or 5xx response, and the initial backoff between attempts.
+// The backoff doubles after every attempt. Use maxRetries 0 to disable retries.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// New creates a client for the API at baseURL (e.g. http://localhost:8080).
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/") + "/v1",
+		httpClient: http.DefaultClient,
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is returned when the server responds with a non-2xx status.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api error %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is an APIError with status 404.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// ListUsers returns all users with their message counts.
+func (c *Client) ListUsers(ctx context.Context) ([]handlers.UserResponse, error) {
+	var resp handlers.GetUsersResponse
+	if err := c.do(ctx, http.MethodGet, "/users", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Users, nil
+}
+
+// GetUser returns a single user.
+func (c *Client) GetUser(ctx context.Context, userID int) (handlers.UserResponse, error) {
+	var resp handlers.UserResponse
+	err := c.do(ctx, http.MethodGet, "/users/"+strconv.Itoa(userID), nil, &resp)
+	return resp, err
+}
+
+// CreateUser creates a user.
+func (c *Client) CreateUser(ctx context.Context, req handlers.CreateUserRequest) (handlers.UserResponse, error) {
+	var resp handlers.UserResponse
+	err := c.do(ctx, http.MethodPost, "/users", req, &resp)
+	return resp, err
+}
+
+// UpdateUser modifies a user. Nil fields are left unchanged, except Nickname
+// which is always sent; an empty nickname removes it.
+func (c *Client) UpdateUser(ctx context.Context, userID int, req handlers.UpdateUserParams) (handlers.UserResponse, error) {
+	var resp handlers.UserResponse
+	err := c.do(ctx, http.MethodPatch, "/users/"+strconv.Itoa(userID), req, &resp)
+	return resp, err
+}
+
+// ListMessages returns all messages.
+func (c *Client) ListMessages(ctx context.Context) ([]handlers.MessageResponse, error) {
+	var resp handlers.GetMessagesResponse
+	if err := c.do(ctx, http.MethodGet, "/messages", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Messages, nil
+}
+
+// CreateMessage posts a message.
+func (c *Client) CreateMessage(ctx context.Context, req handlers.CreateMessageRequest) (handlers.MessageResponse, error) {
+	var resp handlers.MessageResponse
+	err := c.do(ctx, http.MethodPost, "/messages", req, &resp)
+	return resp, err
+}
+
+// ListUserMessages returns a user's messages, newest first.
+func (c *Client) ListUserMessages(ctx context.Context, userID int) ([]handlers.MessageResponse, error) {
+	var resp handlers.GetMessagesResponse
+	if err := c.do(ctx, http.MethodGet, "/users/"+strconv.Itoa(userID)+"/messages", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Messages, nil
+}
+
+// do sends a JSON request and decodes the JSON response into out, retrying
+// idempotent requests on transient failures.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+	}
+
+	retries := 0
+	if isIdempotent(method) {
+		retries = c.maxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, payload)
+		if err == nil && !isRetryableStatus(resp.StatusCode) {
+			defer resp.Body.Close()
+			return decodeResponse(resp, out)
+		}
+		if attempt >= retries || ctx.Err() != nil {
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			return decodeResponse(resp, out)
+		}
+
+		wait := c.backoffFor(attempt)
+		if resp != nil {
+			if retryAfter, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil {
+				wait = min(time.Duration(retryAfter)*time.Second, maxBackoff)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, payload []byte) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// backoffFor returns the exponential backoff with jitter for the given attempt.
+func (c *Client) backoffFor(attempt int) time.Duration {
+	wait := min(c.backoff<<attempt, maxBackoff)
+	if wait <= 0 {
+		return 0
+	}
+	return wait/2 + rand.N(wait/2+1)
+}
+
+func decodeResponse(resp *http.Response, out any) error {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var errResp handlers.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Error == "" {
+			errResp.Error = http.StatusText(resp.StatusCode)
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: errResp.Error}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
@@ -0,0 +1,185 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"main/handlers"
+	"main/moderation"
+	"main/router"
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	server := httptest.NewServer(router.New())
+	t.Cleanup(server.Close)
+	return server
+}
+
+// requireDatabase skips tests that need the real database behind the router.
+func requireDatabase(t *testing.T) {
+	if os.Getenv("DB_CONNECTION_STRING") == "" {
+		t.Skip("DB_CONNECTION_STRING not set")
+	}
+}
+
+func TestValidationErrorsAreDecoded(t *testing.T) {
+	server := newTestServer(t)
+	c := New(server.URL, WithRetries(0, 0))
+
+	_, err := c.CreateUser(context.Background(), handlers.CreateUserRequest{Username: "x"})
+
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Contains(t, apiErr.Message, "Invalid request body")
+
+	_, err = c.UpdateUser(context.Background(), 1, handlers.UpdateUserParams{UserType: stringPtr("UTYPE_ROOT")})
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Contains(t, apiErr.Message, "Invalid user type")
+}
+
+func TestRetriesTransientFailures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	real := router.New()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		real.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithRetries(3, time.Millisecond))
+	err := c.do(context.Background(), http.MethodGet, "/users/abc", nil, nil)
+
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, "Invalid user ID", apiErr.Message)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestDoesNotRetryPost(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithRetries(3, time.Millisecond))
+	_, err := c.CreateMessage(context.Background(), handlers.CreateMessageRequest{UserID: 1, Content: "hi"})
+
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestSendsTokenAndHonoursContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	c := New(server.URL, WithToken("secret"), WithRetries(10, time.Second))
+	_, err := c.ListMessages(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestUsersAndMessagesRoundTrip(t *testing.T) {
+	requireDatabase(t)
+	server := newTestServer(t)
+	c := New(server.URL)
+	ctx := context.Background()
+
+	username := "client" + time.Now().Format("150405.000000")
+	user, err := c.CreateUser(ctx, handlers.CreateUserRequest{
+		Username: username,
+		Email:    username + "@example.com",
+		UserType: "UTYPE_USER",
+	})
+	assert.NoError(t, err)
+
+	nickname := "Client Test"
+	updated, err := c.UpdateUser(ctx, user.ID, handlers.UpdateUserParams{Nickname: &nickname})
+	assert.NoError(t, err)
+	assert.Equal(t, &nickname, updated.Nickname)
+
+	_, err = c.CreateMessage(ctx, handlers.CreateMessageRequest{UserID: user.ID, Content: "hello from the client"})
+	assert.NoError(t, err)
+
+	fetched, err := c.GetUser(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), fetched.MessageCount)
+
+	messages, err := c.ListUserMessages(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+
+	users, err := c.ListUsers(ctx)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, users)
+
+	_, err = c.GetUser(ctx, -1)
+	assert.True(t, IsNotFound(err))
+}
+
+func TestHeldMessageDoesNotChangeUser(t *testing.T) {
+	requireDatabase(t)
+	service.ConfigureModeration(moderation.Chain{moderation.NewWordList([]string{"holdme"}, moderation.Hold)})
+	t.Cleanup(func() { service.ConfigureModeration(nil) })
+	server := newTestServer(t)
+	c := New(server.URL)
+	ctx := context.Background()
+
+	username := "held" + time.Now().Format("150405.000000")
+	user, err := c.CreateUser(ctx, handlers.CreateUserRequest{
+		Username: username,
+		Email:    username + "@example.com",
+		UserType: "UTYPE_USER",
+	})
+	assert.NoError(t, err)
+	_, err = c.CreateMessage(ctx, handlers.CreateMessageRequest{UserID: user.ID, Content: "visible"})
+	assert.NoError(t, err)
+	before, err := c.GetUser(ctx, user.ID)
+	assert.NoError(t, err)
+
+	held, err := c.CreateMessage(ctx, handlers.CreateMessageRequest{UserID: user.ID, Content: "please holdme"})
+	assert.NoError(t, err)
+	assert.True(t, held.Held)
+
+	after, err := c.GetUser(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+	assert.Equal(t, int32(1), after.MessageCount)
+
+	users, err := c.ListUsers(ctx)
+	assert.NoError(t, err)
+	for _, listed := range users {
+		if listed.ID == user.ID {
+			assert.Equal(t, int32(1), listed.MessageCount)
+		}
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
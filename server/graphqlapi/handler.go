@@ -0,0 +1,30 @@
+// Package graphqlapi serves the users and messages API over GraphQL, with
+// per-request dataloaders so nested fields are batched instead of N+1 queries.
+package graphqlapi
+
+import (
+	_ "embed"
+
+	"github.com/gin-gonic/gin"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// Handler serves GraphQL POST requests.
+func Handler() gin.HandlerFunc {
+	return newHandler(queriesSource)
+}
+
+func newHandler(src dataSource) gin.HandlerFunc {
+	schema := graphql.MustParseSchema(schemaSDL, &resolver{src: src})
+	h := &relay.Handler{Schema: schema}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		ctx = withLoaders(ctx, newLoaders(ctx, src))
+		h.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+	}
+}
@@ -0,0 +1,162 @@
+package graphqlapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"main/queries"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSource struct {
+	users    []queries.GetUsersQueryRow
+	messages []queries.GetMessagesQueryRow
+
+	usersByIDsCalls atomic.Int32
+	messageCalls    atomic.Int32
+}
+
+func (f *fakeSource) dataSource() dataSource {
+	return dataSource{
+		usersPage: func(ctx context.Context, limit, offset int) ([]queries.GetUsersQueryRow, error) {
+			end := min(offset+limit, len(f.users))
+			return f.users[min(offset, end):end], nil
+		},
+		usersByIDs: func(ctx context.Context, userIDs []int) ([]queries.GetUsersQueryRow, error) {
+			f.usersByIDsCalls.Add(1)
+			var rows []queries.GetUsersQueryRow
+			for _, u := range f.users {
+				for _, id := range userIDs {
+					if u.ID == id {
+						rows = append(rows, u)
+					}
+				}
+			}
+			return rows, nil
+		},
+		messagesPage: func(ctx context.Context, limit, offset int) ([]queries.GetMessagesQueryRow, error) {
+			return f.messages, nil
+		},
+		latestMessagesByUsers: func(ctx context.Context, userIDs []int, limit, offset int) ([]queries.GetMessagesQueryRow, error) {
+			f.messageCalls.Add(1)
+			counts := map[int]int{}
+			var rows []queries.GetMessagesQueryRow
+			for _, m := range f.messages {
+				for _, id := range userIDs {
+					if m.UserID == id && counts[id] < limit {
+						counts[id]++
+						rows = append(rows, m)
+					}
+				}
+			}
+			return rows, nil
+		},
+	}
+}
+
+func newFakeSource() *fakeSource {
+	created := pgtype.Timestamptz{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	return &fakeSource{
+		users: []queries.GetUsersQueryRow{
+			{ID: 1, Username: "Liam", UserType: "UTYPE_ADMIN", Nickname: pgtype.Text{String: "L dawg", Valid: true}},
+			{ID: 2, Username: "Jon", UserType: "UTYPE_USER"},
+			{ID: 3, Username: "Myles", UserType: "UTYPE_USER"},
+		},
+		messages: []queries.GetMessagesQueryRow{
+			{ID: 4, UserID: 2, Content: "Another message from Jon.", CreatedAt: created},
+			{ID: 2, UserID: 2, Content: "Hi, I am Jon.", CreatedAt: created},
+			{ID: 1, UserID: 1, Content: "Hello, this is Liam!", CreatedAt: created},
+		},
+	}
+}
+
+func execute(t *testing.T, src dataSource, query string) map[string]any {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/graphql", newHandler(src))
+
+	body, _ := json.Marshal(map[string]any{"query": query})
+	req, _ := http.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func TestUsersWithMessagesAreBatched(t *testing.T) {
+	fake := newFakeSource()
+	resp := execute(t, fake.dataSource(), `{
+		users {
+			username
+			nickname
+			userType
+			messages(limit: 1) { content user { username } }
+		}
+	}`)
+
+	assert.Nil(t, resp["errors"])
+	users := resp["data"].(map[string]any)["users"].([]any)
+	assert.Len(t, users, 3)
+
+	jon := users[1].(map[string]any)
+	assert.Nil(t, jon["nickname"])
+	assert.Equal(t, []any{map[string]any{
+		"content": "Another message from Jon.",
+		"user":    map[string]any{"username": "Jon"},
+	}}, jon["messages"])
+
+	// One query for all users' messages; authors are already known.
+	assert.Equal(t, int32(1), fake.messageCalls.Load())
+	assert.Equal(t, int32(0), fake.usersByIDsCalls.Load())
+}
+
+func TestMessageAuthorsAreBatched(t *testing.T) {
+	fake := newFakeSource()
+	resp := execute(t, fake.dataSource(), `{ messages { id user { username } } }`)
+
+	assert.Nil(t, resp["errors"])
+	assert.Len(t, resp["data"].(map[string]any)["messages"], 3)
+	assert.Equal(t, int32(1), fake.usersByIDsCalls.Load())
+}
+
+func TestMutationValidation(t *testing.T) {
+	resp := execute(t, newFakeSource().dataSource(), `mutation {
+		createUser(input: {username: "", email: "a@example.com", userType: UTYPE_USER}) { id }
+	}`)
+
+	errs := resp["errors"].([]any)
+	assert.Equal(t, "Username is required", errs[0].(map[string]any)["message"])
+
+	resp = execute(t, newFakeSource().dataSource(), `mutation {
+		createMessage(input: {userId: 1, content: " "}) { id }
+	}`)
+	errs = resp["errors"].([]any)
+	assert.Equal(t, "Content is required", errs[0].(map[string]any)["message"])
+}
+
+func TestUserAvatarURL(t *testing.T) {
+	fake := newFakeSource()
+	fake.users[0].AvatarKey = pgtype.Text{String: "avatars/1/0123456789abcdef0123456789abcdef.png", Valid: true}
+	resp := execute(t, fake.dataSource(), `{ users { avatarUrl small: avatarUrl(size: 40) huge: avatarUrl(size: 1000) } }`)
+
+	assert.Nil(t, resp["errors"])
+	users := resp["data"].(map[string]any)["users"].([]any)
+	assert.Equal(t, map[string]any{
+		"avatarUrl": "/avatars/1/0123456789abcdef0123456789abcdef.png",
+		"small":     "/avatars/1/0123456789abcdef0123456789abcdef_48.png",
+		"huge":      "/avatars/1/0123456789abcdef0123456789abcdef.png",
+	}, users[0])
+	assert.Equal(t, map[string]any{"avatarUrl": nil, "small": nil, "huge": nil}, users[1])
+}
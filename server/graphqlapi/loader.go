@@ -0,0 +1,73 @@
+package graphqlapi
+
+import "sync"
+
+// Loader batches lookups by key. Keys announced with Expect are fetched
+// together with the first Load that misses the cache, so resolving a field on
+// every item of a list costs one query instead of one per item.
+type Loader[K comparable, V any] struct {
+	fetch func(keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending map[K]struct{}
+	cache   map[K]V
+}
+
+func NewLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:   fetch,
+		pending: map[K]struct{}{},
+		cache:   map[K]V{},
+	}
+}
+
+// Expect announces keys that are likely to be loaded soon.
+func (l *Loader[K, V]) Expect(keys ...K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		if _, ok := l.cache[key]; !ok {
+			l.pending[key] = struct{}{}
+		}
+	}
+}
+
+// Prime stores an already known value so it is never fetched.
+func (l *Loader[K, V]) Prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cache[key] = value
+	delete(l.pending, key)
+}
+
+// Load returns the value for key, fetching it along with all pending keys on
+// a cache miss. Keys missing from the fetch result load as the zero value.
+func (l *Loader[K, V]) Load(key K) (V, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if value, ok := l.cache[key]; ok {
+		return value, nil
+	}
+
+	l.pending[key] = struct{}{}
+	keys := make([]K, 0, len(l.pending))
+	for k := range l.pending {
+		keys = append(keys, k)
+	}
+
+	values, err := l.fetch(keys)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+
+	for _, k := range keys {
+		l.cache[k] = values[k]
+	}
+	clear(l.pending)
+
+	return l.cache[key], nil
+}
@@ -0,0 +1,55 @@
+package graphqlapi
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoaderBatchesExpectedKeys(t *testing.T) {
+	var batches [][]int
+	loader := NewLoader(func(keys []int) (map[int]string, error) {
+		batches = append(batches, keys)
+		values := map[int]string{}
+		for _, k := range keys {
+			if k != 3 {
+				values[k] = "v" + string(rune('0'+k))
+			}
+		}
+		return values, nil
+	})
+
+	loader.Expect(1, 2, 3)
+	loader.Prime(4, "primed")
+
+	v, err := loader.Load(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", v)
+
+	v, _ = loader.Load(2)
+	assert.Equal(t, "v2", v)
+	v, _ = loader.Load(3)
+	assert.Equal(t, "", v)
+	v, _ = loader.Load(4)
+	assert.Equal(t, "primed", v)
+
+	assert.Len(t, batches, 1)
+	assert.ElementsMatch(t, []int{1, 2, 3}, batches[0])
+}
+
+func TestLoaderError(t *testing.T) {
+	calls := 0
+	loader := NewLoader(func(keys []int) (map[int]string, error) {
+		calls++
+		return nil, errors.New("boom")
+	})
+
+	_, err := loader.Load(1)
+	assert.EqualError(t, err, "boom")
+
+	// Failed keys are retried on the next load.
+	_, err = loader.Load(1)
+	assert.Error(t, err)
+	assert.Equal(t, 2, calls)
+}
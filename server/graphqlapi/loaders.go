@@ -0,0 +1,115 @@
+package graphqlapi
+
+import (
+	"context"
+	"sync"
+
+	"main/queries"
+)
+
+// dataSource is the part of the queries layer used by the resolvers.
+type dataSource struct {
+	usersPage             func(ctx context.Context, limit, offset int) ([]queries.GetUsersQueryRow, error)
+	usersByIDs            func(ctx context.Context, userIDs []int) ([]queries.GetUsersQueryRow, error)
+	messagesPage          func(ctx context.Context, limit, offset int) ([]queries.GetMessagesQueryRow, error)
+	latestMessagesByUsers func(ctx context.Context, userIDs []int, limit, offset int) ([]queries.GetMessagesQueryRow, error)
+}
+
+var queriesSource = dataSource{
+	usersPage:             queries.GetUsersPage,
+	usersByIDs:            queries.GetUsersByIDs,
+	messagesPage:          queries.GetMessagesPage,
+	latestMessagesByUsers: queries.GetLatestMessagesByUsers,
+}
+
+type pageArgs struct {
+	limit, offset int
+}
+
+// loaders holds the per-request dataloaders. Every user that reaches a
+// resolver is announced to the message loaders, and every message's author to
+// the user loader, so nested fields are fetched one level at a time. Batches
+// are loaded with the request's context.
+type loaders struct {
+	ctx   context.Context
+	src   dataSource
+	users *Loader[int, *queries.GetUsersQueryRow]
+
+	mu       sync.Mutex
+	userIDs  []int
+	messages map[pageArgs]*Loader[int, []queries.GetMessagesQueryRow]
+}
+
+func newLoaders(ctx context.Context, src dataSource) *loaders {
+	l := &loaders{
+		ctx:      ctx,
+		src:      src,
+		messages: map[pageArgs]*Loader[int, []queries.GetMessagesQueryRow]{},
+	}
+	l.users = NewLoader(func(userIDs []int) (map[int]*queries.GetUsersQueryRow, error) {
+		rows, err := src.usersByIDs(ctx, userIDs)
+		if err != nil {
+			return nil, err
+		}
+		users := make(map[int]*queries.GetUsersQueryRow, len(rows))
+		for i := range rows {
+			users[rows[i].ID] = &rows[i]
+		}
+		return users, nil
+	})
+	return l
+}
+
+// sawUser records a resolved user so its messages are batched with the others.
+func (l *loaders) sawUser(user queries.GetUsersQueryRow) {
+	l.users.Prime(user.ID, &user)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.userIDs = append(l.userIDs, user.ID)
+	for _, loader := range l.messages {
+		loader.Expect(user.ID)
+	}
+}
+
+// sawMessage records a resolved message so its author is batched with the others.
+func (l *loaders) sawMessage(message queries.GetMessagesQueryRow) {
+	l.users.Expect(message.UserID)
+}
+
+// messagesByUser returns the loader for users' latest messages with the given page.
+func (l *loaders) messagesByUser(limit, offset int) *Loader[int, []queries.GetMessagesQueryRow] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	args := pageArgs{limit, offset}
+	if loader, ok := l.messages[args]; ok {
+		return loader
+	}
+
+	loader := NewLoader(func(userIDs []int) (map[int][]queries.GetMessagesQueryRow, error) {
+		rows, err := l.src.latestMessagesByUsers(l.ctx, userIDs, limit, offset)
+		if err != nil {
+			return nil, err
+		}
+		messages := make(map[int][]queries.GetMessagesQueryRow, len(userIDs))
+		for _, row := range rows {
+			messages[row.UserID] = append(messages[row.UserID], row)
+		}
+		return messages, nil
+	})
+	loader.Expect(l.userIDs...)
+	l.messages[args] = loader
+	return loader
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
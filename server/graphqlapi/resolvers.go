@@ -0,0 +1,252 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"main/queries"
+	"main/service"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const maxPageSize = 100
+
+type pageInput struct {
+	Limit  int32
+	Offset int32
+}
+
+// page clamps the pagination arguments to sane bounds.
+func (p pageInput) page() (limit, offset int) {
+	return min(max(int(p.Limit), 1), maxPageSize), max(int(p.Offset), 0)
+}
+
+type resolver struct {
+	src dataSource
+}
+
+func (r *resolver) Users(ctx context.Context, args pageInput) ([]*userResolver, error) {
+	limit, offset := args.page()
+	rows, err := r.src.usersPage(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return newUserResolvers(ctx, rows), nil
+}
+
+func (r *resolver) User(ctx context.Context, args struct{ ID int32 }) (*userResolver, error) {
+	row, err := loadersFrom(ctx).users.Load(int(args.ID))
+	if err != nil || row == nil {
+		return nil, err
+	}
+	return newUserResolver(ctx, *row), nil
+}
+
+func (r *resolver) Messages(ctx context.Context, args pageInput) ([]*messageResolver, error) {
+	limit, offset := args.page()
+	rows, err := r.src.messagesPage(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return newMessageResolvers(ctx, rows), nil
+}
+
+type createUserInput struct {
+	Username string
+	Email    string
+	UserType string
+	Nickname *string
+}
+
+func (r *resolver) CreateUser(ctx context.Context, args struct{ Input createUserInput }) (*userResolver, error) {
+	row, err := service.CreateUser(ctx, queries.CreateUserParams{
+		Username: args.Input.Username,
+		Email:    args.Input.Email,
+		UserType: args.Input.UserType,
+		Nickname: args.Input.Nickname,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newUserResolver(ctx, row), nil
+}
+
+type updateUserInput struct {
+	Username *string
+	Email    *string
+	UserType *string
+	Nickname graphql.NullString
+}
+
+func (r *resolver) UpdateUser(ctx context.Context, args struct {
+	ID              int32
+	Input           updateUserInput
+	ExpectedVersion *int32
+}) (*userResolver, error) {
+	params := queries.UpdateUserParams{
+		Username: args.Input.Username,
+		Email:    args.Input.Email,
+		UserType: args.Input.UserType,
+	}
+	if args.ExpectedVersion != nil {
+		params.MatchVersions = []int32{*args.ExpectedVersion}
+	}
+	if args.Input.Nickname.Set {
+		// An empty nickname removes it, so null maps to "".
+		nickname := ""
+		if args.Input.Nickname.Value != nil {
+			nickname = *args.Input.Nickname.Value
+		}
+		params.Nickname = &nickname
+	}
+
+	row, err := service.UpdateUser(ctx, int(args.ID), params)
+	if err != nil {
+		return nil, err
+	}
+	return newUserResolver(ctx, row), nil
+}
+
+type createMessageInput struct {
+	UserID         int32
+	Content        string
+	ExpiresAt      *string
+	ParentID       *int32
+	ConversationID *int32
+}
+
+func (r *resolver) CreateMessage(ctx context.Context, args struct{ Input createMessageInput }) (*messageResolver, error) {
+	params := queries.CreateMessageParams{
+		UserID:  int(args.Input.UserID),
+		Content: args.Input.Content,
+	}
+	if args.Input.ExpiresAt != nil {
+		expiresAt, err := time.Parse(time.RFC3339, *args.Input.ExpiresAt)
+		if err != nil {
+			return nil, errors.New("Invalid expiresAt: must be an RFC 3339 timestamp")
+		}
+		params.ExpiresAt = pgtype.Timestamptz{Time: expiresAt, Valid: true}
+	}
+	if args.Input.ParentID != nil {
+		parentID := int(*args.Input.ParentID)
+		params.ParentID = &parentID
+	}
+	if args.Input.ConversationID != nil {
+		if *args.Input.ConversationID <= 0 {
+			return nil, errors.New("Invalid conversationId")
+		}
+		params.ConversationID = int(*args.Input.ConversationID)
+	}
+
+	row, err := service.CreateMessage(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return newMessageResolver(ctx, row), nil
+}
+
+type userResolver struct {
+	row queries.GetUsersQueryRow
+}
+
+func newUserResolver(ctx context.Context, row queries.GetUsersQueryRow) *userResolver {
+	loadersFrom(ctx).sawUser(row)
+	return &userResolver{row: row}
+}
+
+func newUserResolvers(ctx context.Context, rows []queries.GetUsersQueryRow) []*userResolver {
+	users := make([]*userResolver, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, newUserResolver(ctx, row))
+	}
+	return users
+}
+
+func (u *userResolver) ID() int32           { return int32(u.row.ID) }
+func (u *userResolver) Username() string    { return u.row.Username }
+func (u *userResolver) Email() string       { return u.row.Email }
+func (u *userResolver) UserType() string    { return u.row.UserType }
+func (u *userResolver) MessageCount() int32 { return u.row.MessageCount }
+func (u *userResolver) Version() int32      { return u.row.Version }
+
+func (u *userResolver) Nickname() *string {
+	if !u.row.Nickname.Valid {
+		return nil
+	}
+	return &u.row.Nickname.String
+}
+
+func (u *userResolver) AvatarURL(args struct{ Size int32 }) *string {
+	if !u.row.AvatarKey.Valid {
+		return nil
+	}
+	size := service.AvatarSizes[0]
+	for _, candidate := range service.AvatarSizes {
+		if candidate >= int(args.Size) {
+			size = candidate
+		}
+	}
+	url := service.AvatarURL(u.row.AvatarKey.String, size)
+	return &url
+}
+
+func (u *userResolver) Messages(ctx context.Context, args pageInput) ([]*messageResolver, error) {
+	rows, err := loadersFrom(ctx).messagesByUser(args.page()).Load(u.row.ID)
+	if err != nil {
+		return nil, err
+	}
+	return newMessageResolvers(ctx, rows), nil
+}
+
+type messageResolver struct {
+	row queries.GetMessagesQueryRow
+}
+
+func newMessageResolver(ctx context.Context, row queries.GetMessagesQueryRow) *messageResolver {
+	loadersFrom(ctx).sawMessage(row)
+	return &messageResolver{row: row}
+}
+
+func newMessageResolvers(ctx context.Context, rows []queries.GetMessagesQueryRow) []*messageResolver {
+	messages := make([]*messageResolver, 0, len(rows))
+	for _, row := range rows {
+		messages = append(messages, newMessageResolver(ctx, row))
+	}
+	return messages
+}
+
+func (m *messageResolver) ID() int32       { return int32(m.row.ID) }
+func (m *messageResolver) UserID() int32   { return int32(m.row.UserID) }
+func (m *messageResolver) Content() string { return m.row.Content }
+
+func (m *messageResolver) CreatedAt() string {
+	return m.row.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00")
+}
+
+func (m *messageResolver) ExpiresAt() *string {
+	if !m.row.ExpiresAt.Valid {
+		return nil
+	}
+	expiresAt := m.row.ExpiresAt.Time.Format("2006-01-02T15:04:05Z07:00")
+	return &expiresAt
+}
+
+func (m *messageResolver) ParentID() *int32 {
+	if !m.row.ParentID.Valid {
+		return nil
+	}
+	return &m.row.ParentID.Int32
+}
+
+func (m *messageResolver) ConversationID() int32 { return int32(m.row.ConversationID) }
+
+func (m *messageResolver) User(ctx context.Context) (*userResolver, error) {
+	row, err := loadersFrom(ctx).users.Load(m.row.UserID)
+	if err != nil || row == nil {
+		return nil, err
+	}
+	return newUserResolver(ctx, *row), nil
+}
@@ -0,0 +1,54 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"main/service"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys mirroring the REST X-User-ID and X-Request-ID headers.
+const (
+	userIDKey    = "x-user-id"
+	requestIDKey = "x-request-id"
+)
+
+// actorInterceptor attaches the service.Actor for a call (user and request ID
+// from metadata, client IP from the peer) to its context, for the audit log.
+func actorInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	actor, err := actorFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(service.WithActor(ctx, actor), req)
+}
+
+func actorFrom(ctx context.Context) (service.Actor, error) {
+	var actor service.Actor
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		actor.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(actor.IP); err == nil {
+			actor.IP = host
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(requestIDKey); len(values) > 0 {
+		actor.RequestID = values[0]
+	}
+	if values := md.Get(userIDKey); len(values) > 0 {
+		userID, err := strconv.Atoi(values[0])
+		if err != nil || userID <= 0 {
+			return service.Actor{}, status.Error(codes.InvalidArgument, "Invalid "+userIDKey+" metadata")
+		}
+		actor.UserID = userID
+	}
+	return actor, nil
+}
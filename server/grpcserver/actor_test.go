@@ -0,0 +1,31 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"main/service"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestActorFrom(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5000}})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(userIDKey, "3", requestIDKey, "req-1"))
+
+	actor, err := actorFrom(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, service.Actor{UserID: 3, IP: "10.0.0.1", RequestID: "req-1"}, actor)
+}
+
+func TestActorFromInvalidUserID(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(userIDKey, "abc"))
+
+	_, err := actorFrom(ctx)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,99 @@
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	apiv1 "main/proto/api/v1"
+	"main/queries"
+	"main/service"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type messageServer struct {
+	apiv1.UnimplementedMessageServiceServer
+}
+
+func (s *messageServer) ListMessages(ctx context.Context, req *apiv1.ListMessagesRequest) (*apiv1.ListMessagesResponse, error) {
+	rows, err := service.ListMessages(ctx)
+	if err != nil {
+		return nil, toStatus(err, "Failed to retrieve messages")
+	}
+	return newMessagesResponse(ctx, rows)
+}
+
+func (s *messageServer) ListUserMessages(ctx context.Context, req *apiv1.ListUserMessagesRequest) (*apiv1.ListMessagesResponse, error) {
+	rows, err := service.ListUserMessages(ctx, int(req.GetUserId()))
+	if err != nil {
+		return nil, toStatus(err, "Failed to retrieve messages")
+	}
+	return newMessagesResponse(ctx, rows)
+}
+
+func (s *messageServer) CreateMessage(ctx context.Context, req *apiv1.CreateMessageRequest) (*apiv1.Message, error) {
+	params := queries.CreateMessageParams{
+		UserID:  int(req.GetUserId()),
+		Content: req.GetContent(),
+	}
+	if req.ParentId != nil {
+		parentID := int(req.GetParentId())
+		params.ParentID = &parentID
+	}
+	if req.ConversationId != nil {
+		if req.GetConversationId() <= 0 {
+			return nil, status.Error(codes.InvalidArgument, "Invalid conversation ID")
+		}
+		params.ConversationID = int(req.GetConversationId())
+	}
+	if req.ExpiresAt != nil {
+		expiresAt, err := time.Parse(time.RFC3339, req.GetExpiresAt())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "Invalid expires_at: must be an RFC 3339 timestamp")
+		}
+		params.ExpiresAt = pgtype.Timestamptz{Time: expiresAt, Valid: true}
+	}
+
+	row, err := service.CreateMessage(ctx, params)
+	if err != nil {
+		return nil, toStatus(err, "Failed to create message")
+	}
+	messages, err := newMessages(ctx, []queries.GetMessagesQueryRow{row})
+	if err != nil {
+		// The message was created, so return it without its details.
+		return newMessage(row), nil
+	}
+	return messages[0], nil
+}
+
+// WatchMessages streams messages created after the call starts until the
+// client cancels. Messages are delivered from this server process only.
+func (s *messageServer) WatchMessages(req *apiv1.WatchMessagesRequest, stream grpc.ServerStreamingServer[apiv1.Message]) error {
+	messages, unsubscribe := service.NewMessages.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case message := <-messages:
+			if req.UserId != nil && int32(message.UserID) != req.GetUserId() {
+				continue
+			}
+			if err := stream.Send(newMessage(message)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func newMessagesResponse(ctx context.Context, rows []queries.GetMessagesQueryRow) (*apiv1.ListMessagesResponse, error) {
+	messages, err := newMessages(ctx, rows)
+	if err != nil {
+		return nil, toStatus(err, "Failed to retrieve messages")
+	}
+	return &apiv1.ListMessagesResponse{Messages: messages}, nil
+}
@@ -0,0 +1,142 @@
+// Package grpcserver exposes the users and messages API over gRPC. It calls
+// the same service layer as the REST handlers.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	apiv1 "main/proto/api/v1"
+	"main/queries"
+	"main/service"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// New returns a gRPC server with the user and message services registered.
+// Calls carry the acting user in x-user-id metadata, as REST does in X-User-ID.
+func New(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{grpc.ChainUnaryInterceptor(actorInterceptor)}, opts...)
+	s := grpc.NewServer(opts...)
+	apiv1.RegisterUserServiceServer(s, &userServer{})
+	apiv1.RegisterMessageServiceServer(s, &messageServer{})
+	return s
+}
+
+// toStatus maps service errors to gRPC status errors, mirroring the HTTP
+// statuses used by the REST handlers.
+func toStatus(err error, attempted string) error {
+	var validationErr *service.ValidationError
+	var rejectedErr *service.MessageRejectedError
+	switch {
+	case errors.As(err, &validationErr):
+		return status.Error(codes.InvalidArgument, validationErr.Message)
+	case errors.As(err, &rejectedErr):
+		return status.Error(codes.InvalidArgument, rejectedErr.Error())
+	case errors.Is(err, queries.ErrUserNotFound),
+		errors.Is(err, queries.ErrConversationNotFound),
+		errors.Is(err, queries.ErrMessageNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, queries.ErrVersionMismatch):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.InvalidArgument, attempted+": "+err.Error())
+	}
+}
+
+func newUser(row queries.GetUsersQueryRow) *apiv1.User {
+	user := &apiv1.User{
+		Id:           int32(row.ID),
+		Username:     row.Username,
+		Email:        row.Email,
+		UserType:     row.UserType,
+		MessageCount: row.MessageCount,
+		Version:      row.Version,
+	}
+	if row.Nickname.Valid {
+		user.Nickname = &row.Nickname.String
+	}
+	if row.AvatarKey.Valid {
+		user.AvatarUrl = service.AvatarURL(row.AvatarKey.String, service.AvatarSizes[0])
+		user.AvatarUrls = map[int32]string{}
+		for size, url := range service.AvatarURLs(row.AvatarKey.String) {
+			user.AvatarUrls[int32(size)] = url
+		}
+	}
+	return user
+}
+
+// newMessage converts the fields stored on a message, without its details.
+func newMessage(row queries.GetMessagesQueryRow) *apiv1.Message {
+	message := &apiv1.Message{
+		Id:             int32(row.ID),
+		UserId:         int32(row.UserID),
+		Content:        row.Content,
+		CreatedAt:      row.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		Held:           row.Held,
+		ConversationId: int32(row.ConversationID),
+	}
+	if row.ExpiresAt.Valid {
+		message.ExpiresAt = row.ExpiresAt.Time.Format("2006-01-02T15:04:05Z07:00")
+	}
+	if row.ParentID.Valid {
+		message.ParentId = &row.ParentID.Int32
+	}
+	return message
+}
+
+// newMessages converts rows with their reply summaries, reactions, mentions,
+// tags and attachments, each read in one query for the whole list as the REST
+// handlers do.
+func newMessages(ctx context.Context, rows []queries.GetMessagesQueryRow) ([]*apiv1.Message, error) {
+	messageIDs := make([]int, 0, len(rows))
+	for _, row := range rows {
+		messageIDs = append(messageIDs, row.ID)
+	}
+	details, err := service.LoadMessageDetails(ctx, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*apiv1.Message, 0, len(rows))
+	for _, row := range rows {
+		message := newMessage(row)
+		if summary, ok := details.Replies[row.ID]; ok {
+			message.ReplyCount = summary.ReplyCount
+			message.LastReplyAt = summary.LastReplyAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		}
+		for _, summary := range details.Reactions[row.ID] {
+			message.Reactions = append(message.Reactions, &apiv1.Reaction{
+				Emoji:       summary.Emoji,
+				Count:       summary.Count,
+				ReactedByMe: summary.ReactedByViewer,
+			})
+		}
+		for _, mention := range details.Mentions[row.ID] {
+			message.Mentions = append(message.Mentions, &apiv1.Mention{UserId: int32(mention.UserID), Username: mention.Username})
+		}
+		message.Tags = details.Tags[row.ID]
+		for _, attachment := range details.Attachments[row.ID] {
+			message.Attachments = append(message.Attachments, newAttachment(attachment))
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+func newAttachment(attachment service.Attachment) *apiv1.Attachment {
+	response := &apiv1.Attachment{
+		Id:          int32(attachment.ID),
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		Size:        attachment.SizeBytes,
+		Url:         attachment.URL,
+		CreatedAt:   attachment.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if attachment.URL != "" {
+		response.UrlExpiresAt = attachment.URLExpiresAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+	return response
+}
@@ -0,0 +1,135 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	apiv1 "main/proto/api/v1"
+	"main/queries"
+	"main/service"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestConn(t *testing.T) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	server := New()
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestValidationMatchesREST(t *testing.T) {
+	conn := newTestConn(t)
+	users := apiv1.NewUserServiceClient(conn)
+	messages := apiv1.NewMessageServiceClient(conn)
+	ctx := context.Background()
+
+	_, err := users.CreateUser(ctx, &apiv1.CreateUserRequest{Email: "a@example.com", UserType: "UTYPE_USER"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, "Username is required", status.Convert(err).Message())
+
+	userType := "UTYPE_ROOT"
+	_, err = users.UpdateUser(ctx, &apiv1.UpdateUserRequest{Id: 1, UserType: &userType})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "Invalid user type")
+
+	_, err = users.SearchUsers(ctx, &apiv1.SearchUsersRequest{Q: "  "})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = messages.CreateMessage(ctx, &apiv1.CreateMessageRequest{UserId: 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, "Content is required", status.Convert(err).Message())
+
+	expiresAt := "tomorrow"
+	_, err = messages.CreateMessage(ctx, &apiv1.CreateMessageRequest{UserId: 1, Content: "hi", ExpiresAt: &expiresAt})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, "Invalid expires_at: must be an RFC 3339 timestamp", status.Convert(err).Message())
+
+	conversationID := int32(0)
+	_, err = messages.CreateMessage(ctx, &apiv1.CreateMessageRequest{UserId: 1, Content: "hi", ConversationId: &conversationID})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, "Invalid conversation ID", status.Convert(err).Message())
+}
+
+func TestNewMessage(t *testing.T) {
+	message := newMessage(queries.GetMessagesQueryRow{ID: 1, UserID: 2, Content: "hi", ConversationID: queries.DefaultConversationID})
+	assert.Empty(t, message.ExpiresAt)
+	assert.False(t, message.Held)
+	assert.Nil(t, message.ParentId)
+	assert.Equal(t, int32(queries.DefaultConversationID), message.ConversationId)
+
+	message = newMessage(queries.GetMessagesQueryRow{
+		ID:             1,
+		UserID:         2,
+		Content:        "hi",
+		ExpiresAt:      pgtype.Timestamptz{Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true},
+		Held:           true,
+		ParentID:       pgtype.Int4{Int32: 7, Valid: true},
+		ConversationID: 3,
+	})
+	assert.Equal(t, "2025-01-02T03:04:05Z", message.ExpiresAt)
+	assert.True(t, message.Held)
+	assert.Equal(t, int32(7), message.GetParentId())
+	assert.Equal(t, int32(3), message.ConversationId)
+}
+
+func TestWatchMessages(t *testing.T) {
+	conn := newTestConn(t)
+	messages := apiv1.NewMessageServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userID := int32(2)
+	stream, err := messages.WatchMessages(ctx, &apiv1.WatchMessagesRequest{UserId: &userID})
+	assert.NoError(t, err)
+
+	// Publish until the subscription is in place; messages from other users are filtered out.
+	go func() {
+		for ctx.Err() == nil {
+			service.NewMessages.Publish(queries.GetMessagesQueryRow{ID: 1, UserID: 1, Content: "not for you"})
+			service.NewMessages.Publish(queries.GetMessagesQueryRow{
+				ID:        2,
+				UserID:    2,
+				Content:   "hello",
+				CreatedAt: pgtype.Timestamptz{Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true},
+			})
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	msg, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), msg.GetUserId())
+	assert.Equal(t, "hello", msg.GetContent())
+	assert.Equal(t, "2025-01-02T03:04:05Z", msg.GetCreatedAt())
+}
+
+func TestNewUserAvatar(t *testing.T) {
+	user := newUser(queries.GetUsersQueryRow{ID: 1, Username: "liam"})
+	assert.Empty(t, user.AvatarUrl)
+	assert.Empty(t, user.AvatarUrls)
+
+	user = newUser(queries.GetUsersQueryRow{ID: 1, Username: "liam", AvatarKey: pgtype.Text{String: "avatars/1/abc.png", Valid: true}})
+	assert.Equal(t, service.AvatarURL("avatars/1/abc.png", service.AvatarSizes[0]), user.AvatarUrl)
+	assert.Len(t, user.AvatarUrls, len(service.AvatarSizes))
+	for _, size := range service.AvatarSizes {
+		assert.Equal(t, service.AvatarURL("avatars/1/abc.png", size), user.AvatarUrls[int32(size)])
+	}
+}
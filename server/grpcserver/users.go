@@ -0,0 +1,76 @@
+package grpcserver
+
+import (
+	"context"
+
+	apiv1 "main/proto/api/v1"
+	"main/queries"
+	"main/service"
+)
+
+type userServer struct {
+	apiv1.UnimplementedUserServiceServer
+}
+
+func (s *userServer) ListUsers(ctx context.Context, req *apiv1.ListUsersRequest) (*apiv1.ListUsersResponse, error) {
+	rows, err := service.ListUsers(ctx)
+	if err != nil {
+		return nil, toStatus(err, "Failed to retrieve users")
+	}
+	return newUsersResponse(rows), nil
+}
+
+func (s *userServer) SearchUsers(ctx context.Context, req *apiv1.SearchUsersRequest) (*apiv1.ListUsersResponse, error) {
+	rows, err := service.SearchUsers(ctx, req.GetQ(), int(req.GetLimit()))
+	if err != nil {
+		return nil, toStatus(err, "Failed to search users")
+	}
+	return newUsersResponse(rows), nil
+}
+
+func (s *userServer) GetUser(ctx context.Context, req *apiv1.GetUserRequest) (*apiv1.User, error) {
+	row, err := service.GetUser(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, toStatus(err, "Failed to retrieve user")
+	}
+	return newUser(row), nil
+}
+
+func (s *userServer) CreateUser(ctx context.Context, req *apiv1.CreateUserRequest) (*apiv1.User, error) {
+	row, err := service.CreateUser(ctx, queries.CreateUserParams{
+		Username: req.GetUsername(),
+		Email:    req.GetEmail(),
+		UserType: req.GetUserType(),
+		Nickname: req.Nickname,
+	})
+	if err != nil {
+		return nil, toStatus(err, "Failed to create user")
+	}
+	return newUser(row), nil
+}
+
+func (s *userServer) UpdateUser(ctx context.Context, req *apiv1.UpdateUserRequest) (*apiv1.User, error) {
+	params := queries.UpdateUserParams{
+		Username: req.Username,
+		Email:    req.Email,
+		UserType: req.UserType,
+		Nickname: req.Nickname,
+	}
+	if req.ExpectedVersion != nil {
+		params.MatchVersions = []int32{req.GetExpectedVersion()}
+	}
+
+	row, err := service.UpdateUser(ctx, int(req.GetId()), params)
+	if err != nil {
+		return nil, toStatus(err, "Failed to update user")
+	}
+	return newUser(row), nil
+}
+
+func newUsersResponse(rows []queries.GetUsersQueryRow) *apiv1.ListUsersResponse {
+	users := make([]*apiv1.User, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, newUser(row))
+	}
+	return &apiv1.ListUsersResponse{Users: users}
+}
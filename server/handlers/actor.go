@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"main/queries"
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RequestIDHeader carries the request ID, echoed back (or generated) on every response.
+	RequestIDHeader = "X-Request-ID"
+	// UserIDHeader identifies the acting user until the API gains authentication.
+	UserIDHeader = "X-User-ID"
+)
+
+const maxRequestIDLength = 100
+
+// RequestID ensures every request has an ID. A client-supplied X-Request-ID
+// is kept if it is reasonably short; otherwise a random one is generated.
+// The ID is returned in the X-Request-ID response header.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength || strings.ContainsFunc(id, isControl) {
+			id = newRequestID()
+		}
+		c.Request.Header.Set(RequestIDHeader, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Actor attaches the service.Actor for the request (user from X-User-ID,
+// client IP and request ID) to the request context, for the audit log.
+// Run it after RequestID.
+// Response:
+//   - 400: Error if X-User-ID is not a valid user ID.
+func Actor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := service.Actor{
+			IP:        c.ClientIP(),
+			RequestID: c.GetHeader(RequestIDHeader),
+		}
+		if header := c.GetHeader(UserIDHeader); header != "" {
+			userID, err := strconv.Atoi(header)
+			if err != nil || userID <= 0 {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid " + UserIDHeader + " header"})
+				return
+			}
+			actor.UserID = userID
+		}
+
+		c.Request = c.Request.WithContext(service.WithActor(c.Request.Context(), actor))
+		c.Next()
+	}
+}
+
+// RequireUserType restricts a route to actors whose user type is one of userTypes.
+// Response:
+//   - 401: Error if there is no acting user or they do not exist.
+//   - 403: Error if the acting user has another user type.
+func RequireUserType(userTypes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := service.ActorFrom(c.Request.Context())
+		if actor.UserID == 0 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": UserIDHeader + " header is required"})
+			return
+		}
+
+		user, err := service.GetUser(c.Request.Context(), actor.UserID)
+		if errors.Is(err, queries.ErrUserNotFound) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unknown user"})
+			return
+		}
+		if err != nil {
+			respondError(c, err, "Failed to retrieve user")
+			c.Abort()
+			return
+		}
+
+		if !slices.Contains(userTypes, user.UserType) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Requires user type " + strings.Join(userTypes, " or ")})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireUser restricts a route to requests with an acting user who exists.
+// Response:
+//   - 401: Error if there is no acting user or they do not exist.
+func RequireUser() gin.HandlerFunc {
+	return RequireUserType(service.UserTypes...)
+}
+
+// RequireSelfOrUserType restricts a route to the user named by its user_id
+// path parameter and to actors whose user type is one of userTypes.
+// Response:
+//   - 401: Error if there is no acting user or they do not exist.
+//   - 403: Error if the acting user is someone else with another user type.
+func RequireSelfOrUserType(userTypes ...string) gin.HandlerFunc {
+	requireUserType := RequireUserType(userTypes...)
+	return func(c *gin.Context) {
+		actor := service.ActorFrom(c.Request.Context())
+		if actor.UserID != 0 && c.Param("user_id") == strconv.Itoa(actor.UserID) {
+			c.Next()
+			return
+		}
+		requireUserType(c)
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func actorRouter(handlers ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID(), Actor())
+	router.GET("/", append(handlers, func(c *gin.Context) {
+		c.JSON(http.StatusOK, service.ActorFrom(c.Request.Context()))
+	})...)
+	return router
+}
+
+func TestActor(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set(UserIDHeader, "3")
+	req.Header.Set(RequestIDHeader, "req-1")
+	req.RemoteAddr = "10.0.0.1:1234"
+	w := httptest.NewRecorder()
+	actorRouter().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "req-1", w.Header().Get(RequestIDHeader))
+	assert.JSONEq(t, `{"UserID":3,"IP":"10.0.0.1","RequestID":"req-1"}`, w.Body.String())
+}
+
+func TestRequestIDIsGenerated(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "bad\nid")
+	w := httptest.NewRecorder()
+	actorRouter().ServeHTTP(w, req)
+
+	assert.Len(t, w.Header().Get(RequestIDHeader), 32)
+}
+
+func TestActorInvalidUserID(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set(UserIDHeader, "abc")
+	w := httptest.NewRecorder()
+	actorRouter().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"Invalid X-User-ID header"}`, w.Body.String())
+}
+
+func TestRequireUserTypeWithoutActor(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	actorRouter(RequireUserType("UTYPE_ADMIN")).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"error":"X-User-ID header is required"}`, w.Body.String())
+}
+
+func TestRequireSelfOrUserType(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID(), Actor())
+	router.GET("/users/:user_id", RequireSelfOrUserType("UTYPE_ADMIN"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	// The user themselves passes without their type being looked up.
+	req, _ := http.NewRequest("GET", "/users/3", nil)
+	req.Header.Set(UserIDHeader, "3")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	req, _ = http.NewRequest("GET", "/users/3", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"error":"X-User-ID header is required"}`, w.Body.String())
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxMultipartOverhead allows for the multipart headers and boundaries
+// around an uploaded file.
+const maxMultipartOverhead = 64 << 10
+
+// AddAttachment handles POST /messages/:message_id/attachments requests,
+// attaching the file in the multipart form field "file" to a message written
+// by the acting user. The content type is detected from the file's contents
+// and must be one of service.AttachmentTypes.
+// Response:
+//   - 201: JSON of the attachment, with a signed download URL.
+//   - 400: Error if the file is missing, empty or of an unsupported type, or the message has too many attachments.
+//   - 403: Error if the acting user did not write the message.
+//   - 404: Error if message is not found.
+//   - 413: Error if the file is larger than service.MaxAttachmentBytes.
+func AddAttachment(c *gin.Context) {
+	messageID, err := strconv.Atoi(c.Param("message_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	tooLarge := gin.H{"error": "Attachments are limited to " + strconv.Itoa(service.MaxAttachmentBytes>>20) + "MB"}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxAttachmentBytes+maxMultipartOverhead)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload: a multipart form with a file field is required"})
+		return
+	}
+	if header.Size > service.MaxAttachmentBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		respondError(c, err, "Failed to read upload")
+		return
+	}
+	defer file.Close()
+
+	attachment, err := service.AddAttachment(c.Request.Context(), messageID, header.Filename, file, header.Size)
+	if err != nil {
+		respondError(c, err, "Failed to add attachment")
+		return
+	}
+	c.JSON(http.StatusCreated, newAttachmentResponse(attachment))
+}
+
+// newAttachmentResponse converts an attachment into its API representation.
+func newAttachmentResponse(attachment service.Attachment) AttachmentResponse {
+	response := AttachmentResponse{
+		ID:          attachment.ID,
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		Size:        attachment.SizeBytes,
+		URL:         attachment.URL,
+		CreatedAt:   attachment.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if attachment.URL != "" {
+		response.URLExpiresAt = attachment.URLExpiresAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+	return response
+}
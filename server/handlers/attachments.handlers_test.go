@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"main/queries"
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+// multipartFile returns a multipart body with content in the field "file".
+func multipartFile(field, filename string, content []byte) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile(field, filename)
+	part.Write(content)
+	writer.Close()
+	return body, writer.FormDataContentType()
+}
+
+func setupAttachmentTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(service.WithActor(c.Request.Context(), service.Actor{UserID: 1}))
+	})
+	router.POST("/messages/:message_id/attachments", AddAttachment)
+	return router
+}
+
+func TestAddAttachmentInvalidParams(t *testing.T) {
+	router := setupAttachmentTestRouter()
+
+	empty, emptyType := multipartFile("file", "a.txt", nil)
+	wrongField, wrongFieldType := multipartFile("upload", "a.txt", []byte("hi"))
+	unnamed, unnamedType := multipartFile("file", "", []byte("hi"))
+	cases := map[string]struct {
+		url         string
+		body        *bytes.Buffer
+		contentType string
+		message     string
+	}{
+		"message ID":  {"/messages/abc/attachments", empty, emptyType, "Invalid message ID"},
+		"not a form":  {"/messages/1/attachments", bytes.NewBufferString(`{"file":"a"}`), "application/json", "Invalid upload: a multipart form with a file field is required"},
+		"wrong field": {"/messages/1/attachments", wrongField, wrongFieldType, "Invalid upload: a multipart form with a file field is required"},
+		"no filename": {"/messages/1/attachments", unnamed, unnamedType, "Invalid upload: a multipart form with a file field is required"},
+		"empty":       {"/messages/1/attachments", empty, emptyType, "File is empty"},
+	}
+	for name, tc := range cases {
+		req, _ := http.NewRequest(http.MethodPost, tc.url, tc.body)
+		req.Header.Set("Content-Type", tc.contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+		assert.JSONEq(t, `{"error":"`+tc.message+`"}`, w.Body.String(), name)
+	}
+}
+
+func TestAddAttachmentTooLarge(t *testing.T) {
+	router := setupAttachmentTestRouter()
+
+	body, contentType := multipartFile("file", "big.txt", []byte(strings.Repeat("a", service.MaxAttachmentBytes+1)))
+	req, _ := http.NewRequest(http.MethodPost, "/messages/1/attachments", body)
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.JSONEq(t, `{"error":"Attachments are limited to 10MB"}`, w.Body.String())
+}
+
+func TestNewAttachmentResponse(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	attachment := service.Attachment{
+		MessageAttachment: queries.MessageAttachment{
+			ID:          4,
+			MessageID:   pgtype.Int4{Int32: 1, Valid: true},
+			StorageKey:  "attachments/1/abc.png",
+			Filename:    "photo.png",
+			ContentType: "image/png",
+			SizeBytes:   1024,
+			CreatedAt:   pgtype.Timestamptz{Time: createdAt, Valid: true},
+		},
+		URL:          "http://localhost:8080/blobs/attachments/1/abc.png?expires=1&signature=x",
+		URLExpiresAt: createdAt.Add(15 * time.Minute),
+	}
+	body, _ := json.Marshal(newAttachmentResponse(attachment))
+	assert.JSONEq(t, `{
+		"id":4,"filename":"photo.png","content_type":"image/png","size":1024,
+		"url":"http://localhost:8080/blobs/attachments/1/abc.png?expires=1&signature=x",
+		"url_expires_at":"2024-05-01T12:15:00Z","created_at":"2024-05-01T12:00:00Z"
+	}`, string(body))
+
+	attachment.URL = ""
+	body, _ = json.Marshal(newAttachmentResponse(attachment))
+	assert.JSONEq(t, `{"id":4,"filename":"photo.png","content_type":"image/png","size":1024,"created_at":"2024-05-01T12:00:00Z"}`, string(body))
+}
+
+func TestRespondErrorNotMessageAuthor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	respondError(c, service.ErrNotMessageAuthor, "Failed to add attachment")
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"error":"Only the message's author can do this"}`, w.Body.String())
+}
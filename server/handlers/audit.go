@@ -0,0 +1,18 @@
+package handlers
+
+type AuditEventResponse struct {
+	ID         int64          `json:"id"`
+	ActorID    *int32         `json:"actor_id"`
+	Action     string         `json:"action"`
+	TargetType string         `json:"target_type"`
+	TargetID   int            `json:"target_id"`
+	Before     map[string]any `json:"before"`
+	After      map[string]any `json:"after"`
+	IP         *string        `json:"ip"`
+	RequestID  *string        `json:"request_id"`
+	CreatedAt  string         `json:"created_at"`
+}
+
+type GetAuditEventsResponse struct {
+	Events []AuditEventResponse `json:"events"`
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"main/queries"
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditEvents handles GET /audit requests. Restricted to admins.
+// Query parameters (all optional):
+//   - actor_id, action, target_type, target_id: Exact-match filters.
+//   - since, until: RFC 3339 timestamps bounding created_at.
+//   - before_id: Only events older than this ID, for paging.
+//   - limit: Maximum number of events (default 50, max 200).
+//
+// Response:
+//   - 200: JSON list of audit events, newest first.
+//   - 400: Error if parameters are invalid or database query fails.
+func GetAuditEvents(c *gin.Context) {
+	filter := service.AuditFilter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+	}
+
+	ints := map[string]*int{
+		"actor_id":  &filter.ActorID,
+		"target_id": &filter.TargetID,
+		"limit":     &filter.Limit,
+	}
+	for name, dest := range ints {
+		if value := c.Query(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+				return
+			}
+			*dest = parsed
+		}
+	}
+	if value := c.Query("before_id"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before_id"})
+			return
+		}
+		filter.BeforeID = parsed
+	}
+
+	times := map[string]*time.Time{
+		"since": &filter.Since,
+		"until": &filter.Until,
+	}
+	for name, dest := range times {
+		if value := c.Query(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name + ": must be an RFC 3339 timestamp"})
+				return
+			}
+			*dest = parsed
+		}
+	}
+
+	rows, err := service.ListAuditEvents(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, err, "Failed to retrieve audit events")
+		return
+	}
+
+	events := make([]AuditEventResponse, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, newAuditEventResponse(row))
+	}
+	c.JSON(http.StatusOK, GetAuditEventsResponse{Events: events})
+}
+
+// newAuditEventResponse converts an audit event row into its API representation.
+func newAuditEventResponse(row queries.AuditEvent) AuditEventResponse {
+	event := AuditEventResponse{
+		ID:         row.ID,
+		Action:     row.Action,
+		TargetType: row.TargetType,
+		TargetID:   row.TargetID,
+		CreatedAt:  row.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if row.ActorID.Valid {
+		event.ActorID = &row.ActorID.Int32
+	}
+	if row.IP.Valid {
+		event.IP = &row.IP.String
+	}
+	if row.RequestID.Valid {
+		event.RequestID = &row.RequestID.String
+	}
+	if len(row.Before) > 0 {
+		json.Unmarshal(row.Before, &event.Before)
+	}
+	if len(row.After) > 0 {
+		json.Unmarshal(row.After, &event.After)
+	}
+	return event
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"main/queries"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAuditEventsInvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/audit", GetAuditEvents)
+
+	cases := map[string]string{
+		"/audit?actor_id=abc":    "Invalid actor_id",
+		"/audit?limit=0":         "Invalid limit",
+		"/audit?before_id=-1":    "Invalid before_id",
+		"/audit?since=yesterday": "Invalid since: must be an RFC 3339 timestamp",
+		"/audit?since=2025-01-02T00:00:00Z&until=2025-01-01T00:00:00Z": "until must be after since",
+	}
+	for url, message := range cases {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+		assert.JSONEq(t, `{"error":"`+message+`"}`, w.Body.String(), url)
+	}
+}
+
+func TestNewAuditEventResponse(t *testing.T) {
+	event := newAuditEventResponse(queries.AuditEvent{
+		ID:         5,
+		ActorID:    pgtype.Int4{Int32: 1, Valid: true},
+		Action:     "user.update",
+		TargetType: "user",
+		TargetID:   2,
+		Before:     []byte(`{"user_type":"UTYPE_USER"}`),
+		After:      []byte(`{"user_type":"UTYPE_ADMIN"}`),
+		IP:         pgtype.Text{String: "10.0.0.1", Valid: true},
+		CreatedAt:  pgtype.Timestamptz{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+	})
+
+	assert.Equal(t, int32(1), *event.ActorID)
+	assert.Equal(t, map[string]any{"user_type": "UTYPE_USER"}, event.Before)
+	assert.Equal(t, map[string]any{"user_type": "UTYPE_ADMIN"}, event.After)
+	assert.Equal(t, "10.0.0.1", *event.IP)
+	assert.Nil(t, event.RequestID)
+	assert.Equal(t, "2025-01-01T00:00:00Z", event.CreatedAt)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"main/service"
+	"main/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetAvatar handles PUT /users/:user_id/avatar requests, replacing the user's
+// avatar with the image in the multipart form field "file". The image type is
+// detected from its contents and must be one of service.AvatarTypes.
+// Response:
+//   - 200: JSON of the user, with their new avatar URLs.
+//   - 400: Error if the image is missing, invalid or of an unsupported type.
+//   - 404: Error if user is not found.
+//   - 413: Error if the image is larger than service.MaxAvatarBytes.
+func SetAvatar(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	tooLarge := gin.H{"error": "Avatars are limited to " + strconv.Itoa(service.MaxAvatarBytes>>20) + "MB"}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxAvatarBytes+maxMultipartOverhead)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload: a multipart form with a file field is required"})
+		return
+	}
+	if header.Size > service.MaxAvatarBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		respondError(c, err, "Failed to read upload")
+		return
+	}
+	defer file.Close()
+
+	user, err := service.SetAvatar(c.Request.Context(), userID, file, header.Size)
+	if err != nil {
+		respondError(c, err, "Failed to set avatar")
+		return
+	}
+	respondUser(c, http.StatusOK, user)
+}
+
+// GetAvatar handles GET /avatars/*key requests, made through the avatar URLs
+// in user responses. Those URLs do not expire, so this redirects to a signed
+// URL of the avatar's blob.
+// Response:
+//   - 302: Redirect to the image.
+//   - 404: Error if key is not an avatar key.
+func GetAvatar(c *gin.Context) {
+	url, err := service.AvatarDownloadURL(c.Request.Context(), "avatars/"+strings.TrimPrefix(c.Param("key"), "/"))
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Avatar not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, err, "Failed to sign avatar URL")
+		return
+	}
+
+	// Shorter than the signed URL's lifetime, so a cached redirect still works.
+	c.Header("Cache-Control", "public, max-age=600")
+	c.Redirect(http.StatusFound, url)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"main/queries"
+	"main/service"
+	"main/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetAvatarInvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PUT("/users/:user_id/avatar", SetAvatar)
+
+	empty, emptyType := multipartFile("file", "a.png", nil)
+	text, textType := multipartFile("file", "a.png", []byte("not an image"))
+	cases := map[string]struct {
+		url         string
+		body        *bytes.Buffer
+		contentType string
+		message     string
+	}{
+		"user ID":     {"/users/abc/avatar", empty, emptyType, "Invalid user ID"},
+		"not a form":  {"/users/1/avatar", bytes.NewBufferString(`{}`), "application/json", "Invalid upload: a multipart form with a file field is required"},
+		"empty":       {"/users/1/avatar", empty, emptyType, "Image is empty"},
+		"unsupported": {"/users/1/avatar", text, textType, "Unsupported image type text/plain"},
+	}
+	for name, tc := range cases {
+		req, _ := http.NewRequest(http.MethodPut, tc.url, tc.body)
+		req.Header.Set("Content-Type", tc.contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+		assert.JSONEq(t, `{"error":"`+tc.message+`"}`, w.Body.String(), name)
+	}
+
+	big, bigType := multipartFile("file", "big.png", []byte(strings.Repeat("a", service.MaxAvatarBytes+1)))
+	req, _ := http.NewRequest(http.MethodPut, "/users/1/avatar", big)
+	req.Header.Set("Content-Type", bigType)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.JSONEq(t, `{"error":"Avatars are limited to 5MB"}`, w.Body.String())
+}
+
+func TestGetAvatar(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/avatars/*key", GetAvatar)
+
+	store, err := storage.NewLocal(t.TempDir(), "http://localhost:8080/blobs", []byte("secret"))
+	assert.NoError(t, err)
+	service.ConfigureBlobStore(store)
+	t.Cleanup(func() { service.ConfigureBlobStore(nil) })
+
+	key := "2/" + strings.Repeat("ab", 16) + "_48.png"
+	req, _ := http.NewRequest(http.MethodGet, "/avatars/"+key, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Location"), "http://localhost:8080/blobs/avatars/"+key+"?"))
+	assert.Equal(t, "public, max-age=600", w.Header().Get("Cache-Control"))
+
+	req, _ = http.NewRequest(http.MethodGet, "/avatars/2/../../attachments/1/a.png", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.JSONEq(t, `{"error":"Avatar not found"}`, w.Body.String())
+}
+
+func TestNewUserResponseAvatar(t *testing.T) {
+	body, _ := json.Marshal(newUserResponse(queries.GetUsersQueryRow{ID: 2, Username: "bob"}))
+	assert.NotContains(t, string(body), "avatar")
+
+	key := "avatars/2/" + strings.Repeat("ab", 16) + ".jpg"
+	user := newUserResponse(queries.GetUsersQueryRow{ID: 2, Username: "bob", AvatarKey: pgtype.Text{String: key, Valid: true}})
+	assert.Equal(t, "/"+key, user.AvatarURL)
+	assert.Equal(t, map[string]string{
+		"512": "/" + key,
+		"128": "/avatars/2/" + strings.Repeat("ab", 16) + "_128.jpg",
+		"48":  "/avatars/2/" + strings.Repeat("ab", 16) + "_48.jpg",
+	}, user.AvatarURLs)
+	assert.Equal(t, user.AvatarURLs, user.V2().AvatarURLs)
+}
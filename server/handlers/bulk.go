@@ -0,0 +1,16 @@
+package handlers
+
+type ImportUsersResponse struct {
+	DryRun  bool                     `json:"dry_run"`
+	Mode    string                   `json:"mode"`
+	Total   int                      `json:"total"`
+	Created int                      `json:"created"`
+	Failed  int                      `json:"failed"`
+	Errors  []ImportRowErrorResponse `json:"errors"`
+}
+
+type ImportRowErrorResponse struct {
+	// Line of the import file the row started on, counting the CSV header.
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
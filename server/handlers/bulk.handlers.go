@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"main/queries"
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxImportBytes limits the size of an import request body.
+const MaxImportBytes = 10 << 20
+
+// importContentTypes maps the media types accepted for imports to their format.
+var importContentTypes = map[string]string{
+	"text/csv":             service.FormatCSV,
+	"application/x-ndjson": service.FormatNDJSON,
+	"application/ndjson":   service.FormatNDJSON,
+}
+
+// ImportUsers handles POST /users/import requests. Restricted to admins.
+// The body is CSV with a header row, or NDJSON, chosen by the format query
+// parameter or else the Content-Type (text/csv or application/x-ndjson).
+// Query parameters:
+//   - format: csv or ndjson (default from Content-Type).
+//   - mode: all_or_nothing (default) or best_effort.
+//   - dry_run: true to validate and report without creating any users.
+//
+// Response:
+//   - 200: JSON summary of the import with the errors of any failed rows.
+//   - 400: Error if the parameters or file are invalid.
+//   - 413: Error if the body exceeds 10MB.
+//   - 422: JSON summary of an all-or-nothing import that was not committed because rows failed.
+func ImportUsers(c *gin.Context) {
+	format := c.Query("format")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		format = importContentTypes[mediaType]
+	}
+	if format == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown import format; set format or a Content-Type of text/csv or application/x-ndjson"})
+		return
+	}
+
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dry_run"})
+			return
+		}
+		dryRun = parsed
+	}
+
+	rows, err := service.ReadUserImport(http.MaxBytesReader(c.Writer, c.Request.Body, MaxImportBytes), format)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Import is limited to " + strconv.Itoa(MaxImportBytes>>20) + "MB"})
+		return
+	}
+	if err != nil {
+		respondError(c, err, "Failed to read import")
+		return
+	}
+
+	result, err := service.ImportUsers(c.Request.Context(), rows, c.Query("mode"), dryRun)
+	if err != nil {
+		respondError(c, err, "Failed to import users")
+		return
+	}
+
+	status := http.StatusOK
+	if result.Mode == service.ImportAllOrNothing && len(result.Errors) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, newImportUsersResponse(result))
+}
+
+// ExportUsers handles GET /users/export requests, streaming every user in ID
+// order as a file download. Restricted to admins.
+// Query parameters:
+//   - format: csv (default) or ndjson. NDJSON lines use the /v2 user representation.
+//
+// Response:
+//   - 200: The users as CSV or NDJSON.
+//   - 400: Error if format is invalid or the database query fails before any output.
+func ExportUsers(c *gin.Context) {
+	format, ok := exportFormat(c, service.UserExportFormats)
+	if !ok {
+		return
+	}
+
+	streamExport(c, format, userExportSpec, service.ExportUsers, "Failed to export users")
+}
+
+// ExportMessages handles GET /messages/export requests, streaming messages in
+// ID order as a file download. Messages held for review and messages archived
+// by retention policies are included and flagged. Restricted to admins.
+// Query parameters:
+//   - format: csv (default), ndjson, or zip for a zip archive of a JSON array.
+//   - since, until: RFC 3339 timestamps bounding created_at.
+//
+// Response:
+//   - 200: The messages as CSV, NDJSON or a zip archive.
+//   - 400: Error if parameters are invalid or the database query fails before any output.
+func ExportMessages(c *gin.Context) {
+	exportMessages(c, 0)
+}
+
+// ExportUserMessages handles GET /users/:user_id/messages/export requests,
+// streaming a user's messages in ID order as a file download. Restricted to admins.
+// Query parameters are as for ExportMessages.
+// Response:
+//   - 200: The messages as CSV, NDJSON or a zip archive.
+//   - 400: Error if parameters are invalid or the database query fails before any output.
+//   - 404: Error if user is not found.
+func ExportUserMessages(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	exportMessages(c, userID)
+}
+
+func exportMessages(c *gin.Context, userID int) {
+	format, ok := exportFormat(c, service.MessageExportFormats)
+	if !ok {
+		return
+	}
+
+	filter := queries.MessageExportFilter{UserID: userID, IncludeArchived: true}
+	times := map[string]*time.Time{
+		"since": &filter.Since,
+		"until": &filter.Until,
+	}
+	for name, dest := range times {
+		if value := c.Query(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name + ": must be an RFC 3339 timestamp"})
+				return
+			}
+			*dest = parsed
+		}
+	}
+
+	streamExport(c, format, messageExportSpec, func(ctx context.Context, fn func(queries.ExportedMessage) error) error {
+		return service.ExportMessages(ctx, filter, fn)
+	}, "Failed to export messages")
+}
+
+// userExportSpec encodes user exports. The username, email, user_type and
+// nickname columns let an export be imported again.
+var userExportSpec = exportSpec[queries.GetUsersQueryRow]{
+	name:    "users",
+	columns: []string{"id", "username", "email", "user_type", "nickname", "message_count", "version"},
+	record:  userCSVRecord,
+	value:   func(row queries.GetUsersQueryRow) any { return newUserResponse(row).V2() },
+}
+
+// messageExportSpec encodes message exports, which include messages held for
+// review and, flagged as archived, those moved out by retention policies.
+var messageExportSpec = exportSpec[queries.ExportedMessage]{
+	name:    "messages",
+	columns: []string{"id", "user_id", "content", "created_at", "expires_at", "parent_id", "held", "archived"},
+	record:  messageCSVRecord,
+	value: func(row queries.ExportedMessage) any {
+		return ExportedMessageResponse{MessageResponse: newMessageResponse(row.GetMessagesQueryRow), Archived: row.Archived}
+	},
+}
+
+func userCSVRecord(row queries.GetUsersQueryRow) []string {
+	return []string{
+		strconv.Itoa(row.ID),
+		row.Username,
+		row.Email,
+		row.UserType,
+		row.Nickname.String,
+		strconv.Itoa(int(row.MessageCount)),
+		strconv.Itoa(int(row.Version)),
+	}
+}
+
+func messageCSVRecord(row queries.ExportedMessage) []string {
+	return []string{
+		strconv.Itoa(row.ID),
+		strconv.Itoa(row.UserID),
+		row.Content,
+		row.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		newMessageResponse(row.GetMessagesQueryRow).ExpiresAt,
+		messageParentID(row.GetMessagesQueryRow),
+		strconv.FormatBool(row.Held),
+		strconv.FormatBool(row.Archived),
+	}
+}
+
+func newImportUsersResponse(result service.ImportResult) ImportUsersResponse {
+	errs := make([]ImportRowErrorResponse, 0, len(result.Errors))
+	for _, rowErr := range result.Errors {
+		errs = append(errs, ImportRowErrorResponse{Line: rowErr.Line, Error: rowErr.Message})
+	}
+	return ImportUsersResponse{
+		DryRun:  result.DryRun,
+		Mode:    result.Mode,
+		Total:   result.Total,
+		Created: result.Created,
+		Failed:  len(result.Errors),
+		Errors:  errs,
+	}
+}
+
+func messageParentID(row queries.GetMessagesQueryRow) string {
+	if !row.ParentID.Valid {
+		return ""
+	}
+	return strconv.Itoa(int(row.ParentID.Int32))
+}
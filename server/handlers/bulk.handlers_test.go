@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"main/queries"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupBulkTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/users/import", ImportUsers)
+	router.GET("/users/export", ExportUsers)
+	return router
+}
+
+func TestImportUsersInvalidParams(t *testing.T) {
+	router := setupBulkTestRouter()
+
+	cases := map[string]struct {
+		url, contentType, message string
+	}{
+		"no format":      {"/users/import", "text/plain", "Unknown import format; set format or a Content-Type of text/csv or application/x-ndjson"},
+		"unknown format": {"/users/import?format=xml", "", "Invalid format. Must be one of: csv, ndjson"},
+		"dry_run":        {"/users/import?dry_run=maybe", "text/csv", "Invalid dry_run"},
+		"mode":           {"/users/import?mode=some", "text/csv", "Invalid mode. Must be one of: all_or_nothing, best_effort"},
+	}
+	for name, tc := range cases {
+		req, _ := http.NewRequest(http.MethodPost, tc.url, strings.NewReader("username,email,user_type\na,a@example.com,UTYPE_USER\n"))
+		req.Header.Set("Content-Type", tc.contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+		assert.JSONEq(t, `{"error":"`+tc.message+`"}`, w.Body.String(), name)
+	}
+}
+
+func TestImportUsersRowErrors(t *testing.T) {
+	router := setupBulkTestRouter()
+
+	body := `{"username":"alice","email":"alice@example.com","user_type":"UTYPE_USER"}` + "\n" +
+		`{"username":"bob","user_type":"UTYPE_USER"}` + "\n"
+	req, _ := http.NewRequest(http.MethodPost, "/users/import?dry_run=true", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson; charset=utf-8")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var response ImportUsersResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, ImportUsersResponse{
+		DryRun:  true,
+		Mode:    "all_or_nothing",
+		Total:   2,
+		Created: 0,
+		Failed:  1,
+		Errors:  []ImportRowErrorResponse{{Line: 2, Error: "Email is required"}},
+	}, response)
+}
+
+func TestImportUsersTooLarge(t *testing.T) {
+	router := setupBulkTestRouter()
+
+	body := "username,email,user_type\n" + strings.Repeat("a", MaxImportBytes)
+	req, _ := http.NewRequest(http.MethodPost, "/users/import?format=csv", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.JSONEq(t, `{"error":"Import is limited to 10MB"}`, w.Body.String())
+}
+
+func TestExportUsersInvalidFormat(t *testing.T) {
+	router := setupBulkTestRouter()
+
+	req, _ := http.NewRequest(http.MethodGet, "/users/export?format=xml", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"Invalid format. Must be one of: csv, ndjson"}`, w.Body.String())
+}
+
+func TestUserCSVRecord(t *testing.T) {
+	row := queries.GetUsersQueryRow{
+		ID:           7,
+		Username:     "alice",
+		Email:        "alice@example.com",
+		UserType:     "UTYPE_USER",
+		Nickname:     pgtype.Text{String: "Al", Valid: true},
+		MessageCount: 3,
+		Version:      2,
+	}
+	assert.Equal(t, []string{"7", "alice", "alice@example.com", "UTYPE_USER", "Al", "3", "2"}, userCSVRecord(row))
+
+	row.Nickname = pgtype.Text{}
+	assert.Equal(t, "", userCSVRecord(row)[4])
+	assert.Len(t, userCSVRecord(row), len(userExportSpec.columns))
+}
+
+func TestExportMessagesInvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/messages/export", ExportMessages)
+	router.GET("/users/:user_id/messages/export", ExportUserMessages)
+
+	cases := map[string]string{
+		"/messages/export?format=xml":                                            "Invalid format. Must be one of: csv, ndjson, zip",
+		"/messages/export?since=yesterday":                                       "Invalid since: must be an RFC 3339 timestamp",
+		"/messages/export?until=2025-01-01":                                      "Invalid until: must be an RFC 3339 timestamp",
+		"/users/abc/messages/export":                                             "Invalid user ID",
+		"/users/1/messages/export?format=pdf":                                    "Invalid format. Must be one of: csv, ndjson, zip",
+		"/messages/export?since=2025-01-02T00:00:00Z&until=2025-01-01T00:00:00Z": "until must be after since",
+	}
+	for url, message := range cases {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+		assert.JSONEq(t, `{"error":"`+message+`"}`, w.Body.String(), url)
+	}
+}
+
+func TestMessageCSVRecord(t *testing.T) {
+	row := queries.ExportedMessage{
+		GetMessagesQueryRow: queries.GetMessagesQueryRow{
+			ID:        4,
+			UserID:    7,
+			Content:   "hi",
+			CreatedAt: pgtype.Timestamptz{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true},
+			Held:      true,
+		},
+		Archived: true,
+	}
+	assert.Equal(t, []string{"4", "7", "hi", "2024-01-02T03:04:05Z", "", "", "true", "true"}, messageCSVRecord(row))
+	assert.Len(t, messageCSVRecord(row), len(messageExportSpec.columns))
+
+	data, err := json.Marshal(messageExportSpec.value(row))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":4,"user_id":7,"content":"hi","created_at":"2024-01-02T03:04:05Z","conversation_id":0,"held":true,"archived":true}`, string(data))
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConditionalGET returns middleware for cacheable GET endpoints. It buffers
+// successful responses, tags them with a weak ETag derived from the body and
+// the given Cache-Control directives, and answers 304 Not Modified when the
+// client's If-None-Match or If-Modified-Since shows its copy is current.
+// Handlers supply Last-Modified through setLastModified.
+func ConditionalGET(cacheControl string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		w := &bufferedWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if w.Status() != http.StatusOK {
+			w.flush()
+			return
+		}
+
+		sum := sha256.Sum256(w.body.Bytes())
+		etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+		header := c.Writer.Header()
+		header.Set("ETag", etag)
+		if cacheControl != "" {
+			header.Set("Cache-Control", cacheControl)
+		}
+
+		if notModified(c.Request, etag, header.Get("Last-Modified")) {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			c.Writer.WriteHeader(http.StatusNotModified)
+			c.Writer.WriteHeaderNow()
+			return
+		}
+		w.flush()
+	}
+}
+
+// setLastModified sets the Last-Modified header to the newest of times.
+// Zero times are ignored; nothing is set if all are zero.
+func setLastModified(c *gin.Context, times ...time.Time) {
+	var newest time.Time
+	for _, t := range times {
+		if t.After(newest) {
+			newest = t
+		}
+	}
+	if !newest.IsZero() {
+		c.Header("Last-Modified", newest.UTC().Format(http.TimeFormat))
+	}
+}
+
+// notModified evaluates If-None-Match and, only in its absence,
+// If-Modified-Since, as specified by RFC 9110 section 13.2.2.
+func notModified(r *http.Request, etag, lastModified string) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified == "" {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	return err == nil && !modified.After(ims)
+}
+
+// bufferedWriter holds the response until ConditionalGET decides whether to send it.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int)              { w.status = code }
+func (w *bufferedWriter) WriteHeaderNow()                   {}
+func (w *bufferedWriter) Write(b []byte) (int, error)       { return w.body.Write(b) }
+func (w *bufferedWriter) WriteString(s string) (int, error) { return w.body.WriteString(s) }
+func (w *bufferedWriter) Written() bool                     { return w.status != 0 || w.body.Len() > 0 }
+func (w *bufferedWriter) Size() int                         { return w.body.Len() }
+
+func (w *bufferedWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// flush sends the buffered status and body to the underlying writer.
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.Status())
+	w.ResponseWriter.Write(w.body.Bytes())
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupCachingTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	modified := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	router.GET("/things", ConditionalGET("private, no-cache"), func(c *gin.Context) {
+		setLastModified(c, modified.Add(-time.Hour), modified, time.Time{})
+		c.JSON(http.StatusOK, gin.H{"things": []string{"a", "b"}})
+	})
+	router.GET("/broken", ConditionalGET("private, no-cache"), func(c *gin.Context) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nope"})
+	})
+	return router
+}
+
+func get(router *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestConditionalGET(t *testing.T) {
+	router := setupCachingTestRouter()
+
+	w := get(router, "/things", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"things":["a","b"]}`, w.Body.String())
+	assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "Sat, 01 Mar 2025 12:00:00 GMT", w.Header().Get("Last-Modified"))
+	etag := w.Header().Get("ETag")
+	assert.Regexp(t, `^W/"[0-9a-f]{32}"$`, etag)
+
+	w = get(router, "/things", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	w = get(router, "/things", map[string]string{"If-None-Match": `W/"stale", ` + etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = get(router, "/things", map[string]string{"If-Modified-Since": "Sat, 01 Mar 2025 12:00:00 GMT"})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = get(router, "/things", map[string]string{"If-Modified-Since": "Sat, 01 Mar 2025 11:59:59 GMT"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// If-None-Match takes precedence over If-Modified-Since.
+	w = get(router, "/things", map[string]string{
+		"If-None-Match":     `W/"stale"`,
+		"If-Modified-Since": "Sat, 01 Mar 2025 12:00:00 GMT",
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestConditionalGETPassesErrorsThrough(t *testing.T) {
+	router := setupCachingTestRouter()
+
+	w := get(router, "/broken", map[string]string{"If-None-Match": "*"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "nope")
+	assert.Empty(t, w.Header().Get("ETag"))
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"main/queries"
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateConversation handles POST /conversations requests.
+// Response:
+//   - 201: JSON of the created conversation.
+//   - 400: Error if the request is invalid or the database query fails.
+func CreateConversation(c *gin.Context) {
+	var req CreateConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	conversation, err := service.CreateConversation(c.Request.Context(), req.Title)
+	if err != nil {
+		respondError(c, err, "Failed to create conversation")
+		return
+	}
+	c.JSON(http.StatusCreated, newConversationResponse(conversation))
+}
+
+// GetConversations handles GET /conversations requests.
+// Response:
+//   - 200: JSON array of all conversations, oldest first.
+//   - 400: Error if the database query fails.
+func GetConversations(c *gin.Context) {
+	rows, err := service.ListConversations(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "Failed to retrieve conversations")
+		return
+	}
+
+	conversations := make([]ConversationResponse, 0, len(rows))
+	for _, row := range rows {
+		conversations = append(conversations, newConversationResponse(row))
+	}
+	c.JSON(http.StatusOK, GetConversationsResponse{Conversations: conversations})
+}
+
+// GetConversationMessages handles GET /conversations/:conversation_id/messages requests.
+// Query parameters:
+//   - before_id: Only messages older than this ID, for paging.
+//   - limit: Maximum number of messages (default 50, max 200).
+//
+// Response:
+//   - 200: JSON array of the messages in the conversation, newest first.
+//   - 400: Error if parameters are invalid or the database query fails.
+//   - 404: Error if conversation is not found.
+func GetConversationMessages(c *gin.Context) {
+	conversationID, err := strconv.Atoi(c.Param("conversation_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+	limit, beforeID, ok := parseMessagePage(c, "before_id")
+	if !ok {
+		return
+	}
+
+	rows, err := service.ListConversationMessages(c.Request.Context(), conversationID, limit, beforeID)
+	if err != nil {
+		respondError(c, err, "Failed to retrieve messages")
+		return
+	}
+	respondMessages(c, rows)
+}
+
+func newConversationResponse(row queries.Conversation) ConversationResponse {
+	return ConversationResponse{
+		ID:        row.ID,
+		Title:     row.Title,
+		CreatedAt: row.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
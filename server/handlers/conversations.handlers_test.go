@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConversationsInvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/conversations", CreateConversation)
+	router.GET("/conversations/:conversation_id/messages", GetConversationMessages)
+	router.POST("/messages", CreateMessage)
+
+	cases := map[string]struct {
+		method, url, body, message string
+	}{
+		"title":           {http.MethodPost, "/conversations", `{}`, "Invalid request body: Key: 'CreateConversationRequest.Title' Error:Field validation for 'Title' failed on the 'required' tag"},
+		"conversation ID": {http.MethodGet, "/conversations/abc/messages", "", "Invalid conversation ID"},
+		"before ID":       {http.MethodGet, "/conversations/1/messages?before_id=0", "", "Invalid before_id"},
+		"limit":           {http.MethodGet, "/conversations/1/messages?limit=x", "", "Invalid limit"},
+		"message":         {http.MethodPost, "/messages", `{"user_id":1,"content":"hi","conversation_id":0}`, "Invalid conversation ID"},
+	}
+	for name, tc := range cases {
+		req, _ := http.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+		assert.JSONEq(t, `{"error":"`+tc.message+`"}`, w.Body.String(), name)
+	}
+}
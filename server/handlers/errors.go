@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"main/queries"
+	"main/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorResponse is the body returned by all handlers on failure.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// respondError writes err as an error response with a status chosen by its
+// type. Errors it does not recognise are a 400 prefixed with attempted.
+func respondError(c *gin.Context, err error, attempted string) {
+	var validationErr *service.ValidationError
+	var rejectedErr *service.MessageRejectedError
+	switch {
+	case errors.As(err, &validationErr):
+		// Validation messages are written for clients and need no prefix.
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message})
+	case errors.As(err, &rejectedErr):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": rejectedErr.Error()})
+	case errors.Is(err, queries.ErrUserNotFound),
+		errors.Is(err, queries.ErrConversationNotFound),
+		errors.Is(err, queries.ErrMessageNotFound),
+		errors.Is(err, queries.ErrHeldMessageNotFound),
+		errors.Is(err, queries.ErrReactionNotFound),
+		errors.Is(err, queries.ErrWebhookNotFound),
+		errors.Is(err, queries.ErrDeliveryNotFound),
+		errors.Is(err, queries.ErrRetentionPolicyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotMessageAuthor):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the message's author can do this"})
+	case errors.Is(err, queries.ErrVersionMismatch):
+		// The client's If-Match version is stale.
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": attempted + ": " + err.Error()})
+	}
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportFlushRows is how many rows are written between flushes of an export.
+const exportFlushRows = 100
+
+// exportContentTypes maps export formats to the Content-Type they are sent with.
+var exportContentTypes = map[string]string{
+	service.FormatCSV:    "text/csv; charset=utf-8",
+	service.FormatNDJSON: "application/x-ndjson",
+	service.FormatZip:    "application/zip",
+}
+
+// exportEncoder writes the rows of an export in one format.
+type exportEncoder[T any] interface {
+	begin() error
+	encode(row T) error
+	flush() error
+	end() error
+}
+
+// exportSpec describes how to encode rows of type T in each export format.
+type exportSpec[T any] struct {
+	name    string   // File name without extension, e.g. "users".
+	columns []string // CSV header row.
+	record  func(T) []string
+	value   func(T) any // JSON representation, for NDJSON and zip.
+}
+
+// encoder returns an encoder writing rows to w in format.
+func (s exportSpec[T]) encoder(w io.Writer, format string) exportEncoder[T] {
+	switch format {
+	case service.FormatCSV:
+		return &csvEncoder[T]{w: csv.NewWriter(w), spec: s}
+	case service.FormatZip:
+		return &zipEncoder[T]{zw: zip.NewWriter(w), spec: s}
+	default:
+		return &ndjsonEncoder[T]{enc: json.NewEncoder(w), spec: s}
+	}
+}
+
+type csvEncoder[T any] struct {
+	w    *csv.Writer
+	spec exportSpec[T]
+}
+
+func (e *csvEncoder[T]) begin() error       { return e.w.Write(e.spec.columns) }
+func (e *csvEncoder[T]) encode(row T) error { return e.w.Write(e.spec.record(row)) }
+func (e *csvEncoder[T]) end() error         { return e.flush() }
+
+func (e *csvEncoder[T]) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonEncoder[T any] struct {
+	enc  *json.Encoder
+	spec exportSpec[T]
+}
+
+func (e *ndjsonEncoder[T]) begin() error       { return nil }
+func (e *ndjsonEncoder[T]) encode(row T) error { return e.enc.Encode(e.spec.value(row)) }
+func (e *ndjsonEncoder[T]) flush() error       { return nil }
+func (e *ndjsonEncoder[T]) end() error         { return nil }
+
+// zipEncoder writes a zip archive holding <name>.json, a JSON array of the rows.
+type zipEncoder[T any] struct {
+	zw    *zip.Writer
+	entry io.Writer
+	spec  exportSpec[T]
+	rows  int
+}
+
+func (e *zipEncoder[T]) begin() error {
+	entry, err := e.zw.Create(e.spec.name + ".json")
+	if err != nil {
+		return err
+	}
+	e.entry = entry
+	_, err = io.WriteString(entry, "[")
+	return err
+}
+
+func (e *zipEncoder[T]) encode(row T) error {
+	if e.rows > 0 {
+		if _, err := io.WriteString(e.entry, ",\n"); err != nil {
+			return err
+		}
+	}
+	e.rows++
+	b, err := json.Marshal(e.spec.value(row))
+	if err != nil {
+		return err
+	}
+	_, err = e.entry.Write(b)
+	return err
+}
+
+func (e *zipEncoder[T]) flush() error { return e.zw.Flush() }
+
+func (e *zipEncoder[T]) end() error {
+	if _, err := io.WriteString(e.entry, "]\n"); err != nil {
+		return err
+	}
+	return e.zw.Close()
+}
+
+// exportFormat returns the format query parameter, defaulting to CSV, or
+// responds with an error if it is not one of formats.
+func exportFormat(c *gin.Context, formats []string) (string, bool) {
+	format := c.DefaultQuery("format", service.FormatCSV)
+	if !slices.Contains(formats, format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format. Must be one of: " + strings.Join(formats, ", ")})
+		return "", false
+	}
+	return format, true
+}
+
+// streamExport writes the rows produced by export to the response as a file
+// download in format, flushing every exportFlushRows rows. Headers are only
+// sent with the first row, so an export failing up front is reported as an
+// error response; one failing later is cut short.
+func streamExport[T any](c *gin.Context, format string, spec exportSpec[T], export func(ctx context.Context, fn func(T) error) error, attempted string) {
+	enc := spec.encoder(c.Writer, format)
+	started, written := false, 0
+	start := func() error {
+		started = true
+		c.Header("Content-Type", exportContentTypes[format])
+		c.Header("Content-Disposition", `attachment; filename="`+spec.name+`.`+format+`"`)
+		c.Status(http.StatusOK)
+		return enc.begin()
+	}
+
+	err := export(c.Request.Context(), func(row T) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := enc.encode(row); err != nil {
+			return err
+		}
+		written++
+		if written%exportFlushRows == 0 {
+			if err := enc.flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		err = enc.end()
+	}
+	if err != nil && !started {
+		respondError(c, err, attempted)
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		c.Abort()
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type exportTestRow struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+var exportTestSpec = exportSpec[exportTestRow]{
+	name:    "rows",
+	columns: []string{"id", "name"},
+	record:  func(r exportTestRow) []string { return []string{strconv.Itoa(r.ID), r.Name} },
+	value:   func(r exportTestRow) any { return r },
+}
+
+func serveExport(format string, export func(ctx context.Context, fn func(exportTestRow) error) error) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/export", func(c *gin.Context) {
+		streamExport(c, format, exportTestSpec, export, "Failed to export rows")
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/export", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func exportRows(rows ...exportTestRow) func(ctx context.Context, fn func(exportTestRow) error) error {
+	return func(ctx context.Context, fn func(exportTestRow) error) error {
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestStreamExportCSV(t *testing.T) {
+	w := serveExport(service.FormatCSV, exportRows(exportTestRow{1, "a"}, exportTestRow{2, "b,c"}))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="rows.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "id,name\n1,a\n2,\"b,c\"\n", w.Body.String())
+}
+
+func TestStreamExportNDJSON(t *testing.T) {
+	w := serveExport(service.FormatNDJSON, exportRows(exportTestRow{1, "a"}, exportTestRow{2, "b"}))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"id\":1,\"name\":\"a\"}\n{\"id\":2,\"name\":\"b\"}\n", w.Body.String())
+}
+
+func TestStreamExportZip(t *testing.T) {
+	w := serveExport(service.FormatZip, exportRows(exportTestRow{1, "a"}, exportTestRow{2, "b"}))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="rows.zip"`, w.Header().Get("Content-Disposition"))
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	assert.NoError(t, err)
+	assert.Len(t, archive.File, 1)
+	assert.Equal(t, "rows.json", archive.File[0].Name)
+
+	f, err := archive.File[0].Open()
+	assert.NoError(t, err)
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"id":1,"name":"a"},{"id":2,"name":"b"}]`, string(content))
+}
+
+func TestStreamExportEmpty(t *testing.T) {
+	w := serveExport(service.FormatCSV, exportRows())
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "id,name\n", w.Body.String())
+
+	w = serveExport(service.FormatZip, exportRows())
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	assert.NoError(t, err)
+	f, _ := archive.File[0].Open()
+	content, _ := io.ReadAll(f)
+	assert.JSONEq(t, `[]`, string(content))
+}
+
+func TestStreamExportErrors(t *testing.T) {
+	// Failing before any row is reported as an error response.
+	w := serveExport(service.FormatCSV, func(ctx context.Context, fn func(exportTestRow) error) error {
+		return errors.New("connection refused")
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"Failed to export rows: connection refused"}`, w.Body.String())
+
+	// Failing later can only cut the response short.
+	w = serveExport(service.FormatCSV, func(ctx context.Context, fn func(exportTestRow) error) error {
+		fn(exportTestRow{1, "a"})
+		return errors.New("connection reset")
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "error")
+}
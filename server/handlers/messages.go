@@ -3,6 +3,13 @@ package handlers
 type CreateMessageRequest struct {
 	UserID  int    `json:"user_id" binding:"required"`
 	Content string `json:"content" binding:"required"`
+	// ExpiresAt makes the message ephemeral: an RFC 3339 timestamp after which it is hidden and deleted.
+	ExpiresAt *string `json:"expires_at"`
+	// ParentID makes the message a reply to another message.
+	ParentID *int `json:"parent_id"`
+	// ConversationID is the conversation to post in. Replies default to their
+	// parent's conversation and other messages to the default conversation, 1.
+	ConversationID *int `json:"conversation_id"`
 }
 
 type MessageResponse struct {
@@ -10,8 +17,101 @@ type MessageResponse struct {
 	UserID    int    `json:"user_id"`
 	Content   string `json:"content"`
 	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+	// ParentID is the message this one replies to.
+	ParentID       *int `json:"parent_id,omitempty"`
+	ConversationID int  `json:"conversation_id"`
+	// ReplyCount and LastReplyAt describe the direct replies to the message; omitted if there are none.
+	ReplyCount  int32  `json:"reply_count,omitempty"`
+	LastReplyAt string `json:"last_reply_at,omitempty"`
+	// Mentions are the users @mentioned in the content; Tags its #tags, lowercased. Omitted if there are none.
+	Mentions []MentionResponse `json:"mentions,omitempty"`
+	Tags     []string          `json:"tags,omitempty"`
+	// Reactions to the message, one per emoji in the order first used; omitted if there are none.
+	Reactions []ReactionResponse `json:"reactions,omitempty"`
+	// Attachments are the files attached to the message, oldest first; omitted if there are none.
+	Attachments []AttachmentResponse `json:"attachments,omitempty"`
+	// Held is set on messages moderation is holding for review, which are hidden until approved.
+	Held bool `json:"held,omitempty"`
+}
+
+type HeldMessageResponse struct {
+	MessageResponse
+	// Reasons describe why moderation held the message.
+	Reasons []string `json:"reasons"`
+	HeldAt  string   `json:"held_at"`
+}
+
+// ExportedMessageResponse is a message in an NDJSON or zip message export.
+type ExportedMessageResponse struct {
+	MessageResponse
+	// Archived is set on messages moved out of the live tables by a retention policy.
+	Archived bool `json:"archived,omitempty"`
+}
+
+type ModerationQueueResponse struct {
+	Messages []HeldMessageResponse `json:"messages"`
+}
+
+type AttachmentResponse struct {
+	ID          int    `json:"id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	// URL is a signed download URL, valid until URLExpiresAt; empty if it could not be signed.
+	URL          string `json:"url,omitempty"`
+	URLExpiresAt string `json:"url_expires_at,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type MentionResponse struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+}
+
+type ReactionResponse struct {
+	Emoji string `json:"emoji"`
+	Count int32  `json:"count"`
+	// ReactedByMe is whether the acting user (X-User-ID) reacted with this emoji.
+	ReactedByMe bool `json:"reacted_by_me"`
+}
+
+type AddReactionRequest struct {
+	Emoji string `json:"emoji" binding:"required"`
+}
+
+type MessageReactionsResponse struct {
+	MessageID int                `json:"message_id"`
+	Reactions []ReactionResponse `json:"reactions"`
 }
 
 type GetMessagesResponse struct {
 	Messages []MessageResponse `json:"messages"`
 }
+
+type CreateConversationRequest struct {
+	Title string `json:"title" binding:"required"`
+}
+
+type ConversationResponse struct {
+	ID        int    `json:"id"`
+	Title     string `json:"title"`
+	CreatedAt string `json:"created_at"`
+}
+
+type GetConversationsResponse struct {
+	Conversations []ConversationResponse `json:"conversations"`
+}
+
+type MarkReadRequest struct {
+	// MessageID is the newest message read; it and every older message in
+	// the conversation are marked read.
+	MessageID int `json:"message_id" binding:"required"`
+}
+
+type ReadMarkerResponse struct {
+	ConversationID    int    `json:"conversation_id"`
+	LastReadMessageID int    `json:"last_read_message_id"`
+	UnreadCount       int    `json:"unread_count"`
+	UpdatedAt         string `json:"updated_at"`
+}
@@ -3,34 +3,23 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"main/queries"
+	"main/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 func GetMessages(c *gin.Context) {
-	messageRows, err := queries.GetMessages()
+	messageRows, err := service.ListMessages(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Failed to retrieve messages: " + err.Error(),
-		})
+		respondError(c, err, "Failed to retrieve messages")
 		return
 	}
 
-	var messages []MessageResponse = []MessageResponse{}
-	for _, row := range messageRows {
-		messages = append(messages, MessageResponse{
-			ID:        row.ID,
-			UserID:    row.UserID,
-			Content:   row.Content,
-			CreatedAt: row.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
-		})
-	}
-
-	c.JSON(http.StatusOK, GetMessagesResponse{
-		Messages: messages,
-	})
+	respondMessages(c, messageRows)
 }
 
 func GetMessagesByUser(c *gin.Context) {
@@ -43,29 +32,177 @@ func GetMessagesByUser(c *gin.Context) {
 		return
 	}
 
-	messageRows, err := queries.GetMessagesByUser(userID)
+	messageRows, err := service.ListUserMessages(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Failed to retrieve messages: " + err.Error(),
-		})
+		respondError(c, err, "Failed to retrieve messages")
 		return
 	}
 
-	var messages []MessageResponse = []MessageResponse{}
-	for _, row := range messageRows {
-		messages = append(messages, MessageResponse{
-			ID:        row.ID,
-			UserID:    row.UserID,
-			Content:   row.Content,
-			CreatedAt: row.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
-		})
+	respondMessages(c, messageRows)
+}
+
+// GetReplies handles GET /messages/:message_id/replies requests.
+// Query parameters:
+//   - after_id: Only replies newer than this ID, for paging.
+//   - limit: Maximum number of replies (default 50, max 200).
+//
+// Response:
+//   - 200: JSON array of the direct replies to the message, oldest first.
+//   - 400: Error if parameters are invalid or the database query fails.
+//   - 404: Error if message is not found.
+func GetReplies(c *gin.Context) {
+	messageID, err := strconv.Atoi(c.Param("message_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	limit, afterID, ok := parseMessagePage(c, "after_id")
+	if !ok {
+		return
 	}
 
+	rows, err := service.ListReplies(c.Request.Context(), messageID, limit, afterID)
+	if err != nil {
+		respondError(c, err, "Failed to retrieve replies")
+		return
+	}
+	respondMessages(c, rows)
+}
+
+// GetMentions handles GET /users/:user_id/mentions requests.
+// Query parameters:
+//   - before_id: Only messages older than this ID, for paging.
+//   - limit: Maximum number of messages (default 50, max 200).
+//
+// Response:
+//   - 200: JSON array of the messages mentioning the user, newest first.
+//   - 400: Error if parameters are invalid or the database query fails.
+//   - 404: Error if user is not found.
+func GetMentions(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	limit, beforeID, ok := parseMessagePage(c, "before_id")
+	if !ok {
+		return
+	}
+
+	rows, err := service.ListMentions(c.Request.Context(), userID, limit, beforeID)
+	if err != nil {
+		respondError(c, err, "Failed to retrieve mentions")
+		return
+	}
+	respondMessages(c, rows)
+}
+
+// GetTagMessages handles GET /tags/:tag/messages requests. The tag is
+// matched case-insensitively, with or without a leading #.
+// Query parameters:
+//   - before_id: Only messages older than this ID, for paging.
+//   - limit: Maximum number of messages (default 50, max 200).
+//
+// Response:
+//   - 200: JSON array of the messages with the tag, newest first.
+//   - 400: Error if parameters are invalid or the database query fails.
+func GetTagMessages(c *gin.Context) {
+	limit, beforeID, ok := parseMessagePage(c, "before_id")
+	if !ok {
+		return
+	}
+
+	rows, err := service.ListTagMessages(c.Request.Context(), c.Param("tag"), limit, beforeID)
+	if err != nil {
+		respondError(c, err, "Failed to retrieve messages")
+		return
+	}
+	respondMessages(c, rows)
+}
+
+// parseMessagePage reads the limit query parameter and the message ID named
+// by cursor (before_id or after_id), writing a 400 response and returning
+// false if either is invalid.
+func parseMessagePage(c *gin.Context, cursor string) (limit, cursorID int, ok bool) {
+	page := []struct {
+		name string
+		dest *int
+	}{
+		{"limit", &limit},
+		{cursor, &cursorID},
+	}
+	for _, param := range page {
+		if value := c.Query(param.name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param.name})
+				return 0, 0, false
+			}
+			*param.dest = parsed
+		}
+	}
+	return limit, cursorID, true
+}
+
+// respondMessages writes rows as a GetMessagesResponse with their details.
+// reacted_by_me depends on the acting user, so the response varies on X-User-ID.
+func respondMessages(c *gin.Context, rows []queries.GetMessagesQueryRow) {
+	messages, modified, err := newMessageResponses(c, rows)
+	if err != nil {
+		respondError(c, err, "Failed to retrieve message details")
+		return
+	}
+	setLastModified(c, modified...)
+	c.Header("Vary", UserIDHeader)
+
 	c.JSON(http.StatusOK, GetMessagesResponse{
 		Messages: messages,
 	})
 }
 
+// newMessageResponses converts rows to their API representation with their
+// reactions, reply summaries, mentions, tags and attachments, each read in one
+// query for the whole list. It also returns the times the messages were last
+// changed by a new reply, reaction or attachment, for Last-Modified.
+func newMessageResponses(c *gin.Context, rows []queries.GetMessagesQueryRow) ([]MessageResponse, []time.Time, error) {
+	messageIDs := make([]int, 0, len(rows))
+	for _, row := range rows {
+		messageIDs = append(messageIDs, row.ID)
+	}
+	details, err := service.LoadMessageDetails(c.Request.Context(), messageIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	messages := make([]MessageResponse, 0, len(rows))
+	modified := make([]time.Time, 0, len(rows))
+	for _, row := range rows {
+		message := newMessageResponse(row)
+		modified = append(modified, row.CreatedAt.Time)
+		if summary, ok := details.Replies[row.ID]; ok {
+			message.ReplyCount = summary.ReplyCount
+			message.LastReplyAt = summary.LastReplyAt.Time.Format("2006-01-02T15:04:05Z07:00")
+			modified = append(modified, summary.LastReplyAt.Time)
+		}
+		for _, mention := range details.Mentions[row.ID] {
+			message.Mentions = append(message.Mentions, MentionResponse{UserID: mention.UserID, Username: mention.Username})
+		}
+		message.Tags = details.Tags[row.ID]
+		for _, summary := range details.Reactions[row.ID] {
+			message.Reactions = append(message.Reactions, newReactionResponse(summary))
+			modified = append(modified, summary.LastReactedAt.Time)
+		}
+		for _, attachment := range details.Attachments[row.ID] {
+			message.Attachments = append(message.Attachments, newAttachmentResponse(attachment))
+			// The signed URLs expire, so a copy older than them is stale.
+			modified = append(modified, attachment.CreatedAt.Time, attachment.URLExpiresAt.Add(-service.AttachmentURLExpiry))
+		}
+		messages = append(messages, message)
+	}
+	return messages, modified, nil
+}
+
 func CreateMessage(c *gin.Context) {
 	var req CreateMessageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -75,29 +212,63 @@ func CreateMessage(c *gin.Context) {
 		return
 	}
 
-	// Note: Since you're using binding:"required" tags, the validation
-	// will be handled by Gin's ShouldBindJSON, so you can remove the manual checks
-	// or keep them for additional validation
-
 	params := queries.CreateMessageParams{
-		UserID:  req.UserID,
-		Content: req.Content,
+		UserID:   req.UserID,
+		Content:  req.Content,
+		ParentID: req.ParentID,
+	}
+	if req.ConversationID != nil {
+		if *req.ConversationID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+			return
+		}
+		params.ConversationID = *req.ConversationID
+	}
+	if req.ExpiresAt != nil {
+		expiresAt, err := time.Parse(time.RFC3339, *req.ExpiresAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expires_at: must be an RFC 3339 timestamp"})
+			return
+		}
+		params.ExpiresAt = pgtype.Timestamptz{Time: expiresAt, Valid: true}
 	}
 
-	message, err := queries.CreateMessage(params)
+	message, err := service.CreateMessage(c.Request.Context(), params)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Failed to create message: " + err.Error(),
-		})
+		respondError(c, err, "Failed to create message")
 		return
 	}
 
-	response := MessageResponse{
-		ID:        message.ID,
-		UserID:    message.UserID,
-		Content:   message.Content,
-		CreatedAt: message.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	// A held message is stored but not yet posted.
+	status := http.StatusCreated
+	if message.Held {
+		status = http.StatusAccepted
+	}
+	messages, _, err := newMessageResponses(c, []queries.GetMessagesQueryRow{message})
+	if err != nil {
+		// The message was created, so report it without its details.
+		c.JSON(status, newMessageResponse(message))
+		return
 	}
+	c.JSON(status, messages[0])
+}
 
-	c.JSON(http.StatusCreated, response)
+// newMessageResponse converts a message query row into its API representation.
+func newMessageResponse(row queries.GetMessagesQueryRow) MessageResponse {
+	message := MessageResponse{
+		ID:             row.ID,
+		UserID:         row.UserID,
+		Content:        row.Content,
+		CreatedAt:      row.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		ConversationID: row.ConversationID,
+	}
+	if row.ExpiresAt.Valid {
+		message.ExpiresAt = row.ExpiresAt.Time.Format("2006-01-02T15:04:05Z07:00")
+	}
+	if row.ParentID.Valid {
+		parentID := int(row.ParentID.Int32)
+		message.ParentID = &parentID
+	}
+	message.Held = row.Held
+	return message
 }
@@ -8,7 +8,10 @@ import (
 	"testing"
 	"time"
 
+	"main/queries"
+
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -133,6 +136,87 @@ func TestCreateMessageErrors(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestCreateMessageInvalidExpiresAt(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/messages", CreateMessage)
+
+	req, _ := http.NewRequest("POST", "/messages", bytes.NewBufferString(`{"user_id":1,"content":"hi","expires_at":"tomorrow"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error":"Invalid expires_at: must be an RFC 3339 timestamp"}`, w.Body.String())
+}
+
+func TestGetRepliesInvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/messages/:message_id/replies", GetReplies)
+
+	cases := map[string]struct {
+		url, message string
+	}{
+		"message ID": {"/messages/abc/replies", "Invalid message ID"},
+		"limit":      {"/messages/1/replies?limit=0", "Invalid limit"},
+		"after ID":   {"/messages/1/replies?after_id=x", "Invalid after_id"},
+	}
+	for name, tc := range cases {
+		req, _ := http.NewRequest("GET", tc.url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+		assert.JSONEq(t, `{"error":"`+tc.message+`"}`, w.Body.String(), name)
+	}
+}
+
+func TestMentionsAndTagsInvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/users/:user_id/mentions", GetMentions)
+	router.GET("/tags/:tag/messages", GetTagMessages)
+
+	cases := map[string]struct {
+		url, message string
+	}{
+		"user ID":   {"/users/abc/mentions", "Invalid user ID"},
+		"before ID": {"/users/1/mentions?before_id=0", "Invalid before_id"},
+		"limit":     {"/tags/go/messages?limit=x", "Invalid limit"},
+		"tag":       {"/tags/not-a-tag/messages", "Invalid tag"},
+	}
+	for name, tc := range cases {
+		req, _ := http.NewRequest("GET", tc.url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+		assert.JSONEq(t, `{"error":"`+tc.message+`"}`, w.Body.String(), name)
+	}
+}
+
+func TestNewMessageResponseParentID(t *testing.T) {
+	row := queries.GetMessagesQueryRow{ID: 2, UserID: 1, Content: "hi"}
+	body, _ := json.Marshal(newMessageResponse(row))
+	assert.NotContains(t, string(body), "parent_id")
+	assert.NotContains(t, string(body), "reply_count")
+
+	row.ParentID = pgtype.Int4{Int32: 1, Valid: true}
+	assert.Equal(t, 1, *newMessageResponse(row).ParentID)
+}
+
+func TestNewMessageResponseExpiresAt(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	row := queries.GetMessagesQueryRow{ID: 1, UserID: 2, Content: "hi", CreatedAt: pgtype.Timestamptz{Time: created, Valid: true}}
+
+	body, _ := json.Marshal(newMessageResponse(row))
+	assert.NotContains(t, string(body), "expires_at")
+
+	row.ExpiresAt = pgtype.Timestamptz{Time: created.Add(time.Hour), Valid: true}
+	assert.Equal(t, "2024-01-02T04:04:05Z", newMessageResponse(row).ExpiresAt)
+}
+
 func TestGetMessages(t *testing.T) {
 	mockService := &MockMessageService{}
 	router := setupTestRouter(mockService)
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"main/queries"
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetModerationQueue handles GET /moderation/queue requests.
+// Query parameters:
+//   - after_id: Only messages newer than this ID, for paging.
+//   - limit: Maximum number of messages (default 50, max 200).
+//
+// Response:
+//   - 200: JSON object with messages, an array of the messages held for
+//     review, oldest first, with why each was held.
+//   - 400: Error if parameters are invalid or the database query fails.
+func GetModerationQueue(c *gin.Context) {
+	limit, afterID, ok := parseMessagePage(c, "after_id")
+	if !ok {
+		return
+	}
+
+	held, err := service.ModerationQueue(c.Request.Context(), limit, afterID)
+	if err != nil {
+		respondError(c, err, "Failed to retrieve moderation queue")
+		return
+	}
+
+	rows := make([]queries.GetMessagesQueryRow, 0, len(held))
+	for _, message := range held {
+		rows = append(rows, message.GetMessagesQueryRow)
+	}
+	messages, _, err := newMessageResponses(c, rows)
+	if err != nil {
+		respondError(c, err, "Failed to retrieve message details")
+		return
+	}
+
+	response := ModerationQueueResponse{Messages: make([]HeldMessageResponse, 0, len(held))}
+	for i, message := range held {
+		response.Messages = append(response.Messages, HeldMessageResponse{
+			MessageResponse: messages[i],
+			Reasons:         message.Reasons,
+			HeldAt:          message.HeldAt.Time.Format(time.RFC3339),
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ApproveMessage handles POST /moderation/queue/:message_id/approve
+// requests, posting a held message.
+// Response:
+//   - 200: JSON of the approved message.
+//   - 400: Error if message ID is invalid or the database query fails.
+//   - 404: Error if the message is not held for review.
+func ApproveMessage(c *gin.Context) {
+	messageID, err := strconv.Atoi(c.Param("message_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	message, err := service.ApproveMessage(c.Request.Context(), messageID)
+	if err != nil {
+		respondError(c, err, "Failed to approve message")
+		return
+	}
+
+	messages, _, err := newMessageResponses(c, []queries.GetMessagesQueryRow{message})
+	if err != nil {
+		// The message was approved, so report it without its details.
+		c.JSON(http.StatusOK, newMessageResponse(message))
+		return
+	}
+	c.JSON(http.StatusOK, messages[0])
+}
+
+// RejectMessage handles POST /moderation/queue/:message_id/reject requests,
+// deleting a held message.
+// Response:
+//   - 204: The message was deleted.
+//   - 400: Error if message ID is invalid or the database query fails.
+//   - 404: Error if the message is not held for review.
+func RejectMessage(c *gin.Context) {
+	messageID, err := strconv.Atoi(c.Param("message_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	if err := service.RejectMessage(c.Request.Context(), messageID); err != nil {
+		respondError(c, err, "Failed to reject message")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"main/moderation"
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModerationInvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(service.WithActor(c.Request.Context(), service.Actor{UserID: 1}))
+	})
+	router.GET("/moderation/queue", GetModerationQueue)
+	router.POST("/moderation/queue/:message_id/approve", ApproveMessage)
+	router.POST("/moderation/queue/:message_id/reject", RejectMessage)
+
+	cases := map[string]struct {
+		method, path, message string
+	}{
+		"limit":    {http.MethodGet, "/moderation/queue?limit=0", "Invalid limit"},
+		"after ID": {http.MethodGet, "/moderation/queue?after_id=x", "Invalid after_id"},
+		"approve":  {http.MethodPost, "/moderation/queue/abc/approve", "Invalid message ID"},
+		"reject":   {http.MethodPost, "/moderation/queue/abc/reject", "Invalid message ID"},
+	}
+	for name, tc := range cases {
+		req, _ := http.NewRequest(tc.method, tc.path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+		assert.JSONEq(t, `{"error":"`+tc.message+`"}`, w.Body.String(), name)
+	}
+}
+
+func TestCreateMessageRejectedByModeration(t *testing.T) {
+	service.ConfigureModeration(moderation.Chain{moderation.NewLinkBlocker(nil, moderation.Reject)})
+	t.Cleanup(func() { service.ConfigureModeration(nil) })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/messages", CreateMessage)
+
+	req, _ := http.NewRequest(http.MethodPost, "/messages", strings.NewReader(`{"user_id":1,"content":"buy at https://spam.example"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.JSONEq(t, `{"error":"Message rejected by moderation: links to spam.example"}`, w.Body.String())
+}
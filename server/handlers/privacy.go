@@ -0,0 +1,26 @@
+package handlers
+
+type EraseUserRequest struct {
+	// MessagePolicy is redact or delete; omit for the server default. Ignored when confirming.
+	MessagePolicy string `json:"message_policy"`
+	// ConfirmationToken from the response to an earlier request; omit to start an erasure.
+	ConfirmationToken string `json:"confirmation_token"`
+}
+
+type ErasureRequestResponse struct {
+	UserID            int    `json:"user_id"`
+	MessagePolicy     string `json:"message_policy"`
+	MessageCount      int32  `json:"message_count"`
+	ConfirmationToken string `json:"confirmation_token"`
+	ExpiresAt         string `json:"expires_at"`
+}
+
+type EraseUserResponse struct {
+	UserID        int    `json:"user_id"`
+	MessagePolicy string `json:"message_policy"`
+	// MessagesAffected is the number of messages redacted or deleted.
+	MessagesAffected int `json:"messages_affected"`
+	// ArchivedMessagesAffected is the number of archived messages redacted or deleted.
+	ArchivedMessagesAffected int    `json:"archived_messages_affected"`
+	ErasedAt                 string `json:"erased_at"`
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportUserData handles POST /users/:user_id/data-export requests with a
+// zip archive of the user's profile, messages and audit entries.
+// Restricted to the user and admins.
+// Response:
+//   - 200: The archive.
+//   - 400: Error if user_id is invalid or the database query fails before any output.
+//   - 404: Error if user is not found.
+func ExportUserData(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	started := false
+	err = service.ExportUserData(c.Request.Context(), userID, func() io.Writer {
+		started = true
+		c.Header("Content-Type", exportContentTypes[service.FormatZip])
+		c.Header("Content-Disposition", `attachment; filename="user-`+strconv.Itoa(userID)+`-data.zip"`)
+		c.Status(http.StatusOK)
+		return c.Writer
+	})
+	if err != nil && !started {
+		respondError(c, err, "Failed to export user data")
+		return
+	}
+	if err != nil {
+		// The response is already under way, so all that can be done is to cut it short.
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// EraseUser handles POST /users/:user_id/erase requests. Restricted to the
+// user and admins. Erasure takes two calls: the first, without a
+// confirmation_token, returns one that expires after 15 minutes; the second
+// sends it back and the user is erased.
+// Response:
+//   - 200: JSON summary of the erasure, once confirmed.
+//   - 202: JSON of the pending erasure request with its confirmation token.
+//   - 400: Error if the request is invalid, the token is invalid or expired, or the database query fails.
+//   - 404: Error if user is not found.
+func EraseUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req EraseUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	if req.ConfirmationToken == "" {
+		pending, err := service.RequestErasure(c.Request.Context(), userID, req.MessagePolicy)
+		if err != nil {
+			respondError(c, err, "Failed to request erasure")
+			return
+		}
+		c.JSON(http.StatusAccepted, ErasureRequestResponse{
+			UserID:            userID,
+			MessagePolicy:     pending.Request.MessagePolicy,
+			MessageCount:      pending.MessageCount,
+			ConfirmationToken: pending.Token,
+			ExpiresAt:         pending.Request.ExpiresAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		})
+		return
+	}
+
+	result, err := service.ConfirmErasure(c.Request.Context(), userID, req.ConfirmationToken)
+	if err != nil {
+		respondError(c, err, "Failed to erase user")
+		return
+	}
+	c.JSON(http.StatusOK, EraseUserResponse{
+		UserID:                   result.User.ID,
+		MessagePolicy:            result.MessagePolicy,
+		MessagesAffected:         result.Messages,
+		ArchivedMessagesAffected: result.ArchivedMessages,
+		ErasedAt:                 result.ErasedAt.Format("2006-01-02T15:04:05Z07:00"),
+	})
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrivacyInvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/users/:user_id/data-export", ExportUserData)
+	router.POST("/users/:user_id/erase", EraseUser)
+
+	cases := map[string]struct {
+		url, body, message string
+	}{
+		"export user ID": {"/users/abc/data-export", "", "Invalid user ID"},
+		"erase user ID":  {"/users/abc/erase", "", "Invalid user ID"},
+		"erase body":     {"/users/1/erase", "{", "Invalid request body: unexpected EOF"},
+		"message policy": {"/users/1/erase", `{"message_policy":"shred"}`, "Invalid message policy. Must be one of: redact, delete"},
+	}
+	for name, tc := range cases {
+		req, _ := http.NewRequest(http.MethodPost, tc.url, strings.NewReader(tc.body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+		assert.JSONEq(t, `{"error":"`+tc.message+`"}`, w.Body.String(), name)
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"main/queries"
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AddReaction handles POST /messages/:message_id/reactions requests, reacting
+// to a message as the acting user. Each user can react once with each emoji.
+// Response:
+//   - 200: JSON of the message's reactions, if the user had already reacted with the emoji.
+//   - 201: JSON of the message's reactions, including the new one.
+//   - 400: Error if the request is invalid or the database query fails.
+//   - 404: Error if message is not found.
+func AddReaction(c *gin.Context) {
+	messageID, err := strconv.Atoi(c.Param("message_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	var req AddReactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	summaries, added, err := service.AddReaction(c.Request.Context(), messageID, req.Emoji)
+	if err != nil {
+		respondError(c, err, "Failed to add reaction")
+		return
+	}
+
+	status := http.StatusOK
+	if added {
+		status = http.StatusCreated
+	}
+	c.JSON(status, newMessageReactionsResponse(messageID, summaries))
+}
+
+// RemoveReaction handles DELETE /messages/:message_id/reactions/:emoji
+// requests, removing the acting user's reaction with emoji, which is URL-encoded.
+// Response:
+//   - 204: The reaction was removed.
+//   - 400: Error if the request is invalid or the database query fails.
+//   - 404: Error if the user has not reacted to the message with emoji.
+func RemoveReaction(c *gin.Context) {
+	messageID, err := strconv.Atoi(c.Param("message_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	if err := service.RemoveReaction(c.Request.Context(), messageID, c.Param("emoji")); err != nil {
+		respondError(c, err, "Failed to remove reaction")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func newReactionResponse(summary queries.ReactionSummary) ReactionResponse {
+	return ReactionResponse{
+		Emoji:       summary.Emoji,
+		Count:       summary.Count,
+		ReactedByMe: summary.ReactedByViewer,
+	}
+}
+
+func newMessageReactionsResponse(messageID int, summaries []queries.ReactionSummary) MessageReactionsResponse {
+	reactions := make([]ReactionResponse, 0, len(summaries))
+	for _, summary := range summaries {
+		reactions = append(reactions, newReactionResponse(summary))
+	}
+	return MessageReactionsResponse{MessageID: messageID, Reactions: reactions}
+}
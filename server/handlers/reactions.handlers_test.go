@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"main/queries"
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReactionInvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(service.WithActor(c.Request.Context(), service.Actor{UserID: 1}))
+	})
+	router.POST("/messages/:message_id/reactions", AddReaction)
+	router.DELETE("/messages/:message_id/reactions/:emoji", RemoveReaction)
+
+	cases := map[string]struct {
+		method, url, body, message string
+	}{
+		"add message ID":    {http.MethodPost, "/messages/abc/reactions", `{"emoji":"👍"}`, "Invalid message ID"},
+		"add body":          {http.MethodPost, "/messages/1/reactions", "{", "Invalid request body: unexpected EOF"},
+		"add emoji":         {http.MethodPost, "/messages/1/reactions", `{"emoji":"thumbs up"}`, "Emoji must not contain spaces or control characters"},
+		"remove message ID": {http.MethodDelete, "/messages/abc/reactions/" + url.PathEscape("👍"), "", "Invalid message ID"},
+		"remove emoji":      {http.MethodDelete, "/messages/1/reactions/" + url.PathEscape("thumbs up"), "", "Emoji must not contain spaces or control characters"},
+	}
+	for name, tc := range cases {
+		req, _ := http.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+		assert.JSONEq(t, `{"error":"`+tc.message+`"}`, w.Body.String(), name)
+	}
+}
+
+func TestNewMessageReactionsResponse(t *testing.T) {
+	response := newMessageReactionsResponse(4, []queries.ReactionSummary{
+		{MessageID: 4, Emoji: "👍", Count: 3, ReactedByViewer: true},
+		{MessageID: 4, Emoji: "🎉", Count: 1},
+	})
+	body, _ := json.Marshal(response)
+	assert.JSONEq(t, `{"message_id":4,"reactions":[
+		{"emoji":"👍","count":3,"reacted_by_me":true},
+		{"emoji":"🎉","count":1,"reacted_by_me":false}
+	]}`, string(body))
+
+	body, _ = json.Marshal(newMessageReactionsResponse(4, nil))
+	assert.JSONEq(t, `{"message_id":4,"reactions":[]}`, string(body))
+}
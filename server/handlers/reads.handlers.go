@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MarkRead handles POST /conversations/:conversation_id/read requests,
+// marking messages in the conversation up to the given one read by the
+// acting user. The marker never moves back.
+// Response:
+//   - 200: JSON of the user's read marker and the conversation's remaining unread count.
+//   - 400: Error if the request is invalid or the database query fails.
+//   - 404: Error if conversation or message is not found.
+func MarkRead(c *gin.Context) {
+	conversationID, err := strconv.Atoi(c.Param("conversation_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return
+	}
+	var req MarkReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	state, err := service.MarkRead(c.Request.Context(), conversationID, req.MessageID)
+	if err != nil {
+		respondError(c, err, "Failed to mark messages read")
+		return
+	}
+	c.JSON(http.StatusOK, ReadMarkerResponse{
+		ConversationID:    state.Marker.ConversationID,
+		LastReadMessageID: state.Marker.LastReadMessageID,
+		UnreadCount:       state.UnreadCount,
+		UpdatedAt:         state.Marker.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	})
+}
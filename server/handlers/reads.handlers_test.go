@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkReadInvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(service.WithActor(c.Request.Context(), service.Actor{UserID: 1}))
+	})
+	router.POST("/conversations/:conversation_id/read", MarkRead)
+
+	cases := map[string]struct {
+		url, body, message string
+	}{
+		"conversation ID": {"/conversations/abc/read", `{"message_id":1}`, "Invalid conversation ID"},
+		"body":            {"/conversations/1/read", "{", "Invalid request body: unexpected EOF"},
+		"message ID":      {"/conversations/1/read", `{"message_id":-1}`, "Invalid message ID"},
+	}
+	for name, tc := range cases {
+		req, _ := http.NewRequest(http.MethodPost, tc.url, strings.NewReader(tc.body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+		assert.JSONEq(t, `{"error":"`+tc.message+`"}`, w.Body.String(), name)
+	}
+}
@@ -0,0 +1,28 @@
+package handlers
+
+type SetRetentionPolicyRequest struct {
+	// MaxAgeDays is the age in days after which messages are removed.
+	MaxAgeDays int `json:"max_age_days" binding:"required"`
+	// Action is purge to delete old messages or archive to move them to the archive.
+	Action string `json:"action" binding:"required"`
+}
+
+type RetentionPolicyResponse struct {
+	// Scope is default for the global policy, the user type the policy applies
+	// to, or conversation:<id> for the conversation it applies to.
+	Scope      string `json:"scope"`
+	MaxAgeDays int32  `json:"max_age_days"`
+	Action     string `json:"action"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+type GetRetentionPoliciesResponse struct {
+	Policies []RetentionPolicyResponse `json:"policies"`
+}
+
+type SweepMessagesResponse struct {
+	Expired  int `json:"expired"`
+	Purged   int `json:"purged"`
+	Archived int `json:"archived"`
+	Total    int `json:"total"`
+}
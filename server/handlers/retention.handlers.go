@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+
+	"main/queries"
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetRetentionPolicies handles GET /retention-policies requests. Restricted to admins.
+// Response:
+//   - 200: JSON array of retention policies: the global default first, then
+//     the user type policies, then the conversation policies.
+//   - 400: Error if the database query fails.
+func GetRetentionPolicies(c *gin.Context) {
+	rows, err := service.ListRetentionPolicies(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "Failed to retrieve retention policies")
+		return
+	}
+
+	policies := make([]RetentionPolicyResponse, 0, len(rows))
+	for _, row := range rows {
+		policies = append(policies, newRetentionPolicyResponse(row))
+	}
+	c.JSON(http.StatusOK, GetRetentionPoliciesResponse{Policies: policies})
+}
+
+// SetRetentionPolicy handles PUT /retention-policies/:scope requests, creating
+// or replacing the policy for a scope: default, a user type or
+// conversation:<id>. Restricted to admins.
+// Response:
+//   - 200: JSON of the policy.
+//   - 400: Error if the request is invalid or the database query fails.
+//   - 404: Error if the scope names a missing conversation.
+func SetRetentionPolicy(c *gin.Context) {
+	var req SetRetentionPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	policy, err := service.SetRetentionPolicy(c.Request.Context(), service.RetentionPolicy{
+		Scope:      c.Param("scope"),
+		MaxAgeDays: req.MaxAgeDays,
+		Action:     req.Action,
+	})
+	if err != nil {
+		respondError(c, err, "Failed to set retention policy")
+		return
+	}
+	c.JSON(http.StatusOK, newRetentionPolicyResponse(policy))
+}
+
+// DeleteRetentionPolicy handles DELETE /retention-policies/:scope requests.
+// Restricted to admins.
+// Response:
+//   - 204: The policy was deleted.
+//   - 400: Error if the scope is invalid or the database query fails.
+//   - 404: Error if the scope has no policy.
+func DeleteRetentionPolicy(c *gin.Context) {
+	if err := service.DeleteRetentionPolicy(c.Request.Context(), c.Param("scope")); err != nil {
+		respondError(c, err, "Failed to delete retention policy")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// SweepMessages handles POST /retention-policies/sweep requests, running the
+// retention sweeper now rather than waiting for its schedule. Restricted to admins.
+// Response:
+//   - 200: JSON counts of the messages removed.
+//   - 400: Error if the database query fails.
+func SweepMessages(c *gin.Context) {
+	result, err := service.SweepMessages(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "Failed to sweep messages")
+		return
+	}
+	c.JSON(http.StatusOK, SweepMessagesResponse{
+		Expired:  result.Expired,
+		Purged:   result.Purged,
+		Archived: result.Archived,
+		Total:    result.Total(),
+	})
+}
+
+func newRetentionPolicyResponse(row queries.RetentionPolicy) RetentionPolicyResponse {
+	return RetentionPolicyResponse{
+		Scope:      service.RetentionScope(row),
+		MaxAgeDays: row.MaxAgeDays,
+		Action:     row.Action,
+		UpdatedAt:  row.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
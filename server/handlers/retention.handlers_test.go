@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"main/queries"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionPolicyInvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PUT("/retention-policies/:scope", SetRetentionPolicy)
+	router.DELETE("/retention-policies/:scope", DeleteRetentionPolicy)
+
+	cases := map[string]struct {
+		method, url, body, message string
+	}{
+		"body":         {http.MethodPut, "/retention-policies/default", "{", "Invalid request body: unexpected EOF"},
+		"action":       {http.MethodPut, "/retention-policies/default", `{"max_age_days":30,"action":"shred"}`, "Invalid action. Must be one of: purge, archive"},
+		"max age":      {http.MethodPut, "/retention-policies/default", `{"max_age_days":-1,"action":"purge"}`, "max_age_days must be positive"},
+		"set scope":    {http.MethodPut, "/retention-policies/everyone", `{"max_age_days":30,"action":"purge"}`, "Invalid scope. Must be default, conversation:<id> or one of: UTYPE_USER, UTYPE_ADMIN, UTYPE_MODERATOR"},
+		"delete scope": {http.MethodDelete, "/retention-policies/everyone", "", "Invalid scope. Must be default, conversation:<id> or one of: UTYPE_USER, UTYPE_ADMIN, UTYPE_MODERATOR"},
+	}
+	for name, tc := range cases {
+		req, _ := http.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+		assert.JSONEq(t, `{"error":"`+tc.message+`"}`, w.Body.String(), name)
+	}
+}
+
+func TestNewRetentionPolicyResponse(t *testing.T) {
+	updated := pgtype.Timestamptz{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true}
+
+	global := newRetentionPolicyResponse(queries.RetentionPolicy{MaxAgeDays: 365, Action: "archive", UpdatedAt: updated})
+	assert.Equal(t, RetentionPolicyResponse{Scope: "default", MaxAgeDays: 365, Action: "archive", UpdatedAt: "2024-01-02T03:04:05Z"}, global)
+
+	perType := newRetentionPolicyResponse(queries.RetentionPolicy{
+		UserType:   pgtype.Text{String: "UTYPE_USER", Valid: true},
+		MaxAgeDays: 30,
+		Action:     "purge",
+		UpdatedAt:  updated,
+	})
+	assert.Equal(t, "UTYPE_USER", perType.Scope)
+
+	perConversation := newRetentionPolicyResponse(queries.RetentionPolicy{
+		ConversationID: pgtype.Int4{Int32: 2, Valid: true},
+		MaxAgeDays:     7,
+		Action:         "purge",
+		UpdatedAt:      updated,
+	})
+	assert.Equal(t, "conversation:2", perConversation.Scope)
+}
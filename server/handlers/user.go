@@ -18,6 +18,55 @@ type UserResponse struct {
 	UserType     string  `json:"userType"`
 	Nickname     *string `json:"nickname,omitempty"`
 	MessageCount int32   `json:"message_count"`
+	Version      int32   `json:"version"`
+	// UnreadCount and UnreadByConversation are only returned to the user
+	// themselves: their total number of unread messages, and the number in
+	// each conversation with any, keyed by conversation ID.
+	UnreadCount          *int           `json:"unread_count,omitempty"`
+	UnreadByConversation map[string]int `json:"unread_by_conversation,omitempty"`
+	// AvatarURL is the URL of the largest size of the user's avatar, and
+	// AvatarURLs those of every size keyed by width in pixels. Omitted if
+	// the user has no avatar.
+	AvatarURL  string            `json:"avatar_url,omitempty"`
+	AvatarURLs map[string]string `json:"avatar_urls,omitempty"`
+}
+
+// UserResponseV2 is the /v2 representation of a user. It differs from
+// UserResponse only in using snake_case for every field.
+type UserResponseV2 struct {
+	ID           int     `json:"id"`
+	Username     string  `json:"username"`
+	Email        string  `json:"email"`
+	UserType     string  `json:"user_type"`
+	Nickname     *string `json:"nickname,omitempty"`
+	MessageCount int32   `json:"message_count"`
+	Version      int32   `json:"version"`
+	UnreadCount  *int    `json:"unread_count,omitempty"`
+	// UnreadByConversation is keyed by conversation ID.
+	UnreadByConversation map[string]int    `json:"unread_by_conversation,omitempty"`
+	AvatarURL            string            `json:"avatar_url,omitempty"`
+	AvatarURLs           map[string]string `json:"avatar_urls,omitempty"`
+}
+
+type GetUsersResponseV2 struct {
+	Users []UserResponseV2 `json:"users"`
+}
+
+// V2 converts the user to its /v2 representation.
+func (u UserResponse) V2() UserResponseV2 {
+	return UserResponseV2{
+		ID:                   u.ID,
+		Username:             u.Username,
+		Email:                u.Email,
+		UserType:             u.UserType,
+		Nickname:             u.Nickname,
+		MessageCount:         u.MessageCount,
+		Version:              u.Version,
+		UnreadCount:          u.UnreadCount,
+		UnreadByConversation: u.UnreadByConversation,
+		AvatarURL:            u.AvatarURL,
+		AvatarURLs:           u.AvatarURLs,
+	}
 }
 
 type UpdateUserParams struct {
@@ -1,42 +1,77 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"main/queries"
+	"main/service"
 	"net/http"
-	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // GetUsers handles GET /users requests.
+// Last-Modified reflects the newest user update or message in the result.
 // Response:
 //   - 200: JSON list of all users.
 //   - 400: Error if database query fails.
 func GetUsers(c *gin.Context) {
-	userRows, err := queries.GetUsers()
+	userRows, err := service.ListUsers(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to retrieve users: " + err.Error()})
+		respondError(c, err, "Failed to retrieve users")
 		return
 	}
 
-	users := make([]UserResponse, 0, len(userRows))
+	updated := make([]time.Time, 0, len(userRows))
 	for _, row := range userRows {
-		var nickname *string
-		if row.Nickname.Valid {
-			nickname = &row.Nickname.String
-		}
-		users = append(users, UserResponse{
-			ID:           row.ID,
-			Username:     row.Username,
-			Email:        row.Email,
-			UserType:     row.UserType,
-			Nickname:     nickname,
-			MessageCount: row.MessageCount,
-		})
+		updated = append(updated, row.UpdatedAt.Time)
 	}
+	setLastModified(c, updated...)
+
+	respondUsers(c, userRows)
+}
 
-	c.JSON(http.StatusOK, GetUsersResponse{Users: users})
+// GetUser handles GET /users/:user_id requests.
+// Response:
+//   - 200: JSON of the user, with their unread counts if X-User-ID is the user.
+//   - 400: Error if user_id is invalid or database query fails.
+//   - 404: Error if user is not found.
+func GetUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := service.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "Failed to retrieve user")
+		return
+	}
+
+	// Only the user themselves sees their unread counts, which change without
+	// the user's version, so they are part of the ETag.
+	c.Header("Vary", UserIDHeader)
+	resp := newUserResponse(user)
+	etag := userETag(user.Version)
+	if service.ActorFrom(c.Request.Context()).UserID == userID {
+		unread, err := service.GetUnreadCounts(c.Request.Context(), userID)
+		if err != nil {
+			respondError(c, err, "Failed to count unread messages")
+			return
+		}
+		resp.UnreadCount = &unread.Total
+		resp.UnreadByConversation = map[string]int{}
+		for _, conversation := range unread.Conversations {
+			resp.UnreadByConversation[strconv.Itoa(conversation.ConversationID)] = conversation.Count
+		}
+		etag = viewerUserETag(user.Version, unread)
+	}
+	writeUser(c, http.StatusOK, etag, resp)
 }
 
 // CreateUser handles POST /users requests to create a new user.
@@ -51,19 +86,6 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
-	if req.Username == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Username is required"})
-		return
-	}
-	if req.Email == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is required"})
-		return
-	}
-	if req.UserType == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User type is required"})
-		return
-	}
-
 	params := queries.CreateUserParams{
 		Username:     req.Username,
 		Email:        req.Email,
@@ -72,37 +94,26 @@ func CreateUser(c *gin.Context) {
 		MessageCount: 0,
 	}
 
-	user, err := queries.CreateUser(params)
+	user, err := service.CreateUser(c.Request.Context(), params)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create user: " + err.Error()})
+		respondError(c, err, "Failed to create user")
 		return
 	}
 
-	var nickname *string
-	if user.Nickname.Valid {
-		nickname = &user.Nickname.String
-	}
-
-	resp := UserResponse{
-		ID:       user.ID,
-		Username: user.Username,
-		Email:    user.Email,
-		UserType: user.UserType,
-		Nickname: nickname,
-	}
-
-	c.JSON(http.StatusCreated, resp)
+	respondUser(c, http.StatusCreated, user)
 }
 
 // UpdateUser handles PATCH /users/:user_id requests.
 // Validates:
 //   - user_id as integer.
 //   - user_type (if provided) must be a valid role.
+//   - If-Match (if provided) must match the user's current ETag.
 //
 // Response:
 //   - 200: JSON of the updated user.
 //   - 400: Error if input validation fails.
 //   - 404: Error if user is not found.
+//   - 412: Error if the user was modified since the If-Match ETag was issued.
 func UpdateUser(c *gin.Context) {
 	userIDStr := c.Param("user_id")
 	userID, err := strconv.Atoi(userIDStr)
@@ -117,44 +128,115 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
-	if req.UserType != nil {
-		valid := slices.Contains([]string{"UTYPE_USER", "UTYPE_ADMIN", "UTYPE_MODERATOR"}, *req.UserType)
-		if !valid {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user type. Must be one of: UTYPE_USER, UTYPE_ADMIN, UTYPE_MODERATOR"})
-			return
-		}
-	}
-
 	updateParams := queries.UpdateUserParams{
 		Username: req.Username,
 		Email:    req.Email,
 		UserType: req.UserType,
 		Nickname: req.Nickname,
 	}
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" && ifMatch != "*" {
+		updateParams.MatchVersions = parseETags(ifMatch)
+		if len(updateParams.MatchVersions) == 0 {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": queries.ErrVersionMismatch.Error()})
+			return
+		}
+	}
 
-	user, err := queries.UpdateUser(userID, updateParams)
+	user, err := service.UpdateUser(c.Request.Context(), userID, updateParams)
 	if err != nil {
-		if err.Error() == "user not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, err, "Failed to update user")
+		return
+	}
+
+	respondUser(c, http.StatusOK, user)
+}
+
+// SearchUsers handles GET /users/search requests for typeahead lookups.
+// Matches the "q" query parameter against username, nickname and email.
+// Query parameters:
+//   - q: Search term (required).
+//   - limit: Maximum number of results (default 10, max 25).
+//
+// Response:
+//   - 200: JSON list of matching users, best matches first.
+//   - 400: Error if parameters are invalid or database query fails.
+func SearchUsers(c *gin.Context) {
+	limit := service.DefaultSearchLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
 			return
 		}
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update user: " + err.Error()})
+		limit = parsed
+	}
+
+	userRows, err := service.SearchUsers(c.Request.Context(), c.Query("q"), limit)
+	if err != nil {
+		respondError(c, err, "Failed to search users")
 		return
 	}
 
+	respondUsers(c, userRows)
+}
+
+// newUserResponse converts a user query row into its API representation.
+func newUserResponse(row queries.GetUsersQueryRow) UserResponse {
 	var nickname *string
-	if user.Nickname.Valid {
-		nickname = &user.Nickname.String
+	if row.Nickname.Valid {
+		nickname = &row.Nickname.String
+	}
+	user := UserResponse{
+		ID:           row.ID,
+		Username:     row.Username,
+		Email:        row.Email,
+		UserType:     row.UserType,
+		Nickname:     nickname,
+		MessageCount: row.MessageCount,
+		Version:      row.Version,
+	}
+	if row.AvatarKey.Valid {
+		user.AvatarURL = service.AvatarURL(row.AvatarKey.String, service.AvatarSizes[0])
+		user.AvatarURLs = map[string]string{}
+		for size, url := range service.AvatarURLs(row.AvatarKey.String) {
+			user.AvatarURLs[strconv.Itoa(size)] = url
+		}
 	}
+	return user
+}
 
-	resp := UserResponse{
-		ID:           user.ID,
-		Username:     user.Username,
-		Email:        user.Email,
-		UserType:     user.UserType,
-		Nickname:     nickname,
-		MessageCount: user.MessageCount,
+// userETag returns the entity tag for a user at the given version.
+func userETag(version int32) string {
+	return `"` + strconv.Itoa(int(version)) + `"`
+}
+
+// viewerUserETag returns the entity tag for a user at the given version as
+// seen by the user themselves: the version followed by a digest of their
+// unread counts.
+func viewerUserETag(version int32, unread service.UnreadCounts) string {
+	h := sha256.New()
+	for _, conversation := range unread.Conversations {
+		fmt.Fprintf(h, "%d:%d\n", conversation.ConversationID, conversation.Count)
 	}
+	return `"` + strconv.Itoa(int(version)) + "-" + hex.EncodeToString(h.Sum(nil)[:8]) + `"`
+}
 
-	c.JSON(http.StatusOK, resp)
+// parseETags parses an If-Match header into the user versions it names,
+// ignoring the unread digest of tags from viewerUserETag. Weak and malformed
+// entity tags can never match, so they are skipped.
+func parseETags(header string) []int32 {
+	var versions []int32
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		value, _, _ := strings.Cut(tag[1:len(tag)-1], "-")
+		version, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, int32(version))
+	}
+	return versions
 }
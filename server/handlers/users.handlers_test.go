@@ -7,6 +7,9 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"main/queries"
+	"main/service"
+
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/assert"
@@ -194,3 +197,75 @@ func TestGetUsers(t *testing.T) {
 
 	mockService.AssertExpectations(t)
 }
+
+func TestSearchUsersValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/users/search", SearchUsers)
+
+	cases := []string{
+		"/users/search",
+		"/users/search?q=%20%20",
+		"/users/search?q=li&limit=abc",
+		"/users/search?q=li&limit=0",
+	}
+	for _, url := range cases {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+}
+
+func TestGetUserInvalidID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/users/:user_id", GetUser)
+
+	req, _ := http.NewRequest("GET", "/users/abc", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid user ID")
+}
+
+func TestParseETags(t *testing.T) {
+	assert.Equal(t, []int32{3}, parseETags(`"3"`))
+	assert.Equal(t, []int32{3, 5}, parseETags(`"3", W/"4", "5"`))
+	assert.Empty(t, parseETags(`W/"3"`))
+	assert.Empty(t, parseETags(`"abc"`))
+	assert.Equal(t, []int32{3}, parseETags(viewerUserETag(3, service.UnreadCounts{})))
+}
+
+func TestViewerUserETag(t *testing.T) {
+	read := viewerUserETag(3, service.UnreadCounts{})
+	unread := viewerUserETag(3, service.UnreadCounts{
+		Total:         2,
+		Conversations: []queries.ConversationUnreadCount{{ConversationID: 1, Count: 2}},
+	})
+	moved := viewerUserETag(3, service.UnreadCounts{
+		Total:         2,
+		Conversations: []queries.ConversationUnreadCount{{ConversationID: 2, Count: 2}},
+	})
+
+	assert.Regexp(t, `^"3-[0-9a-f]{16}"$`, read)
+	assert.NotEqual(t, read, unread)
+	assert.NotEqual(t, unread, moved)
+	assert.NotEqual(t, userETag(3), read)
+}
+
+func TestUpdateUserUnmatchableIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PATCH("/users/:user_id", UpdateUser)
+
+	req, _ := http.NewRequest("PATCH", "/users/1", bytes.NewBufferString(`{"email":"new@example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `W/"1"`)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"main/queries"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const apiVersionKey = "api_version"
+
+// APIVersion returns middleware that tags requests in a route group with the
+// API version they were made against. Handlers are shared between versions and
+// only differ in how responses are rendered.
+func APIVersion(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionKey, version)
+		c.Next()
+	}
+}
+
+// Deprecated returns middleware for the legacy unversioned routes. It marks
+// responses with a Deprecation header and links to the same path under
+// successorPrefix (e.g. /v1).
+func Deprecated(successorPrefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+successorPrefix+c.Request.URL.Path+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
+
+// apiVersion returns the API version of the current request, defaulting to 1.
+func apiVersion(c *gin.Context) int {
+	if v, ok := c.Get(apiVersionKey); ok {
+		if version, ok := v.(int); ok {
+			return version
+		}
+	}
+	return 1
+}
+
+// respondUser writes a single user in the representation of the request's API
+// version, with an ETag for conditional updates.
+func respondUser(c *gin.Context, status int, row queries.GetUsersQueryRow) {
+	writeUser(c, status, userETag(row.Version), newUserResponse(row))
+}
+
+// writeUser writes resp, a user tagged etag, in the representation of the request's API version.
+func writeUser(c *gin.Context, status int, etag string, resp UserResponse) {
+	c.Header("ETag", etag)
+	if apiVersion(c) >= 2 {
+		c.JSON(status, resp.V2())
+		return
+	}
+	c.JSON(status, resp)
+}
+
+// respondUsers writes a list of users in the representation of the request's API version.
+func respondUsers(c *gin.Context, rows []queries.GetUsersQueryRow) {
+	if apiVersion(c) >= 2 {
+		users := make([]UserResponseV2, 0, len(rows))
+		for _, row := range rows {
+			users = append(users, newUserResponse(row).V2())
+		}
+		c.JSON(http.StatusOK, GetUsersResponseV2{Users: users})
+		return
+	}
+
+	users := make([]UserResponse, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, newUserResponse(row))
+	}
+	c.JSON(http.StatusOK, GetUsersResponse{Users: users})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"main/queries"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRespondUserVersions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	row := queries.GetUsersQueryRow{
+		ID:           1,
+		Username:     "liam",
+		Email:        "liam@email.com",
+		UserType:     "UTYPE_ADMIN",
+		Nickname:     pgtype.Text{String: "L dawg", Valid: true},
+		MessageCount: 3,
+		Version:      4,
+	}
+
+	router := gin.New()
+	handler := func(c *gin.Context) { respondUser(c, http.StatusOK, row) }
+	router.GET("/v1/user", APIVersion(1), handler)
+	router.GET("/v2/user", APIVersion(2), handler)
+
+	req, _ := http.NewRequest("GET", "/v1/user", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.JSONEq(t, `{"id":1,"username":"liam","email":"liam@email.com","userType":"UTYPE_ADMIN","nickname":"L dawg","message_count":3,"version":4}`, w.Body.String())
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+
+	req, _ = http.NewRequest("GET", "/v2/user", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.JSONEq(t, `{"id":1,"username":"liam","email":"liam@email.com","user_type":"UTYPE_ADMIN","nickname":"L dawg","message_count":3,"version":4}`, w.Body.String())
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+}
+
+func TestDeprecated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/users", Deprecated("/v1"), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req, _ := http.NewRequest("GET", "/users", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "true", w.Header().Get("Deprecation"))
+	assert.Equal(t, `</v1/users>; rel="successor-version"`, w.Header().Get("Link"))
+}
@@ -0,0 +1,50 @@
+package handlers
+
+type CreateWebhookRequest struct {
+	URL string `json:"url" binding:"required"`
+	// EventTypes to deliver; omit for all.
+	EventTypes []string `json:"event_types"`
+	// Secret signs deliveries; omit to have one generated.
+	Secret string `json:"secret"`
+}
+
+type WebhookResponse struct {
+	ID         int      `json:"id"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Active     bool     `json:"active"`
+	CreatedAt  string   `json:"created_at"`
+	// Secret is only returned when the webhook is created.
+	Secret *string `json:"secret,omitempty"`
+}
+
+type GetWebhooksResponse struct {
+	Webhooks []WebhookResponse `json:"webhooks"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             int64                            `json:"id"`
+	WebhookID      int                              `json:"webhook_id"`
+	EventID        int64                            `json:"event_id"`
+	EventType      string                           `json:"event_type"`
+	Payload        map[string]any                   `json:"payload"`
+	Status         string                           `json:"status"`
+	Attempts       int32                            `json:"attempts"`
+	NextAttemptAt  *string                          `json:"next_attempt_at"`
+	LastStatusCode *int32                           `json:"last_status_code"`
+	LastError      *string                          `json:"last_error"`
+	CreatedAt      string                           `json:"created_at"`
+	UpdatedAt      string                           `json:"updated_at"`
+	AttemptHistory []WebhookDeliveryAttemptResponse `json:"attempt_history,omitempty"`
+}
+
+type WebhookDeliveryAttemptResponse struct {
+	StatusCode  *int32  `json:"status_code"`
+	LatencyMS   int32   `json:"latency_ms"`
+	Error       *string `json:"error"`
+	AttemptedAt string  `json:"attempted_at"`
+}
+
+type GetWebhookDeliveriesResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+}
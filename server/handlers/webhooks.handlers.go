@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"main/queries"
+	"main/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateWebhook handles POST /webhooks requests. Restricted to admins.
+// Validates the URL, event types (omit for all) and secret (omit to generate one).
+// Response:
+//   - 201: JSON of the created webhook, including its secret.
+//   - 400: Error if validation or database insertion fails.
+func CreateWebhook(c *gin.Context) {
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	webhook, err := service.CreateWebhook(c.Request.Context(), queries.CreateWebhookParams{
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+	})
+	if err != nil {
+		respondError(c, err, "Failed to create webhook")
+		return
+	}
+
+	response := newWebhookResponse(webhook)
+	response.Secret = &webhook.Secret
+	c.JSON(http.StatusCreated, response)
+}
+
+// GetWebhooks handles GET /webhooks requests. Restricted to admins.
+// Response:
+//   - 200: JSON list of webhooks, without their secrets.
+//   - 400: Error if database query fails.
+func GetWebhooks(c *gin.Context) {
+	rows, err := service.ListWebhooks(c.Request.Context())
+	if err != nil {
+		respondError(c, err, "Failed to retrieve webhooks")
+		return
+	}
+
+	webhooks := make([]WebhookResponse, 0, len(rows))
+	for _, row := range rows {
+		webhooks = append(webhooks, newWebhookResponse(row))
+	}
+	c.JSON(http.StatusOK, GetWebhooksResponse{Webhooks: webhooks})
+}
+
+// DeleteWebhook handles DELETE /webhooks/:webhook_id requests. Restricted to admins.
+// Response:
+//   - 204: Webhook and its deliveries deleted.
+//   - 400: Error if webhook_id is invalid or database query fails.
+//   - 404: Error if webhook is not found.
+func DeleteWebhook(c *gin.Context) {
+	webhookID, ok := webhookIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := service.DeleteWebhook(c.Request.Context(), webhookID); err != nil {
+		respondError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// TestWebhook handles POST /webhooks/:webhook_id/test requests, sending a
+// sample webhook.test delivery right away. Restricted to admins.
+// Response:
+//   - 200: JSON of the delivery with the outcome of its first attempt.
+//   - 400: Error if webhook_id is invalid or database query fails.
+//   - 404: Error if webhook is not found.
+func TestWebhook(c *gin.Context) {
+	webhookID, ok := webhookIDParam(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := service.TestWebhook(c.Request.Context(), webhookID)
+	if err != nil {
+		respondError(c, err, "Failed to test webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, newWebhookDeliveryResponse(delivery))
+}
+
+// GetWebhookDeliveries handles GET /webhooks/:webhook_id/deliveries requests.
+// Restricted to admins.
+// Query parameters:
+//   - before_id: Only deliveries older than this ID, for paging.
+//   - limit: Maximum number of deliveries (default 50, max 200).
+//
+// Response:
+//   - 200: JSON list of the webhook's deliveries with their attempt history, newest first.
+//   - 400: Error if parameters are invalid or database query fails.
+//   - 404: Error if webhook is not found.
+func GetWebhookDeliveries(c *gin.Context) {
+	webhookID, ok := webhookIDParam(c)
+	if !ok {
+		return
+	}
+	limit, beforeID, ok := parseDeliveryPage(c)
+	if !ok {
+		return
+	}
+
+	rows, err := service.ListWebhookDeliveries(c.Request.Context(), webhookID, limit, beforeID)
+	if err != nil {
+		respondError(c, err, "Failed to retrieve deliveries")
+		return
+	}
+
+	respondDeliveries(c, rows)
+}
+
+// webhookIDParam reads the webhook_id path parameter, writing a 400
+// response and returning false if it is invalid.
+func webhookIDParam(c *gin.Context) (int, bool) {
+	webhookID, err := strconv.Atoi(c.Param("webhook_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return 0, false
+	}
+	return webhookID, true
+}
+
+// GetDeadLetters handles GET /webhooks/dead-letters requests. Restricted to admins.
+// Query parameters:
+//   - before_id: Only deliveries older than this ID, for paging.
+//   - limit: Maximum number of deliveries (default 50, max 200).
+//
+// Response:
+//   - 200: JSON list of dead webhook deliveries, newest first.
+//   - 400: Error if parameters are invalid or database query fails.
+func GetDeadLetters(c *gin.Context) {
+	limit, beforeID, ok := parseDeliveryPage(c)
+	if !ok {
+		return
+	}
+
+	rows, err := service.ListDeadLetters(c.Request.Context(), limit, beforeID)
+	if err != nil {
+		respondError(c, err, "Failed to retrieve dead letters")
+		return
+	}
+
+	respondDeliveries(c, rows)
+}
+
+// RetryDeadLetter handles POST /webhooks/dead-letters/:delivery_id/retry
+// requests, queueing a dead delivery to be sent again. Restricted to admins.
+// Response:
+//   - 202: JSON of the requeued delivery.
+//   - 400: Error if delivery_id is invalid or database query fails.
+//   - 404: Error if there is no dead delivery with that ID.
+func RetryDeadLetter(c *gin.Context) {
+	deliveryID, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+
+	delivery, err := service.RetryDeadLetter(c.Request.Context(), deliveryID)
+	if err != nil {
+		respondError(c, err, "Failed to retry delivery")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, newWebhookDeliveryResponse(delivery))
+}
+
+// parseDeliveryPage reads the limit and before_id query parameters, writing
+// a 400 response and returning false if either is invalid.
+func parseDeliveryPage(c *gin.Context) (limit int, beforeID int64, ok bool) {
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return 0, 0, false
+		}
+		limit = parsed
+	}
+	if value := c.Query("before_id"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before_id"})
+			return 0, 0, false
+		}
+		beforeID = parsed
+	}
+	return limit, beforeID, true
+}
+
+func respondDeliveries(c *gin.Context, rows []queries.WebhookDelivery) {
+	deliveries := make([]WebhookDeliveryResponse, 0, len(rows))
+	for _, row := range rows {
+		deliveries = append(deliveries, newWebhookDeliveryResponse(row))
+	}
+	c.JSON(http.StatusOK, GetWebhookDeliveriesResponse{Deliveries: deliveries})
+}
+
+// newWebhookResponse converts a webhook row into its API representation, without its secret.
+func newWebhookResponse(row queries.Webhook) WebhookResponse {
+	eventTypes := row.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	return WebhookResponse{
+		ID:         row.ID,
+		URL:        row.URL,
+		EventTypes: eventTypes,
+		Active:     row.Active,
+		CreatedAt:  row.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// newWebhookDeliveryResponse converts a delivery row into its API representation.
+func newWebhookDeliveryResponse(row queries.WebhookDelivery) WebhookDeliveryResponse {
+	delivery := WebhookDeliveryResponse{
+		ID:        row.ID,
+		WebhookID: row.WebhookID,
+		EventID:   row.EventID,
+		EventType: row.EventType,
+		Status:    row.Status,
+		Attempts:  row.Attempts,
+		CreatedAt: row.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt: row.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if row.Status == queries.DeliveryPending {
+		nextAttemptAt := row.NextAttemptAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		delivery.NextAttemptAt = &nextAttemptAt
+	}
+	if row.LastStatusCode.Valid {
+		delivery.LastStatusCode = &row.LastStatusCode.Int32
+	}
+	if row.LastError.Valid {
+		delivery.LastError = &row.LastError.String
+	}
+	json.Unmarshal(row.Payload, &delivery.Payload)
+
+	for _, attempt := range row.History {
+		response := WebhookDeliveryAttemptResponse{
+			LatencyMS:   attempt.LatencyMS,
+			AttemptedAt: attempt.AttemptedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		}
+		if attempt.StatusCode.Valid {
+			response.StatusCode = &attempt.StatusCode.Int32
+		}
+		if attempt.Error.Valid {
+			response.Error = &attempt.Error.String
+		}
+		delivery.AttemptHistory = append(delivery.AttemptHistory, response)
+	}
+	return delivery
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"main/queries"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookInvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/webhooks/dead-letters", GetDeadLetters)
+	router.POST("/webhooks/dead-letters/:delivery_id/retry", RetryDeadLetter)
+	router.POST("/webhooks", CreateWebhook)
+	router.DELETE("/webhooks/:webhook_id", DeleteWebhook)
+	router.POST("/webhooks/:webhook_id/test", TestWebhook)
+	router.GET("/webhooks/:webhook_id/deliveries", GetWebhookDeliveries)
+
+	cases := map[string]string{
+		"DELETE /webhooks/abc":                   "Invalid webhook ID",
+		"POST /webhooks/abc/test":                "Invalid webhook ID",
+		"GET /webhooks/abc/deliveries":           "Invalid webhook ID",
+		"GET /webhooks/1/deliveries?limit=-1":    "Invalid limit",
+		"GET /webhooks/dead-letters?limit=x":     "Invalid limit",
+		"GET /webhooks/dead-letters?before_id=0": "Invalid before_id",
+		"POST /webhooks/dead-letters/abc/retry":  "Invalid delivery ID",
+	}
+	for request, message := range cases {
+		method, url, _ := strings.Cut(request, " ")
+		req, _ := http.NewRequest(method, url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, request)
+		assert.JSONEq(t, `{"error":"`+message+`"}`, w.Body.String(), request)
+	}
+}
+
+func TestCreateWebhookValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/webhooks", CreateWebhook)
+
+	req, _ := http.NewRequest("POST", "/webhooks", strings.NewReader(`{"url":"https://example.com/hook","event_types":["user.deleted"]}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Unknown event type: user.deleted")
+}
+
+func TestNewWebhookDeliveryResponse(t *testing.T) {
+	created := pgtype.Timestamptz{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	delivery := newWebhookDeliveryResponse(queries.WebhookDelivery{
+		ID:             9,
+		WebhookID:      2,
+		EventID:        4,
+		EventType:      "message.created",
+		Payload:        []byte(`{"id":1,"content":"hi"}`),
+		Status:         queries.DeliveryDead,
+		Attempts:       8,
+		NextAttemptAt:  created,
+		LastStatusCode: pgtype.Int4{Int32: 500, Valid: true},
+		LastError:      pgtype.Text{String: "unexpected status 500 Internal Server Error", Valid: true},
+		CreatedAt:      created,
+		UpdatedAt:      created,
+		History: []queries.WebhookDeliveryAttempt{
+			{StatusCode: pgtype.Int4{Int32: 500, Valid: true}, LatencyMS: 42, AttemptedAt: created},
+			{LatencyMS: 10000, Error: pgtype.Text{String: "timeout", Valid: true}, AttemptedAt: created},
+		},
+	})
+
+	assert.Equal(t, map[string]any{"id": float64(1), "content": "hi"}, delivery.Payload)
+	assert.Nil(t, delivery.NextAttemptAt, "dead deliveries are not scheduled")
+	assert.Equal(t, int32(500), *delivery.LastStatusCode)
+	assert.Equal(t, "2025-01-01T00:00:00Z", delivery.CreatedAt)
+
+	assert.Len(t, delivery.AttemptHistory, 2)
+	assert.Equal(t, int32(500), *delivery.AttemptHistory[0].StatusCode)
+	assert.Equal(t, int32(42), delivery.AttemptHistory[0].LatencyMS)
+	assert.Nil(t, delivery.AttemptHistory[1].StatusCode)
+	assert.Equal(t, "timeout", *delivery.AttemptHistory[1].Error)
+}
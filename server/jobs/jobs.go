@@ -0,0 +1,65 @@
+// Package jobs runs background work queued in the Postgres jobs table.
+// Handlers are registered per typed Kind; jobs are retried with exponential
+// backoff and can be enqueued on a cron schedule. Any number of workers can
+// share the table since dequeueing uses FOR UPDATE SKIP LOCKED.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"main/queries"
+)
+
+// DefaultMaxAttempts is how many times a job is tried unless WithMaxAttempts says otherwise.
+const DefaultMaxAttempts = 5
+
+// Kind names a type of job whose arguments are an A, encoded as JSON.
+type Kind[A any] struct {
+	Name string
+}
+
+// NewKind returns the job kind called name.
+func NewKind[A any](name string) Kind[A] {
+	return Kind[A]{Name: name}
+}
+
+// Enqueuer adds jobs. queries.Queries implements it, so jobs can be enqueued
+// inside queries.WithTx and are only run if the transaction commits.
+type Enqueuer interface {
+	EnqueueJob(ctx context.Context, params queries.EnqueueJobParams) (int64, error)
+}
+
+// EnqueueOption configures Enqueue.
+type EnqueueOption func(*queries.EnqueueJobParams)
+
+// WithMaxAttempts sets how many times the job is tried (default DefaultMaxAttempts).
+func WithMaxAttempts(n int) EnqueueOption {
+	return func(p *queries.EnqueueJobParams) { p.MaxAttempts = n }
+}
+
+// WithRunAt delays the job until t.
+func WithRunAt(t time.Time) EnqueueOption {
+	return func(p *queries.EnqueueJobParams) { p.RunAt = t }
+}
+
+// WithUniqueKey makes the enqueue a no-op if a job with key already exists.
+func WithUniqueKey(key string) EnqueueOption {
+	return func(p *queries.EnqueueJobParams) { p.UniqueKey = key }
+}
+
+// Enqueue adds a job of kind with args. It returns the job's ID, or zero if
+// WithUniqueKey was given and the key is taken.
+func Enqueue[A any](ctx context.Context, e Enqueuer, kind Kind[A], args A, opts ...EnqueueOption) (int64, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return 0, err
+	}
+
+	params := queries.EnqueueJobParams{Kind: kind.Name, Args: data, MaxAttempts: DefaultMaxAttempts}
+	for _, opt := range opts {
+		opt(&params)
+	}
+	return e.EnqueueJob(ctx, params)
+}
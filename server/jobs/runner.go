@@ -0,0 +1,248 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"main/queries"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Store is the runner's view of the jobs table.
+type Store interface {
+	Enqueuer
+	DequeueJobs(ctx context.Context, workerID string, kinds []string, limit int) ([]queries.Job, error)
+	CompleteJob(ctx context.Context, jobID int64) error
+	FailJob(ctx context.Context, params queries.FailJobParams) error
+	RequeueStaleJobs(ctx context.Context, timeout time.Duration) (int, error)
+}
+
+// DB is the Store backed by the database, with a new connection per call.
+var DB Store = dbStore{}
+
+type dbStore struct{}
+
+func (dbStore) EnqueueJob(ctx context.Context, params queries.EnqueueJobParams) (int64, error) {
+	return queries.EnqueueJob(ctx, params)
+}
+func (dbStore) DequeueJobs(ctx context.Context, workerID string, kinds []string, limit int) ([]queries.Job, error) {
+	return queries.DequeueJobs(ctx, workerID, kinds, limit)
+}
+func (dbStore) CompleteJob(ctx context.Context, jobID int64) error {
+	return queries.CompleteJob(ctx, jobID)
+}
+func (dbStore) FailJob(ctx context.Context, params queries.FailJobParams) error {
+	return queries.FailJob(ctx, params)
+}
+func (dbStore) RequeueStaleJobs(ctx context.Context, timeout time.Duration) (int, error) {
+	return queries.RequeueStaleJobs(ctx, timeout)
+}
+
+type handler func(ctx context.Context, args json.RawMessage) error
+
+type schedule struct {
+	spec     string
+	schedule cron.Schedule
+	enqueue  func(ctx context.Context, runAt time.Time) error
+	last     time.Time
+}
+
+// Runner dequeues jobs and runs their registered handlers.
+type Runner struct {
+	Store    Store
+	WorkerID string
+	// Concurrency is how many jobs run at once.
+	Concurrency int
+	// Timeout bounds each job. Jobs running for twice as long are presumed
+	// abandoned by a dead worker and requeued.
+	Timeout time.Duration
+	// BaseBackoff is the delay before the first retry; it doubles per
+	// attempt up to MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Now returns the current time (defaults to time.Now).
+	Now func() time.Time
+
+	handlers  map[string]handler
+	schedules []*schedule
+}
+
+// NewRunner returns a runner backed by the database with default settings.
+func NewRunner() *Runner {
+	hostname, _ := os.Hostname()
+	return &Runner{
+		Store:       DB,
+		WorkerID:    hostname + ":" + strconv.Itoa(os.Getpid()),
+		Concurrency: 4,
+		Timeout:     5 * time.Minute,
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  time.Hour,
+		Now:         time.Now,
+		handlers:    map[string]handler{},
+	}
+}
+
+// Register sets the handler for jobs of kind. A handler's error fails the
+// attempt; the job is retried until it runs out of attempts.
+func Register[A any](r *Runner, kind Kind[A], fn func(ctx context.Context, args A) error) {
+	r.handlers[kind.Name] = func(ctx context.Context, data json.RawMessage) error {
+		var args A
+		if err := json.Unmarshal(data, &args); err != nil {
+			return fmt.Errorf("decode %s args: %w", kind.Name, err)
+		}
+		return fn(ctx, args)
+	}
+}
+
+// Schedule enqueues a job of kind with args at the times given by spec, a
+// standard five-field cron expression or a descriptor such as "@hourly" or
+// "@every 10m". Every worker can run the same schedules: each scheduled time
+// is enqueued once.
+func Schedule[A any](r *Runner, spec string, kind Kind[A], args A) error {
+	parsed, err := cron.ParseStandard(spec)
+	if err != nil {
+		return fmt.Errorf("schedule %s: %w", kind.Name, err)
+	}
+
+	r.schedules = append(r.schedules, &schedule{
+		spec:     spec,
+		schedule: parsed,
+		enqueue: func(ctx context.Context, runAt time.Time) error {
+			key := fmt.Sprintf("cron:%s:%s:%d", kind.Name, spec, runAt.Unix())
+			_, err := Enqueue(ctx, r.Store, kind, args, WithRunAt(runAt), WithUniqueKey(key))
+			return err
+		},
+	})
+	return nil
+}
+
+// Run works through due jobs every interval until ctx is done, then waits
+// for running jobs to finish.
+func (r *Runner) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.enqueueScheduled(ctx); err != nil {
+			log.Printf("jobs: %v", err)
+		}
+		if _, err := r.Store.RequeueStaleJobs(ctx, 2*r.Timeout); err != nil {
+			log.Printf("jobs: requeue stale jobs: %v", err)
+		}
+		// Keep going while there is a backlog.
+		for ctx.Err() == nil {
+			n, err := r.Work(ctx)
+			if err != nil {
+				log.Printf("jobs: %v", err)
+			}
+			if n < r.Concurrency {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// enqueueScheduled enqueues every scheduled time that has passed since the
+// previous call. Times before the first call are skipped, not backfilled.
+func (r *Runner) enqueueScheduled(ctx context.Context) error {
+	now := r.Now()
+	for _, s := range r.schedules {
+		if s.last.IsZero() {
+			s.last = now
+			continue
+		}
+		for next := s.schedule.Next(s.last); !next.After(now); next = s.schedule.Next(next) {
+			if err := s.enqueue(ctx, next); err != nil {
+				return fmt.Errorf("enqueue scheduled %s: %w", s.spec, err)
+			}
+			s.last = next
+		}
+	}
+	return nil
+}
+
+// Work runs up to Concurrency due jobs concurrently and waits for them.
+// Returns how many jobs were run.
+func (r *Runner) Work(ctx context.Context) (int, error) {
+	kinds := make([]string, 0, len(r.handlers))
+	for kind := range r.handlers {
+		kinds = append(kinds, kind)
+	}
+
+	jobs, err := r.Store.DequeueJobs(ctx, r.WorkerID, kinds, r.Concurrency)
+	if err != nil {
+		return 0, fmt.Errorf("dequeue: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.run(ctx, job); err != nil {
+				log.Printf("jobs: record job %d: %v", job.ID, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return len(jobs), nil
+}
+
+// run runs one job and records the outcome.
+func (r *Runner) run(ctx context.Context, job queries.Job) error {
+	err := r.call(ctx, job)
+	// The outcome is recorded even if ctx was cancelled during shutdown.
+	ctx = context.WithoutCancel(ctx)
+	if err == nil {
+		return r.Store.CompleteJob(ctx, job.ID)
+	}
+
+	log.Printf("jobs: %s job %d attempt %d failed: %v", job.Kind, job.ID, job.Attempts, err)
+	params := queries.FailJobParams{ID: job.ID, Error: err.Error()}
+	if job.Attempts < job.MaxAttempts {
+		params.RetryAt = r.Now().Add(r.backoff(int(job.Attempts)))
+	}
+	return r.Store.FailJob(ctx, params)
+}
+
+// call runs the job's handler with the job timeout, turning panics into errors.
+func (r *Runner) call(ctx context.Context, job queries.Job) (err error) {
+	h, ok := r.handlers[job.Kind]
+	if !ok {
+		return fmt.Errorf("no handler for %s", job.Kind)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return h(ctx, job.Args)
+}
+
+// backoff returns the delay after the given number of attempts: BaseBackoff
+// doubled per attempt, capped at MaxBackoff, with up to 20% jitter.
+func (r *Runner) backoff(attempts int) time.Duration {
+	delay := r.BaseBackoff
+	for i := 1; i < attempts && delay < r.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, r.MaxBackoff)
+	return delay + time.Duration(rand.Int64N(int64(delay)/5+1))
+}
@@ -0,0 +1,212 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"main/queries"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memStore is an in-memory Store.
+type memStore struct {
+	mu        sync.Mutex
+	nextID    int64
+	jobs      map[int64]*queries.Job
+	keys      map[string]bool
+	enqueued  []queries.EnqueueJobParams
+	completed []int64
+	failed    []queries.FailJobParams
+}
+
+func newMemStore() *memStore {
+	return &memStore{jobs: map[int64]*queries.Job{}, keys: map[string]bool{}}
+}
+
+func (s *memStore) EnqueueJob(ctx context.Context, params queries.EnqueueJobParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if params.UniqueKey != "" {
+		if s.keys[params.UniqueKey] {
+			return 0, nil
+		}
+		s.keys[params.UniqueKey] = true
+	}
+	s.nextID++
+	s.enqueued = append(s.enqueued, params)
+	s.jobs[s.nextID] = &queries.Job{
+		ID:          s.nextID,
+		Kind:        params.Kind,
+		Args:        params.Args,
+		Status:      queries.JobQueued,
+		MaxAttempts: int32(params.MaxAttempts),
+	}
+	return s.nextID, nil
+}
+
+func (s *memStore) DequeueJobs(ctx context.Context, workerID string, kinds []string, limit int) ([]queries.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var jobs []queries.Job
+	for id := int64(1); id <= s.nextID && len(jobs) < limit; id++ {
+		job := s.jobs[id]
+		if job.Status == queries.JobQueued {
+			job.Status = queries.JobRunning
+			job.Attempts++
+			jobs = append(jobs, *job)
+		}
+	}
+	return jobs, nil
+}
+
+func (s *memStore) CompleteJob(ctx context.Context, jobID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[jobID].Status = queries.JobSucceeded
+	s.completed = append(s.completed, jobID)
+	return nil
+}
+
+func (s *memStore) FailJob(ctx context.Context, params queries.FailJobParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[params.ID].Status = queries.JobQueued
+	if params.RetryAt.IsZero() {
+		s.jobs[params.ID].Status = queries.JobFailed
+	}
+	s.failed = append(s.failed, params)
+	return nil
+}
+
+func (s *memStore) RequeueStaleJobs(ctx context.Context, timeout time.Duration) (int, error) {
+	return 0, nil
+}
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+var greetJob = NewKind[greeting]("greet")
+
+func newTestRunner(store Store) *Runner {
+	r := NewRunner()
+	r.Store = store
+	return r
+}
+
+func TestEnqueueAndWork(t *testing.T) {
+	store := newMemStore()
+	r := newTestRunner(store)
+
+	var got []string
+	var mu sync.Mutex
+	Register(r, greetJob, func(ctx context.Context, args greeting) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, args.Name)
+		return nil
+	})
+
+	ctx := context.Background()
+	Enqueue(ctx, store, greetJob, greeting{Name: "liam"})
+	Enqueue(ctx, store, greetJob, greeting{Name: "jon"}, WithMaxAttempts(1))
+	assert.Equal(t, json.RawMessage(`{"name":"liam"}`), store.enqueued[0].Args)
+	assert.Equal(t, DefaultMaxAttempts, store.enqueued[0].MaxAttempts)
+	assert.Equal(t, 1, store.enqueued[1].MaxAttempts)
+
+	n, err := r.Work(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.ElementsMatch(t, []string{"liam", "jon"}, got)
+	assert.ElementsMatch(t, []int64{1, 2}, store.completed)
+}
+
+func TestFailedJobsRetryThenFail(t *testing.T) {
+	store := newMemStore()
+	r := newTestRunner(store)
+	Register(r, greetJob, func(ctx context.Context, args greeting) error {
+		if args.Name == "panic" {
+			panic("boom")
+		}
+		return errors.New("unavailable")
+	})
+
+	ctx := context.Background()
+	Enqueue(ctx, store, greetJob, greeting{}, WithMaxAttempts(2))
+	Enqueue(ctx, store, greetJob, greeting{Name: "panic"}, WithMaxAttempts(1))
+
+	r.Work(ctx)
+	assert.Len(t, store.failed, 2)
+	for _, failed := range store.failed {
+		if failed.ID == 1 {
+			assert.Equal(t, "unavailable", failed.Error)
+			assert.False(t, failed.RetryAt.IsZero(), "retried")
+		} else {
+			assert.Equal(t, "panic: boom", failed.Error)
+			assert.True(t, failed.RetryAt.IsZero(), "out of attempts")
+		}
+	}
+
+	r.Work(ctx)
+	assert.Equal(t, queries.JobFailed, store.jobs[1].Status)
+}
+
+func TestUnknownArgsFail(t *testing.T) {
+	store := newMemStore()
+	r := newTestRunner(store)
+	Register(r, greetJob, func(ctx context.Context, args greeting) error { return nil })
+	store.EnqueueJob(context.Background(), queries.EnqueueJobParams{Kind: "greet", Args: []byte(`[]`), MaxAttempts: 1})
+
+	r.Work(context.Background())
+	assert.Contains(t, store.failed[0].Error, "decode greet args")
+}
+
+func TestScheduleEnqueuesEachTimeOnce(t *testing.T) {
+	store := newMemStore()
+	now := time.Date(2025, 1, 1, 0, 0, 30, 0, time.UTC)
+	r := newTestRunner(store)
+	r.Now = func() time.Time { return now }
+	assert.NoError(t, Schedule(r, "@every 1m", greetJob, greeting{Name: "cron"}))
+
+	// A second worker running the same schedule.
+	other := newTestRunner(store)
+	other.Now = r.Now
+	Schedule(other, "@every 1m", greetJob, greeting{Name: "cron"})
+
+	ctx := context.Background()
+	r.enqueueScheduled(ctx)
+	other.enqueueScheduled(ctx)
+	assert.Empty(t, store.enqueued, "nothing is backfilled")
+
+	now = now.Add(150 * time.Second)
+	r.enqueueScheduled(ctx)
+	other.enqueueScheduled(ctx)
+	assert.Len(t, store.enqueued, 2)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 1, 30, 0, time.UTC), store.enqueued[0].RunAt)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 2, 30, 0, time.UTC), store.enqueued[1].RunAt)
+}
+
+func TestScheduleRejectsBadSpec(t *testing.T) {
+	err := Schedule(newTestRunner(newMemStore()), "every minute", greetJob, greeting{})
+	assert.ErrorContains(t, err, "schedule greet")
+}
+
+func TestBackoff(t *testing.T) {
+	r := newTestRunner(newMemStore())
+	r.BaseBackoff, r.MaxBackoff = time.Second, time.Minute
+
+	for attempts, want := range map[int]time.Duration{1: time.Second, 3: 4 * time.Second, 20: time.Minute} {
+		got := r.backoff(attempts)
+		assert.GreaterOrEqual(t, got, want)
+		assert.LessOrEqual(t, got, want+want/5)
+	}
+}
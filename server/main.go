@@ -1,23 +1,241 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"log"
-	"main/handlers"
-
-	"github.com/gin-gonic/gin"
+	"main/cache"
+	"main/grpcserver"
+	"main/jobs"
+	"main/moderation"
+	"main/router"
+	"main/service"
+	"main/storage"
+	"main/webhooks"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 )
 
+// Usage: server [serve|worker]
+//
+// "serve" runs the HTTP and gRPC APIs, "worker" runs background jobs and
+// webhook delivery, and with no argument the server does both.
 func main() {
+	mode := ""
+	if len(os.Args) > 1 {
+		mode = os.Args[1]
+	}
+	if mode != "" && mode != "serve" && mode != "worker" {
+		log.Fatalf("Unknown mode %q: expected serve or worker", mode)
+	}
+
 	log.Println("Server is starting...")
+	configureUserCache()
+	configureBlobStore()
+	configureModeration()
+	if policy := os.Getenv("ERASURE_MESSAGE_POLICY"); policy != "" {
+		if err := service.ConfigureErasure(policy); err != nil {
+			log.Fatalf("Invalid ERASURE_MESSAGE_POLICY %q: %v", policy, err)
+		}
+	}
 
-	r := gin.Default()
+	switch mode {
+	case "worker":
+		// Stop taking jobs on SIGINT / SIGTERM and let running ones finish.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		runWorker(ctx)
+	case "serve":
+		serve()
+	default:
+		go runWorker(context.Background())
+		serve()
+	}
+}
 
-	// endpoints
-	r.GET("/users", handlers.GetUsers)
-	r.POST("/users", handlers.CreateUser)
-	r.PATCH("/users/:user_id", handlers.UpdateUser)
-	r.GET("/messages", handlers.GetMessages)
-	r.POST("/messages", handlers.CreateMessage)
-	r.GET("/users/:user_id/messages", handlers.GetMessagesByUser)
+// serve runs the gRPC API in the background and the HTTP API until it fails.
+func serve() {
+	grpcPort := os.Getenv("GRPC_PORT")
+	if grpcPort == "" {
+		grpcPort = "9090"
+	}
+	lis, err := net.Listen("tcp", ":"+grpcPort)
+	if err != nil {
+		log.Fatalf("Failed to listen on gRPC port %s: %v", grpcPort, err)
+	}
+	go func() {
+		log.Printf("gRPC server listening on :%s", grpcPort)
+		if err := grpcserver.New().Serve(lis); err != nil {
+			log.Fatalf("gRPC server exited: %v", err)
+		}
+	}()
+
+	// HTTP port is read from PORT (default 8080)
+	r := router.New()
 	r.Run()
 }
+
+// runWorker delivers webhooks and runs background jobs until ctx is done,
+// then waits for running jobs to finish.
+//   - WEBHOOK_POLL_INTERVAL: how often the outbox is polled (default 2s).
+//   - JOB_POLL_INTERVAL: how often the jobs table is polled (default 5s).
+//   - JOB_CONCURRENCY: how many jobs run at once (default 4).
+func runWorker(ctx context.Context) {
+	go webhooks.NewDispatcher().Run(ctx, durationEnv("WEBHOOK_POLL_INTERVAL", 2*time.Second))
+
+	runner := jobs.NewRunner()
+	if v := os.Getenv("JOB_CONCURRENCY"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 {
+			log.Fatalf("Invalid JOB_CONCURRENCY %q", v)
+		}
+		runner.Concurrency = parsed
+	}
+	if err := service.RegisterJobs(runner); err != nil {
+		log.Fatalf("Failed to register jobs: %v", err)
+	}
+
+	log.Printf("Worker %s running %d jobs at a time", runner.WorkerID, runner.Concurrency)
+	runner.Run(ctx, durationEnv("JOB_POLL_INTERVAL", 5*time.Second))
+	log.Println("Worker stopped")
+}
+
+// durationEnv returns the positive duration in the environment variable
+// name, or def if it is unset.
+func durationEnv(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	parsed, err := time.ParseDuration(v)
+	if err != nil || parsed <= 0 {
+		log.Fatalf("Invalid %s %q", name, v)
+	}
+	return parsed
+}
+
+// configureUserCache sets up the user cache from the environment:
+//   - USER_CACHE_TTL: entry lifetime, e.g. "30s" (default 30s).
+//   - USER_CACHE_SIZE: in-process LRU capacity (default 1000).
+//   - REDIS_URL: if set, cache in Redis instead so instances share invalidations.
+func configureUserCache() {
+	ttl := durationEnv("USER_CACHE_TTL", service.DefaultUserCacheTTL)
+
+	if url := os.Getenv("REDIS_URL"); url != "" {
+		store, err := cache.NewRedisFromURL(url, "exercise:")
+		if err != nil {
+			log.Fatalf("Invalid REDIS_URL: %v", err)
+		}
+		service.ConfigureUserCache(store, ttl)
+		log.Printf("User cache: redis, ttl %s", ttl)
+		return
+	}
+
+	size := service.DefaultUserCacheSize
+	if v := os.Getenv("USER_CACHE_SIZE"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 {
+			log.Fatalf("Invalid USER_CACHE_SIZE %q", v)
+		}
+		size = parsed
+	}
+	service.ConfigureUserCache(cache.NewLRU(size), ttl)
+	log.Printf("User cache: in-process LRU of %d entries, ttl %s", size, ttl)
+}
+
+// configureBlobStore sets up attachment and avatar storage from the environment:
+//   - BLOB_STORE: "local" (default) or "s3".
+//   - BLOB_DIR: directory of the local store (default data/blobs).
+//   - PUBLIC_URL: URL clients reach the server at, which avatar URLs start
+//     with (default http://localhost:$PORT).
+//   - BLOB_BASE_URL: public URL of the local store's /blobs endpoint
+//     (default $PUBLIC_URL/blobs).
+//   - BLOB_SIGNING_KEY: secret signing the local store's download URLs. If it
+//     is unset a random one is used, so URLs only work on this instance until it restarts.
+//   - S3_ENDPOINT, S3_BUCKET, S3_REGION, S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY
+//     and S3_USE_SSL ("true" or "false", default true): the S3-compatible store.
+func configureBlobStore() {
+	publicURL := os.Getenv("PUBLIC_URL")
+	if publicURL == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "8080"
+		}
+		publicURL = "http://localhost:" + port
+	}
+	service.ConfigureAvatarBaseURL(publicURL)
+
+	switch kind := os.Getenv("BLOB_STORE"); kind {
+	case "", "local":
+		dir := os.Getenv("BLOB_DIR")
+		if dir == "" {
+			dir = "data/blobs"
+		}
+		baseURL := os.Getenv("BLOB_BASE_URL")
+		if baseURL == "" {
+			baseURL = strings.TrimSuffix(publicURL, "/") + "/blobs"
+		}
+		secret := []byte(os.Getenv("BLOB_SIGNING_KEY"))
+		if len(secret) == 0 {
+			log.Println("BLOB_SIGNING_KEY is not set: download URLs will stop working when the server restarts")
+			secret = make([]byte, 32)
+			rand.Read(secret)
+		}
+		store, err := storage.NewLocal(dir, baseURL, secret)
+		if err != nil {
+			log.Fatalf("Invalid local blob store: %v", err)
+		}
+		service.ConfigureBlobStore(store)
+		log.Printf("Blob store: local directory %s served at %s", dir, baseURL)
+	case "s3":
+		useSSL := true
+		if v := os.Getenv("S3_USE_SSL"); v != "" {
+			parsed, err := strconv.ParseBool(v)
+			if err != nil {
+				log.Fatalf("Invalid S3_USE_SSL %q", v)
+			}
+			useSSL = parsed
+		}
+		cfg := storage.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			Region:          os.Getenv("S3_REGION"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			UseSSL:          useSSL,
+		}
+		store, err := storage.NewS3(context.Background(), cfg)
+		if err != nil {
+			log.Fatalf("Failed to connect to S3 blob store: %v", err)
+		}
+		service.ConfigureBlobStore(store)
+		log.Printf("Blob store: S3 bucket %s at %s", cfg.Bucket, cfg.Endpoint)
+	default:
+		log.Fatalf("Unknown BLOB_STORE %q: expected local or s3", kind)
+	}
+}
+
+// configureModeration loads the moderation chain new messages pass through
+// from the JSON file named by MODERATION_CONFIG. Without one every message
+// is allowed.
+func configureModeration() {
+	path := os.Getenv("MODERATION_CONFIG")
+	if path == "" {
+		return
+	}
+	config, err := moderation.LoadConfig(path)
+	if err != nil {
+		log.Fatalf("Failed to load MODERATION_CONFIG: %v", err)
+	}
+	chain, err := config.Chain()
+	if err != nil {
+		log.Fatalf("Invalid MODERATION_CONFIG: %v", err)
+	}
+	service.ConfigureModeration(chain)
+	log.Printf("Moderation: %d steps from %s", len(chain), path)
+}
@@ -0,0 +1,128 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Classification is a classifier's assessment of content.
+type Classification struct {
+	// Score is how likely the content is to be abusive, from 0 to 1.
+	Score float64 `json:"score"`
+	// Labels name what the content was classified as, e.g. "spam".
+	Labels []string `json:"labels"`
+}
+
+// Classifier scores content, typically by calling an external service.
+type Classifier interface {
+	Classify(ctx context.Context, content string) (Classification, error)
+}
+
+// ClassifierFunc adapts a function to a Classifier.
+type ClassifierFunc func(ctx context.Context, content string) (Classification, error)
+
+func (f ClassifierFunc) Classify(ctx context.Context, content string) (Classification, error) {
+	return f(ctx, content)
+}
+
+// ClassifierStep holds or rejects content by its classification score.
+type ClassifierStep struct {
+	Classifier Classifier
+	// HoldAbove and RejectAbove are the scores at or above which content is
+	// held or rejected. Zero disables either.
+	HoldAbove   float64
+	RejectAbove float64
+	// OnError is the action taken when the classifier fails: Allow to fail
+	// open, or Hold to queue the content for a moderator.
+	OnError Action
+}
+
+func (s ClassifierStep) Check(ctx context.Context, content string) (Action, string, string, error) {
+	classification, err := s.Classifier.Classify(ctx, content)
+	if err != nil {
+		if s.OnError == Allow {
+			return Allow, content, "", nil
+		}
+		return s.OnError, content, "classifier failed: " + err.Error(), nil
+	}
+
+	action := Allow
+	switch {
+	case s.RejectAbove > 0 && classification.Score >= s.RejectAbove:
+		action = Reject
+	case s.HoldAbove > 0 && classification.Score >= s.HoldAbove:
+		action = Hold
+	default:
+		return Allow, content, "", nil
+	}
+	reason := "classifier score " + strconv.FormatFloat(classification.Score, 'f', 2, 64)
+	if len(classification.Labels) > 0 {
+		reason += " (" + strings.Join(classification.Labels, ", ") + ")"
+	}
+	return action, content, reason, nil
+}
+
+// HTTPClassifier calls an external classification service. It POSTs
+// {"content": "..."} to the URL and expects a JSON Classification in reply.
+type HTTPClassifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPClassifier returns a classifier calling url, giving up after timeout.
+func NewHTTPClassifier(url string, timeout time.Duration) *HTTPClassifier {
+	return &HTTPClassifier{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (c *HTTPClassifier) Classify(ctx context.Context, content string) (Classification, error) {
+	body, err := json.Marshal(map[string]string{"content": content})
+	if err != nil {
+		return Classification{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return Classification{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Classification{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Classification{}, fmt.Errorf("classifier returned %s", resp.Status)
+	}
+	var classification Classification
+	if err := json.NewDecoder(resp.Body).Decode(&classification); err != nil {
+		return Classification{}, fmt.Errorf("invalid classifier response: %w", err)
+	}
+	return classification, nil
+}
+
+// StubClassifier stands in for an external classifier in development and
+// tests. It scores content by the weights of the terms it contains,
+// matched case-insensitively, and labels it with those terms.
+type StubClassifier struct {
+	Terms map[string]float64
+}
+
+func (s StubClassifier) Classify(ctx context.Context, content string) (Classification, error) {
+	content = strings.ToLower(content)
+	var classification Classification
+	for term, weight := range s.Terms {
+		if strings.Contains(content, strings.ToLower(term)) {
+			classification.Score = max(classification.Score, weight)
+			classification.Labels = append(classification.Labels, term)
+		}
+	}
+	slices.Sort(classification.Labels)
+	return classification, nil
+}
@@ -0,0 +1,89 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifierStep(t *testing.T) {
+	score := func(s float64, labels ...string) Classifier {
+		return ClassifierFunc(func(ctx context.Context, content string) (Classification, error) {
+			return Classification{Score: s, Labels: labels}, nil
+		})
+	}
+	check := func(step ClassifierStep) (Action, string) {
+		action, masked, reason, err := step.Check(context.Background(), "content")
+		assert.NoError(t, err)
+		assert.Equal(t, "content", masked)
+		return action, reason
+	}
+
+	action, _ := check(ClassifierStep{Classifier: score(0.5), HoldAbove: 0.7, RejectAbove: 0.9})
+	assert.Equal(t, Allow, action)
+
+	action, reason := check(ClassifierStep{Classifier: score(0.7, "spam"), HoldAbove: 0.7, RejectAbove: 0.9})
+	assert.Equal(t, Hold, action)
+	assert.Equal(t, "classifier score 0.70 (spam)", reason)
+
+	action, reason = check(ClassifierStep{Classifier: score(0.95), HoldAbove: 0.7, RejectAbove: 0.9})
+	assert.Equal(t, Reject, action)
+	assert.Equal(t, "classifier score 0.95", reason)
+
+	// Zero thresholds are disabled.
+	action, _ = check(ClassifierStep{Classifier: score(1)})
+	assert.Equal(t, Allow, action)
+
+	failing := ClassifierFunc(func(ctx context.Context, content string) (Classification, error) {
+		return Classification{}, errors.New("timeout")
+	})
+	action, _ = check(ClassifierStep{Classifier: failing, HoldAbove: 0.5})
+	assert.Equal(t, Allow, action)
+	action, reason = check(ClassifierStep{Classifier: failing, HoldAbove: 0.5, OnError: Hold})
+	assert.Equal(t, Hold, action)
+	assert.Equal(t, "classifier failed: timeout", reason)
+}
+
+func TestHTTPClassifier(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Content string }
+		json.NewDecoder(r.Body).Decode(&req)
+		switch req.Content {
+		case "bad":
+			w.Write([]byte(`{"score": 0.9, "labels": ["toxic"]}`))
+		case "broken":
+			w.Write([]byte(`not json`))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	classifier := NewHTTPClassifier(server.URL, time.Second)
+
+	classification, err := classifier.Classify(context.Background(), "bad")
+	assert.NoError(t, err)
+	assert.Equal(t, Classification{Score: 0.9, Labels: []string{"toxic"}}, classification)
+
+	_, err = classifier.Classify(context.Background(), "broken")
+	assert.ErrorContains(t, err, "invalid classifier response")
+
+	_, err = classifier.Classify(context.Background(), "other")
+	assert.EqualError(t, err, "classifier returned 503 Service Unavailable")
+}
+
+func TestStubClassifier(t *testing.T) {
+	stub := StubClassifier{Terms: map[string]float64{"casino": 0.8, "free money": 0.95}}
+
+	classification, err := stub.Classify(context.Background(), "FREE MONEY at the Casino")
+	assert.NoError(t, err)
+	assert.Equal(t, Classification{Score: 0.95, Labels: []string{"casino", "free money"}}, classification)
+
+	classification, _ = StubClassifier{}.Classify(context.Background(), "anything")
+	assert.Equal(t, Classification{}, classification)
+}
@@ -0,0 +1,101 @@
+package moderation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Config describes a Chain in JSON. Steps run in the order of the fields:
+// word lists, patterns, links, then the classifier.
+type Config struct {
+	Words      []WordListConfig  `json:"words"`
+	Patterns   []PatternConfig   `json:"patterns"`
+	Links      *LinksConfig      `json:"links"`
+	Classifier *ClassifierConfig `json:"classifier"`
+}
+
+type WordListConfig struct {
+	Words  []string `json:"words"`
+	Action Action   `json:"action"`
+}
+
+type PatternConfig struct {
+	Pattern string `json:"pattern"`
+	Action  Action `json:"action"`
+	Reason  string `json:"reason"`
+}
+
+type LinksConfig struct {
+	AllowedDomains []string `json:"allowed_domains"`
+	Action         Action   `json:"action"`
+}
+
+type ClassifierConfig struct {
+	// URL is the external classifier's endpoint. Without one, StubTerms
+	// configure a StubClassifier.
+	URL         string             `json:"url"`
+	TimeoutMS   int                `json:"timeout_ms"`
+	StubTerms   map[string]float64 `json:"stub_terms"`
+	HoldAbove   float64            `json:"hold_above"`
+	RejectAbove float64            `json:"reject_above"`
+	OnError     Action             `json:"on_error"`
+}
+
+// LoadConfig reads a Config from a JSON file.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("invalid moderation config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Chain builds the chain the config describes.
+func (c Config) Chain() (Chain, error) {
+	var chain Chain
+	for _, words := range c.Words {
+		if words.Action == Allow {
+			return nil, fmt.Errorf("moderation word list %q needs an action other than allow", words.Words)
+		}
+		chain = append(chain, NewWordList(words.Words, words.Action))
+	}
+	for _, pattern := range c.Patterns {
+		if pattern.Action == Allow {
+			return nil, fmt.Errorf("moderation pattern %q needs an action other than allow", pattern.Pattern)
+		}
+		rule, err := NewRegexRule(pattern.Pattern, pattern.Action, pattern.Reason)
+		if err != nil {
+			return nil, fmt.Errorf("invalid moderation pattern %q: %w", pattern.Pattern, err)
+		}
+		chain = append(chain, rule)
+	}
+	if c.Links != nil {
+		if c.Links.Action == Allow {
+			return nil, fmt.Errorf("moderation links need an action other than allow")
+		}
+		chain = append(chain, NewLinkBlocker(c.Links.AllowedDomains, c.Links.Action))
+	}
+	if c.Classifier != nil {
+		var classifier Classifier = StubClassifier{Terms: c.Classifier.StubTerms}
+		if c.Classifier.URL != "" {
+			timeout := 2 * time.Second
+			if c.Classifier.TimeoutMS > 0 {
+				timeout = time.Duration(c.Classifier.TimeoutMS) * time.Millisecond
+			}
+			classifier = NewHTTPClassifier(c.Classifier.URL, timeout)
+		}
+		chain = append(chain, ClassifierStep{
+			Classifier:  classifier,
+			HoldAbove:   c.Classifier.HoldAbove,
+			RejectAbove: c.Classifier.RejectAbove,
+			OnError:     c.Classifier.OnError,
+		})
+	}
+	return chain, nil
+}
@@ -0,0 +1,60 @@
+package moderation
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "moderation.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{
+		"words": [{"words": ["darn"], "action": "mask"}, {"words": ["scam"], "action": "reject"}],
+		"patterns": [{"pattern": "(?i)buy now", "action": "hold", "reason": "sales pitch"}],
+		"links": {"allowed_domains": ["example.com"], "action": "hold"},
+		"classifier": {"stub_terms": {"casino": 0.8}, "hold_above": 0.7, "on_error": "hold"}
+	}`), 0o644))
+
+	config, err := LoadConfig(path)
+	assert.NoError(t, err)
+	chain, err := config.Chain()
+	assert.NoError(t, err)
+	assert.Len(t, chain, 5)
+
+	verdict, err := chain.Moderate(context.Background(), "darn, a casino")
+	assert.NoError(t, err)
+	assert.Equal(t, Verdict{Action: Hold, Content: "****, a casino", Reasons: []string{"blocked words: darn", "classifier score 0.80 (casino)"}}, verdict)
+
+	verdict, _ = chain.Moderate(context.Background(), "a scam")
+	assert.Equal(t, Reject, verdict.Action)
+
+	verdict, _ = chain.Moderate(context.Background(), "hello from https://example.com")
+	assert.Equal(t, Allow, verdict.Action)
+}
+
+func TestConfigInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "moderation.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"words": [{"words": ["x"], "action": "delete"}]}`), 0o644))
+	_, err := LoadConfig(path)
+	assert.ErrorContains(t, err, `unknown moderation action "delete"`)
+
+	_, err = LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+
+	for config, message := range map[*Config]string{
+		{Words: []WordListConfig{{Words: []string{"x"}}}}:         `moderation word list ["x"] needs an action other than allow`,
+		{Patterns: []PatternConfig{{Pattern: "x"}}}:               `moderation pattern "x" needs an action other than allow`,
+		{Patterns: []PatternConfig{{Pattern: "(", Action: Hold}}}: "invalid moderation pattern \"(\": error parsing regexp: missing closing ): `(`",
+		{Links: &LinksConfig{AllowedDomains: []string{"a.com"}}}:  "moderation links need an action other than allow",
+	} {
+		_, err := config.Chain()
+		assert.EqualError(t, err, message)
+	}
+
+	chain, err := Config{}.Chain()
+	assert.NoError(t, err)
+	assert.Empty(t, chain)
+}
@@ -0,0 +1,104 @@
+// Package moderation checks message content before it is posted. A Chain
+// runs a sequence of steps (word lists, regular expressions, link blocking
+// and an external classifier) and combines their verdicts: the strictest
+// action wins, and content masked by one step is what the next step sees.
+package moderation
+
+import (
+	"context"
+	"fmt"
+)
+
+// Action is what happens to a message. Actions are ordered from least to
+// most strict.
+type Action int
+
+const (
+	// Allow posts the message unchanged.
+	Allow Action = iota
+	// Mask posts the message with the offending parts replaced.
+	Mask
+	// Hold stores the message but hides it until a moderator approves it.
+	Hold
+	// Reject refuses the message.
+	Reject
+)
+
+var actionNames = []string{"allow", "mask", "hold", "reject"}
+
+func (a Action) String() string {
+	if a < Allow || a > Reject {
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+	return actionNames[a]
+}
+
+// ParseAction parses the name of an action, as returned by Action.String.
+func ParseAction(name string) (Action, error) {
+	for i, actionName := range actionNames {
+		if name == actionName {
+			return Action(i), nil
+		}
+	}
+	return Allow, fmt.Errorf("unknown moderation action %q: expected allow, mask, hold or reject", name)
+}
+
+func (a Action) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *Action) UnmarshalText(text []byte) error {
+	parsed, err := ParseAction(string(text))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// Verdict is the outcome of moderating some content.
+type Verdict struct {
+	Action Action
+	// Content is the content to post: the original, or with parts masked.
+	Content string
+	// Reasons describe why each step that did not allow the content acted.
+	Reasons []string
+}
+
+// Moderator decides what happens to content.
+type Moderator interface {
+	Moderate(ctx context.Context, content string) (Verdict, error)
+}
+
+// Step is one check in a Chain. It returns the action it takes, the content
+// with any parts it masks replaced, and a reason if the action is not Allow.
+type Step interface {
+	Check(ctx context.Context, content string) (action Action, masked string, reason string, err error)
+}
+
+// Chain runs its steps in order. The verdict's action is the strictest any
+// step took, and the chain stops at the first step that rejects. An empty
+// chain allows everything.
+type Chain []Step
+
+func (c Chain) Moderate(ctx context.Context, content string) (Verdict, error) {
+	verdict := Verdict{Action: Allow, Content: content}
+	for _, step := range c {
+		action, masked, reason, err := step.Check(ctx, verdict.Content)
+		if err != nil {
+			return Verdict{}, err
+		}
+		if action == Allow {
+			continue
+		}
+		if action == Mask {
+			verdict.Content = masked
+		}
+		verdict.Action = max(verdict.Action, action)
+		verdict.Reasons = append(verdict.Reasons, reason)
+		if action == Reject {
+			break
+		}
+	}
+	return verdict, nil
+}
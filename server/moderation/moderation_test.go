@@ -0,0 +1,72 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stepFunc adapts a function to a Step.
+type stepFunc func(ctx context.Context, content string) (Action, string, string, error)
+
+func (f stepFunc) Check(ctx context.Context, content string) (Action, string, string, error) {
+	return f(ctx, content)
+}
+
+func TestChain(t *testing.T) {
+	verdict, err := Chain{}.Moderate(context.Background(), "hello")
+	assert.NoError(t, err)
+	assert.Equal(t, Verdict{Action: Allow, Content: "hello"}, verdict)
+
+	var seen []string
+	record := func(action Action, masked, reason string) Step {
+		return stepFunc(func(ctx context.Context, content string) (Action, string, string, error) {
+			seen = append(seen, content)
+			return action, masked, reason, nil
+		})
+	}
+
+	chain := Chain{
+		record(Mask, "h*llo", "masked"),
+		record(Allow, "ignored", ""),
+		record(Hold, "ignored", "held"),
+		record(Mask, "h*ll*", "masked again"),
+	}
+	verdict, err = chain.Moderate(context.Background(), "hello")
+	assert.NoError(t, err)
+	assert.Equal(t, Verdict{Action: Hold, Content: "h*ll*", Reasons: []string{"masked", "held", "masked again"}}, verdict)
+	assert.Equal(t, []string{"hello", "h*llo", "h*llo", "h*llo"}, seen)
+
+	seen = nil
+	chain = Chain{record(Reject, "", "rejected"), record(Mask, "x", "not reached")}
+	verdict, err = chain.Moderate(context.Background(), "hello")
+	assert.NoError(t, err)
+	assert.Equal(t, Verdict{Action: Reject, Content: "hello", Reasons: []string{"rejected"}}, verdict)
+	assert.Len(t, seen, 1)
+
+	failing := stepFunc(func(ctx context.Context, content string) (Action, string, string, error) {
+		return Allow, content, "", errors.New("boom")
+	})
+	_, err = Chain{failing}.Moderate(context.Background(), "hello")
+	assert.EqualError(t, err, "boom")
+}
+
+func TestActionText(t *testing.T) {
+	for _, action := range []Action{Allow, Mask, Hold, Reject} {
+		parsed, err := ParseAction(action.String())
+		assert.NoError(t, err)
+		assert.Equal(t, action, parsed)
+	}
+	_, err := ParseAction("delete")
+	assert.EqualError(t, err, `unknown moderation action "delete": expected allow, mask, hold or reject`)
+	assert.Equal(t, "Action(7)", Action(7).String())
+
+	var decoded struct{ Action Action }
+	assert.NoError(t, json.Unmarshal([]byte(`{"Action":"hold"}`), &decoded))
+	assert.Equal(t, Hold, decoded.Action)
+	encoded, _ := json.Marshal(decoded)
+	assert.JSONEq(t, `{"Action":"hold"}`, string(encoded))
+}
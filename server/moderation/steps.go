@@ -0,0 +1,183 @@
+package moderation
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// mask replaces every match of re in content with one asterisk per character.
+func mask(re *regexp.Regexp, content string) string {
+	return maskRanges(content, re.FindAllStringIndex(content, -1))
+}
+
+// maskRanges replaces the [start, end) byte ranges of content with one
+// asterisk per character.
+func maskRanges(content string, ranges [][]int) string {
+	var b strings.Builder
+	last := 0
+	for _, r := range ranges {
+		b.WriteString(content[last:r[0]])
+		b.WriteString(strings.Repeat("*", utf8.RuneCountInString(content[r[0]:r[1]])))
+		last = r[1]
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+// isWordRune reports whether r can be part of a word.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// WordList acts on content containing any of a list of words, matched
+// case-insensitively as whole words.
+type WordList struct {
+	action Action
+	re     *regexp.Regexp
+}
+
+// NewWordList returns a step taking action on content containing any of words.
+func NewWordList(words []string, action Action) *WordList {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	if len(quoted) == 0 {
+		return &WordList{action: action}
+	}
+	// Longer words first, so that the longest of overlapping words matches.
+	slices.SortFunc(quoted, func(a, b string) int { return len(b) - len(a) })
+	return &WordList{action: action, re: regexp.MustCompile(`(?i)(?:` + strings.Join(quoted, "|") + `)`)}
+}
+
+// matches returns the byte ranges of the words in content that are not part
+// of longer words. Unlike \b, this works for words that start or end with
+// symbols, such as "c++", and for letters outside ASCII.
+func (w *WordList) matches(content string) [][]int {
+	var whole [][]int
+	for _, r := range w.re.FindAllStringIndex(content, -1) {
+		before, _ := utf8.DecodeLastRuneInString(content[:r[0]])
+		after, _ := utf8.DecodeRuneInString(content[r[1]:])
+		first, _ := utf8.DecodeRuneInString(content[r[0]:])
+		last, _ := utf8.DecodeLastRuneInString(content[:r[1]])
+		if r[0] > 0 && isWordRune(before) && isWordRune(first) {
+			continue
+		}
+		if r[1] < len(content) && isWordRune(after) && isWordRune(last) {
+			continue
+		}
+		whole = append(whole, r)
+	}
+	return whole
+}
+
+func (w *WordList) Check(ctx context.Context, content string) (Action, string, string, error) {
+	if w.re == nil {
+		return Allow, content, "", nil
+	}
+	ranges := w.matches(content)
+	if len(ranges) == 0 {
+		return Allow, content, "", nil
+	}
+	found := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		found = append(found, strings.ToLower(content[r[0]:r[1]]))
+	}
+	slices.Sort(found)
+	return w.action, maskRanges(content, ranges), "blocked words: " + strings.Join(slices.Compact(found), ", "), nil
+}
+
+// RegexRule acts on content matching a regular expression.
+type RegexRule struct {
+	action Action
+	re     *regexp.Regexp
+	reason string
+}
+
+// NewRegexRule returns a step taking action on content matching pattern,
+// giving reason. If reason is empty, the pattern is the reason.
+func NewRegexRule(pattern string, action Action, reason string) (*RegexRule, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if reason == "" {
+		reason = "matches " + pattern
+	}
+	return &RegexRule{action: action, re: re, reason: reason}, nil
+}
+
+func (r *RegexRule) Check(ctx context.Context, content string) (Action, string, string, error) {
+	if !r.re.MatchString(content) {
+		return Allow, content, "", nil
+	}
+	return r.action, mask(r.re, content), r.reason, nil
+}
+
+// linkPattern matches http(s) URLs and bare links starting with "www.".
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"']+`)
+
+// LinkBlocker acts on content linking to hosts outside a list of allowed
+// domains. Masking replaces the links with "[link removed]".
+type LinkBlocker struct {
+	action  Action
+	allowed []string
+}
+
+// NewLinkBlocker returns a step taking action on links to any host other
+// than allowedDomains and their subdomains. With no allowed domains, every
+// link is blocked.
+func NewLinkBlocker(allowedDomains []string, action Action) *LinkBlocker {
+	allowed := make([]string, 0, len(allowedDomains))
+	for _, domain := range allowedDomains {
+		allowed = append(allowed, strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "."))
+	}
+	return &LinkBlocker{action: action, allowed: allowed}
+}
+
+func (l *LinkBlocker) Check(ctx context.Context, content string) (Action, string, string, error) {
+	var blocked []string
+	masked := linkPattern.ReplaceAllStringFunc(content, func(match string) string {
+		// Punctuation ending a sentence is not part of the link.
+		link := strings.TrimRight(match, ".,;:!?)]}")
+		host := linkHost(link)
+		if l.allows(host) {
+			return match
+		}
+		blocked = append(blocked, host)
+		return "[link removed]" + match[len(link):]
+	})
+	if len(blocked) == 0 {
+		return Allow, content, "", nil
+	}
+	slices.Sort(blocked)
+	return l.action, masked, "links to " + strings.Join(slices.Compact(blocked), ", "), nil
+}
+
+func (l *LinkBlocker) allows(host string) bool {
+	for _, domain := range l.allowed {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// linkHost returns the lowercased host a link points to.
+func linkHost(link string) string {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.Hostname() == "" {
+		return "invalid link"
+	}
+	return strings.ToLower(parsed.Hostname())
+}
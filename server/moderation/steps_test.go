@@ -0,0 +1,71 @@
+package moderation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWordList(t *testing.T) {
+	words := NewWordList([]string{"darn", "heck", " ", "c++"}, Mask)
+
+	action, masked, reason, err := words.Check(context.Background(), "Darn it, what the heck, darn")
+	assert.NoError(t, err)
+	assert.Equal(t, Mask, action)
+	assert.Equal(t, "**** it, what the ****, ****", masked)
+	assert.Equal(t, "blocked words: darn, heck", reason)
+
+	// Whole words only.
+	action, _, _, _ = words.Check(context.Background(), "darnation and checkmate")
+	assert.Equal(t, Allow, action)
+
+	// Words are matched literally.
+	action, masked, _, _ = words.Check(context.Background(), "I like c++ code")
+	assert.Equal(t, Mask, action)
+	assert.Equal(t, "I like *** code", masked)
+
+	action, _, _, _ = NewWordList(nil, Reject).Check(context.Background(), "anything")
+	assert.Equal(t, Allow, action)
+}
+
+func TestRegexRule(t *testing.T) {
+	_, err := NewRegexRule("(", Hold, "")
+	assert.Error(t, err)
+
+	rule, err := NewRegexRule(`\b\d{4}-\d{4}-\d{4}-\d{4}\b`, Mask, "card number")
+	assert.NoError(t, err)
+	action, masked, reason, _ := rule.Check(context.Background(), "card 1234-5678-9012-3456 thanks")
+	assert.Equal(t, Mask, action)
+	assert.Equal(t, "card ******************* thanks", masked)
+	assert.Equal(t, "card number", reason)
+
+	action, _, _, _ = rule.Check(context.Background(), "no numbers here")
+	assert.Equal(t, Allow, action)
+
+	rule, _ = NewRegexRule(`(?i)buy now`, Hold, "")
+	_, _, reason, _ = rule.Check(context.Background(), "BUY NOW")
+	assert.Equal(t, "matches (?i)buy now", reason)
+}
+
+func TestLinkBlocker(t *testing.T) {
+	links := NewLinkBlocker([]string{"example.com", " .Docs.Org "}, Mask)
+
+	action, masked, reason, err := links.Check(context.Background(),
+		"see https://example.com/a, http://api.example.com, https://docs.org/x and www.spam.biz/deal or HTTP://EVIL.test:8080/?q=1")
+	assert.NoError(t, err)
+	assert.Equal(t, Mask, action)
+	assert.Equal(t, "see https://example.com/a, http://api.example.com, https://docs.org/x and [link removed] or [link removed]", masked)
+	assert.Equal(t, "links to evil.test, www.spam.biz", reason)
+
+	// Lookalike domains are not subdomains.
+	action, _, _, _ = links.Check(context.Background(), "https://notexample.com")
+	assert.Equal(t, Mask, action)
+
+	action, _, _, _ = links.Check(context.Background(), "no links, just example.com mentioned")
+	assert.Equal(t, Allow, action)
+
+	action, _, reason, _ = NewLinkBlocker(nil, Hold).Check(context.Background(), "https://example.com")
+	assert.Equal(t, Hold, action)
+	assert.Equal(t, "links to example.com", reason)
+}
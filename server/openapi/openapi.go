@@ -0,0 +1,340 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Route describes an API endpoint: how it is served and how it is documented.
+// The route table in package router is the single source for both router
+// registration and the generated OpenAPI document.
+type Route struct {
+	Method      string
+	Path        string // Gin-style path, e.g. /users/:user_id
+	Handler     gin.HandlerFunc
+	Middleware  []gin.HandlerFunc // Run before Handler; not documented.
+	Summary     string
+	OperationID string // Defaults to the handler's function name.
+	Deprecated  bool
+	Tags        []string
+	Params      []Param     // Query parameters and path parameter overrides.
+	Request     any         // Zero value of the JSON request body type, a Raw, or nil if none.
+	Responses   map[int]any // Status code to zero value of the response body type, a Raw, or nil for no body.
+}
+
+// Raw documents a request or response body that is not JSON, such as a CSV
+// file, as a string in each of the given media types.
+type Raw struct {
+	MediaTypes []string
+}
+
+// Param documents a single query or path parameter.
+type Param struct {
+	Name        string
+	In          string // "query", "header" or "path"
+	Type        string // JSON schema type, e.g. "string" or "integer"
+	Required    bool
+	Description string
+}
+
+// Document is the subset of an OpenAPI 3 document produced by Generate.
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var pathParamPattern = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+// Generate builds an OpenAPI 3 document for the given routes.
+// Request and response schemas are derived from the Go types by reflection,
+// using their json tags for property names and binding:"required" for required fields.
+func Generate(info Info, routes []Route) *Document {
+	doc := &Document{
+		OpenAPI:    "3.0.3",
+		Info:       info,
+		Paths:      map[string]map[string]*Operation{},
+		Components: Components{Schemas: map[string]*Schema{}},
+	}
+
+	for _, route := range routes {
+		path := SpecPath(route.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*Operation{}
+		}
+
+		op := &Operation{
+			OperationID: route.OperationID,
+			Summary:     route.Summary,
+			Deprecated:  route.Deprecated,
+			Tags:        route.Tags,
+			Parameters:  parameters(route),
+			Responses:   map[string]*Response{},
+		}
+		if op.OperationID == "" {
+			op.OperationID = HandlerName(route.Handler)
+		}
+		if route.Request != nil {
+			op.RequestBody = &RequestBody{Required: true, Content: doc.contentFor(route.Request)}
+		}
+		for status, body := range route.Responses {
+			resp := &Response{Description: http.StatusText(status)}
+			if body != nil {
+				resp.Content = doc.contentFor(body)
+			}
+			op.Responses[strconv.Itoa(status)] = resp
+		}
+
+		doc.Paths[path][strings.ToLower(route.Method)] = op
+	}
+
+	return doc
+}
+
+// contentFor returns the media types and schemas of a request or response body.
+func (doc *Document) contentFor(body any) map[string]*MediaType {
+	raw, ok := body.(Raw)
+	if !ok {
+		return map[string]*MediaType{
+			"application/json": {Schema: doc.schemaFor(reflect.TypeOf(body))},
+		}
+	}
+	content := map[string]*MediaType{}
+	for _, mediaType := range raw.MediaTypes {
+		content[mediaType] = &MediaType{Schema: &Schema{Type: "string"}}
+	}
+	return content
+}
+
+// SpecPath converts a Gin route path (/users/:user_id) to OpenAPI form (/users/{user_id}).
+func SpecPath(ginPath string) string {
+	return pathParamPattern.ReplaceAllString(ginPath, "{$1}")
+}
+
+// Handler serves the document as JSON.
+func Handler(doc *Document) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, doc)
+	}
+}
+
+// SwaggerUI serves a Swagger UI page that renders the document at specURL.
+func SwaggerUI(specURL string) gin.HandlerFunc {
+	page := strings.ReplaceAll(swaggerUIPage, "{{SPEC_URL}}", specURL)
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+	}
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <title>API Docs</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "{{SPEC_URL}}", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
+
+// parameters returns the documented parameters for a route. Path parameters
+// are derived from the route path; IDs (id, *_id) are typed as integers unless
+// overridden in Route.Params.
+func parameters(route Route) []Parameter {
+	declared := map[string]Param{}
+	for _, p := range route.Params {
+		declared[p.In+":"+p.Name] = p
+	}
+
+	var params []Parameter
+	for _, match := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+		name := match[1]
+		p, ok := declared["path:"+name]
+		if !ok {
+			p = Param{Name: name, In: "path", Type: "string"}
+			if name == "id" || strings.HasSuffix(name, "_id") {
+				p.Type = "integer"
+			}
+		}
+		params = append(params, Parameter{
+			Name:        name,
+			In:          "path",
+			Required:    true,
+			Description: p.Description,
+			Schema:      &Schema{Type: p.Type},
+		})
+	}
+
+	for _, p := range route.Params {
+		if p.In == "path" {
+			continue
+		}
+		params = append(params, Parameter{
+			Name:        p.Name,
+			In:          p.In,
+			Required:    p.Required,
+			Description: p.Description,
+			Schema:      &Schema{Type: p.Type},
+		})
+	}
+
+	return params
+}
+
+// schemaFor returns the schema for t, registering named struct types under
+// components/schemas and referencing them by $ref.
+func (doc *Document) schemaFor(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		s := doc.schemaFor(t.Elem())
+		if s.Ref != "" {
+			return s
+		}
+		s.Nullable = true
+		return s
+	}
+
+	if t.PkgPath() == "time" && t.Name() == "Time" {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: doc.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: doc.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return doc.structSchema(t)
+		}
+		if _, ok := doc.Components.Schemas[t.Name()]; !ok {
+			// Register before recursing so self-referencing types terminate.
+			doc.Components.Schemas[t.Name()] = &Schema{}
+			*doc.Components.Schemas[t.Name()] = *doc.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	default:
+		return &Schema{}
+	}
+}
+
+func (doc *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded := doc.structSchema(field.Type)
+			for k, v := range embedded.Properties {
+				s.Properties[k] = v
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		s.Properties[name] = doc.schemaFor(field.Type)
+		if strings.Contains(field.Tag.Get("binding"), "required") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	sort.Strings(s.Required)
+	return s
+}
+
+// HandlerName returns the unqualified function name of h, e.g. "GetUsers".
+func HandlerName(h gin.HandlerFunc) string {
+	if h == nil {
+		return ""
+	}
+	name := runtime.FuncForPC(reflect.ValueOf(h).Pointer()).Name()
+	name = name[strings.LastIndex(name, ".")+1:]
+	return strings.TrimSuffix(name, "-fm")
+}
@@ -0,0 +1,82 @@
+package openapi
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type testRequest struct {
+	Name     string   `json:"name" binding:"required"`
+	Nickname *string  `json:"nickname,omitempty"`
+	Tags     []string `json:"tags"`
+	Ignored  string   `json:"-"`
+}
+
+type testResponse struct {
+	Items []testRequest `json:"items"`
+}
+
+func testHandler(c *gin.Context) {}
+
+func TestSpecPath(t *testing.T) {
+	assert.Equal(t, "/users", SpecPath("/users"))
+	assert.Equal(t, "/users/{user_id}/messages", SpecPath("/users/:user_id/messages"))
+}
+
+func TestGenerate(t *testing.T) {
+	doc := Generate(Info{Title: "test", Version: "1"}, []Route{
+		{
+			Method:    http.MethodPost,
+			Path:      "/things/:thing_id",
+			Handler:   testHandler,
+			Request:   testRequest{},
+			Params:    []Param{{Name: "dry_run", In: "query", Type: "boolean"}},
+			Responses: map[int]any{http.StatusOK: testResponse{}, http.StatusNoContent: nil},
+		},
+	})
+
+	op := doc.Paths["/things/{thing_id}"]["post"]
+	assert.NotNil(t, op)
+	assert.Equal(t, "testHandler", op.OperationID)
+
+	assert.Len(t, op.Parameters, 2)
+	assert.Equal(t, "thing_id", op.Parameters[0].Name)
+	assert.Equal(t, "integer", op.Parameters[0].Schema.Type)
+	assert.True(t, op.Parameters[0].Required)
+	assert.Equal(t, "dry_run", op.Parameters[1].Name)
+
+	assert.Equal(t, "#/components/schemas/testRequest", op.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Nil(t, op.Responses["204"].Content)
+	assert.Equal(t, "No Content", op.Responses["204"].Description)
+
+	req := doc.Components.Schemas["testRequest"]
+	assert.Equal(t, []string{"name"}, req.Required)
+	assert.True(t, req.Properties["nickname"].Nullable)
+	assert.Equal(t, "array", req.Properties["tags"].Type)
+	assert.NotContains(t, req.Properties, "Ignored")
+
+	resp := doc.Components.Schemas["testResponse"]
+	assert.Equal(t, "#/components/schemas/testRequest", resp.Properties["items"].Items.Ref)
+}
+
+func TestGenerateRaw(t *testing.T) {
+	doc := Generate(Info{Title: "test", Version: "1"}, []Route{
+		{
+			Method:    http.MethodPost,
+			Path:      "/things/import",
+			Handler:   testHandler,
+			Request:   Raw{MediaTypes: []string{"text/csv", "application/x-ndjson"}},
+			Responses: map[int]any{http.StatusOK: Raw{MediaTypes: []string{"text/csv"}}},
+		},
+	})
+
+	op := doc.Paths["/things/import"]["post"]
+	assert.Len(t, op.RequestBody.Content, 2)
+	assert.Equal(t, "string", op.RequestBody.Content["text/csv"].Schema.Type)
+	assert.Equal(t, "string", op.RequestBody.Content["application/x-ndjson"].Schema.Type)
+	assert.Equal(t, "string", op.Responses["200"].Content["text/csv"].Schema.Type)
+	assert.NotContains(t, op.Responses["200"].Content, "application/json")
+}
@@ -0,0 +1,113 @@
+package queries
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const attachmentColumns = `id, message_id, storage_key, filename, content_type, size_bytes, created_at`
+
+// scanAttachment reads a row of attachmentColumns.
+func scanAttachment(row pgx.Row) (MessageAttachment, error) {
+	var attachment MessageAttachment
+	err := row.Scan(
+		&attachment.ID,
+		&attachment.MessageID,
+		&attachment.StorageKey,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.SizeBytes,
+		&attachment.CreatedAt,
+	)
+	return attachment, err
+}
+
+// scanAttachments reads all attachment rows and closes them.
+func scanAttachments(rows pgx.Rows) ([]MessageAttachment, error) {
+	defer rows.Close()
+
+	attachments := []MessageAttachment{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
+
+// CreateAttachment records a file attached to a message.
+func (q Queries) CreateAttachment(ctx context.Context, params CreateAttachmentParams) (MessageAttachment, error) {
+	return scanAttachment(q.db.QueryRow(ctx, `
+		INSERT INTO public.message_attachments (message_id, storage_key, filename, content_type, size_bytes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+attachmentColumns,
+		params.MessageID, params.StorageKey, params.Filename, params.ContentType, params.SizeBytes,
+	))
+}
+
+// CountAttachments returns the number of files attached to a message,
+// locking the message so concurrent uploads are counted one at a time.
+func (q Queries) CountAttachments(ctx context.Context, messageID int) (int, error) {
+	var count int
+	err := q.db.QueryRow(ctx, `
+		SELECT COUNT(a.id)
+		FROM (SELECT id FROM public.messages WHERE id = $1 FOR UPDATE) m
+		LEFT JOIN public.message_attachments a ON a.message_id = m.id
+	`, messageID).Scan(&count)
+	return count, err
+}
+
+// GetAttachments retrieves the files attached to the given messages in a
+// single query, ordered by message and then upload.
+func (q Queries) GetAttachments(ctx context.Context, messageIDs []int) ([]MessageAttachment, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT `+attachmentColumns+`
+		FROM public.message_attachments
+		WHERE message_id = ANY($1)
+		ORDER BY message_id, id
+	`, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanAttachments(rows)
+}
+
+// GetAttachments runs Queries.GetAttachments on a new connection.
+func GetAttachments(ctx context.Context, messageIDs []int) ([]MessageAttachment, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]MessageAttachment, error) {
+		return q.GetAttachments(ctx, messageIDs)
+	})
+}
+
+// GetDetachedAttachments retrieves up to limit attachments whose message has
+// been deleted, and not archived, locking them so concurrent workers take
+// different ones.
+func (q Queries) GetDetachedAttachments(ctx context.Context, limit int) ([]MessageAttachment, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT `+attachmentColumns+`
+		FROM public.message_attachments
+		WHERE message_id IS NULL AND archived_message_id IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanAttachments(rows)
+}
+
+// DeleteAttachment deletes an attachment's row.
+func (q Queries) DeleteAttachment(ctx context.Context, attachmentID int) error {
+	_, err := q.db.Exec(ctx, `
+		DELETE FROM public.message_attachments
+		WHERE id = $1
+	`, attachmentID)
+	return err
+}
@@ -0,0 +1,22 @@
+package queries
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+type MessageAttachment struct {
+	ID int `db:"id"`
+	// MessageID is null once the message has been deleted or archived.
+	MessageID   pgtype.Int4        `db:"message_id"`
+	StorageKey  string             `db:"storage_key"`
+	Filename    string             `db:"filename"`
+	ContentType string             `db:"content_type"`
+	SizeBytes   int64              `db:"size_bytes"`
+	CreatedAt   pgtype.Timestamptz `db:"created_at"`
+}
+
+type CreateAttachmentParams struct {
+	MessageID   int
+	StorageKey  string
+	Filename    string
+	ContentType string
+	SizeBytes   int64
+}
@@ -0,0 +1,161 @@
+package queries
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// CreateAuditEvent records a change. Call it in the same transaction as the
+// change so the trail cannot miss committed writes.
+func (q Queries) CreateAuditEvent(ctx context.Context, params CreateAuditEventParams) error {
+	var actorID pgtype.Int4
+	if params.ActorID != nil {
+		actorID = pgtype.Int4{Int32: int32(*params.ActorID), Valid: true}
+	}
+
+	_, err := q.db.Exec(ctx, `
+		INSERT INTO public.audit_events (actor_id, action, target_type, target_id, before, after, ip, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`,
+		actorID,
+		params.Action,
+		params.TargetType,
+		params.TargetID,
+		nullJSON(params.Before),
+		nullJSON(params.After),
+		pgtype.Text{String: params.IP, Valid: params.IP != ""},
+		pgtype.Text{String: params.RequestID, Valid: params.RequestID != ""},
+	)
+	return err
+}
+
+// GetAuditEvents returns audit events matching filter, newest first.
+func (q Queries) GetAuditEvents(ctx context.Context, filter AuditEventFilter) ([]AuditEvent, error) {
+	where, args := auditEventConditions(filter)
+	args = append(args, filter.Limit)
+
+	rows, err := q.db.Query(ctx, fmt.Sprintf(`
+		SELECT `+auditEventColumns+`
+		FROM public.audit_events
+		WHERE %s
+		ORDER BY id DESC
+		LIMIT $%d
+	`, where, len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []AuditEvent{}
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// GetAuditEvents runs Queries.GetAuditEvents on a new connection.
+func GetAuditEvents(ctx context.Context, filter AuditEventFilter) ([]AuditEvent, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]AuditEvent, error) {
+		return q.GetAuditEvents(ctx, filter)
+	})
+}
+
+// ExportUserAuditEvents calls fn for every audit event made by a user or on
+// the user or their messages, oldest first, stopping at the first error from fn.
+func (q Queries) ExportUserAuditEvents(ctx context.Context, userID int, fn func(AuditEvent) error) error {
+	rows, err := q.db.Query(ctx, `
+		SELECT `+auditEventColumns+`
+		FROM public.audit_events
+		WHERE actor_id = $1
+			OR (target_type = 'user' AND target_id = $1)
+			OR (target_type = 'message' AND target_id IN (SELECT id FROM public.messages WHERE user_id = $1))
+			OR (target_type = 'message' AND target_id IN (SELECT id FROM public.archived_messages WHERE user_id = $1))
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+const auditEventColumns = `id, actor_id, action, target_type, target_id, before, after, ip, request_id, created_at`
+
+func scanAuditEvent(row pgx.Row) (AuditEvent, error) {
+	var event AuditEvent
+	err := row.Scan(
+		&event.ID,
+		&event.ActorID,
+		&event.Action,
+		&event.TargetType,
+		&event.TargetID,
+		&event.Before,
+		&event.After,
+		&event.IP,
+		&event.RequestID,
+		&event.CreatedAt,
+	)
+	return event, err
+}
+
+// auditEventConditions builds the WHERE clause for filter.
+func auditEventConditions(filter AuditEventFilter) (string, []any) {
+	conditions := []string{"TRUE"}
+	args := []any{}
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.ActorID != 0 {
+		add("actor_id = $%d", filter.ActorID)
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.TargetType != "" {
+		add("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		add("target_id = $%d", filter.TargetID)
+	}
+	if !filter.Since.IsZero() {
+		add("created_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		add("created_at < $%d", filter.Until)
+	}
+	if filter.BeforeID != 0 {
+		add("id < $%d", filter.BeforeID)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// nullJSON stores empty JSON as SQL NULL.
+func nullJSON(data []byte) any {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
@@ -0,0 +1,26 @@
+package queries
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditEventConditions(t *testing.T) {
+	where, args := auditEventConditions(AuditEventFilter{})
+	assert.Equal(t, "TRUE", where)
+	assert.Empty(t, args)
+
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	where, args = auditEventConditions(AuditEventFilter{
+		ActorID:    1,
+		Action:     "user.update",
+		TargetType: "user",
+		TargetID:   2,
+		Since:      since,
+		BeforeID:   100,
+	})
+	assert.Equal(t, "TRUE AND actor_id = $1 AND action = $2 AND target_type = $3 AND target_id = $4 AND created_at >= $5 AND id < $6", where)
+	assert.Equal(t, []any{1, "user.update", "user", 2, since, int64(100)}, args)
+}
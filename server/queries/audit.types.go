@@ -0,0 +1,45 @@
+package queries
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type AuditEvent struct {
+	ID         int64              `db:"id"`
+	ActorID    pgtype.Int4        `db:"actor_id"`
+	Action     string             `db:"action"`
+	TargetType string             `db:"target_type"`
+	TargetID   int                `db:"target_id"`
+	Before     json.RawMessage    `db:"before"`
+	After      json.RawMessage    `db:"after"`
+	IP         pgtype.Text        `db:"ip"`
+	RequestID  pgtype.Text        `db:"request_id"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at"`
+}
+
+type CreateAuditEventParams struct {
+	ActorID    *int
+	Action     string
+	TargetType string
+	TargetID   int
+	Before     json.RawMessage
+	After      json.RawMessage
+	IP         string
+	RequestID  string
+}
+
+// AuditEventFilter narrows GetAuditEvents. Zero-valued fields are ignored.
+type AuditEventFilter struct {
+	ActorID    int
+	Action     string
+	TargetType string
+	TargetID   int
+	Since      time.Time
+	Until      time.Time
+	// BeforeID returns only events older than this ID, for paging.
+	BeforeID int64
+	Limit    int
+}
@@ -0,0 +1,108 @@
+package queries
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrConversationNotFound is returned when no conversation exists with the requested ID.
+var ErrConversationNotFound = errors.New("conversation not found")
+
+const conversationColumns = `id, title, created_at`
+
+func scanConversation(row pgx.Row) (Conversation, error) {
+	var conversation Conversation
+	err := row.Scan(&conversation.ID, &conversation.Title, &conversation.CreatedAt)
+	return conversation, err
+}
+
+// CreateConversation starts a conversation with the given title.
+func (q Queries) CreateConversation(ctx context.Context, title string) (Conversation, error) {
+	return scanConversation(q.db.QueryRow(ctx, `
+		INSERT INTO public.conversations (title)
+		VALUES ($1)
+		RETURNING `+conversationColumns,
+		title,
+	))
+}
+
+// GetConversations retrieves all conversations ordered by ID.
+func (q Queries) GetConversations(ctx context.Context) ([]Conversation, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT `+conversationColumns+`
+		FROM public.conversations
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conversations := []Conversation{}
+	for rows.Next() {
+		conversation, err := scanConversation(rows)
+		if err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, conversation)
+	}
+
+	return conversations, rows.Err()
+}
+
+// GetConversations runs Queries.GetConversations on a new connection.
+func GetConversations(ctx context.Context) ([]Conversation, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]Conversation, error) {
+		return q.GetConversations(ctx)
+	})
+}
+
+// GetConversation retrieves a conversation by ID, or returns
+// ErrConversationNotFound if it does not exist.
+func (q Queries) GetConversation(ctx context.Context, conversationID int) (Conversation, error) {
+	conversation, err := scanConversation(q.db.QueryRow(ctx, `
+		SELECT `+conversationColumns+`
+		FROM public.conversations
+		WHERE id = $1
+	`, conversationID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Conversation{}, ErrConversationNotFound
+	}
+	return conversation, err
+}
+
+// GetConversation runs Queries.GetConversation on a new connection.
+func GetConversation(ctx context.Context, conversationID int) (Conversation, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) (Conversation, error) {
+		return q.GetConversation(ctx, conversationID)
+	})
+}
+
+// GetConversationMessages retrieves up to limit messages in a conversation,
+// newest first, starting before the message with ID beforeID (zero for the
+// first page).
+func (q Queries) GetConversationMessages(ctx context.Context, conversationID, limit, beforeID int) ([]GetMessagesQueryRow, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT `+messageColumns+`
+		FROM public.messages
+		WHERE conversation_id = $1
+			AND ($2 = 0 OR id < $2)
+			AND `+messageVisible+`
+		ORDER BY id DESC
+		LIMIT $3
+	`, conversationID, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanMessages(rows)
+}
+
+// GetConversationMessages runs Queries.GetConversationMessages on a new connection.
+func GetConversationMessages(ctx context.Context, conversationID, limit, beforeID int) ([]GetMessagesQueryRow, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]GetMessagesQueryRow, error) {
+		return q.GetConversationMessages(ctx, conversationID, limit, beforeID)
+	})
+}
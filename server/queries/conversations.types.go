@@ -0,0 +1,13 @@
+package queries
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+// DefaultConversationID is the conversation messages that name none are
+// posted in, created with the schema.
+const DefaultConversationID = 1
+
+type Conversation struct {
+	ID        int                `db:"id"`
+	Title     string             `db:"title"`
+	CreatedAt pgtype.Timestamptz `db:"created_at"`
+}
@@ -2,12 +2,14 @@ package queries
 
 import (
 	"context"
+	"errors"
 	"os"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
-func GetConnection() (*pgx.Conn) {
+func GetConnection() *pgx.Conn {
 	conn, err := pgx.Connect(
 		context.Background(),
 		os.Getenv("DB_CONNECTION_STRING"),
@@ -18,4 +20,134 @@ func GetConnection() (*pgx.Conn) {
 	}
 
 	return conn
-}
\ No newline at end of file
+}
+
+// DBTX is implemented by both *pgx.Conn and pgx.Tx.
+type DBTX interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Queries runs the package's queries against a connection or a transaction.
+// Inside WithTx every statement issued through the Queries value is part of
+// the same transaction.
+type Queries struct {
+	db DBTX
+}
+
+// New returns Queries that run against db.
+func New(db DBTX) Queries {
+	return Queries{db: db}
+}
+
+const defaultTxRetries = 3
+
+type txConfig struct {
+	isoLevel   pgx.TxIsoLevel
+	accessMode pgx.TxAccessMode
+	maxRetries int
+}
+
+// TxOption configures WithTx.
+type TxOption func(*txConfig)
+
+// WithIsolation sets the transaction isolation level (default read committed).
+func WithIsolation(level pgx.TxIsoLevel) TxOption {
+	return func(c *txConfig) { c.isoLevel = level }
+}
+
+// WithReadOnly makes the transaction read-only.
+func WithReadOnly() TxOption {
+	return func(c *txConfig) { c.accessMode = pgx.ReadOnly }
+}
+
+// WithMaxRetries sets how many times the transaction is retried after a
+// serialization failure or deadlock (default 3).
+func WithMaxRetries(n int) TxOption {
+	return func(c *txConfig) { c.maxRetries = n }
+}
+
+// WithTx runs fn in a transaction on a new connection, committing if fn
+// returns nil and rolling back otherwise. Transactions aborted by a
+// serialization failure or deadlock are retried, so fn may run more than once
+// and must not have side effects outside the transaction.
+func WithTx(ctx context.Context, fn func(tx Queries) error, opts ...TxOption) error {
+	cfg := txConfig{isoLevel: pgx.ReadCommitted, maxRetries: defaultTxRetries}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	conn := GetConnection()
+	defer conn.Close(ctx)
+
+	for attempt := 0; ; attempt++ {
+		err := runTx(ctx, conn, cfg, fn)
+		if err == nil || attempt >= cfg.maxRetries || !isRetryable(err) {
+			return err
+		}
+	}
+}
+
+func runTx(ctx context.Context, conn *pgx.Conn, cfg txConfig, fn func(tx Queries) error) error {
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: cfg.isoLevel, AccessMode: cfg.accessMode})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(New(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Savepoint runs fn inside a savepoint of the current transaction. If fn
+// fails, only its statements are rolled back and the transaction can carry on.
+// It must be called on the Queries passed to a WithTx function.
+func (q Queries) Savepoint(ctx context.Context, fn func(sp Queries) error) error {
+	if _, err := q.db.Exec(ctx, "SAVEPOINT sp"); err != nil {
+		return err
+	}
+	if err := fn(q); err != nil {
+		if _, rbErr := q.db.Exec(ctx, "ROLLBACK TO SAVEPOINT sp"); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	_, err := q.db.Exec(ctx, "RELEASE SAVEPOINT sp")
+	return err
+}
+
+// isRetryable reports whether err aborted a transaction that may succeed if retried.
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	switch pgErr.Code {
+	case "40001", // serialization_failure
+		"40P01": // deadlock_detected
+		return true
+	}
+	return false
+}
+
+// withConnection runs fn on a new connection that is closed afterwards.
+func withConnection[T any](ctx context.Context, fn func(ctx context.Context, q Queries) (T, error)) (T, error) {
+	conn := GetConnection()
+	defer conn.Close(ctx)
+
+	return fn(ctx, New(conn))
+}
+
+// inTx runs fn in a transaction with the default options.
+func inTx[T any](ctx context.Context, fn func(ctx context.Context, q Queries) (T, error)) (T, error) {
+	var result T
+	err := WithTx(ctx, func(tx Queries) error {
+		var err error
+		result, err = fn(ctx, tx)
+		return err
+	})
+	return result, err
+}
@@ -0,0 +1,37 @@
+package queries
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+// requireDatabase skips tests that need the database when there isn't one.
+func requireDatabase(t *testing.T) {
+	if os.Getenv("DB_CONNECTION_STRING") == "" {
+		t.Skip("DB_CONNECTION_STRING not set")
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, isRetryable(&pgconn.PgError{Code: "40001"}))
+	assert.True(t, isRetryable(fmt.Errorf("wrapped: %w", &pgconn.PgError{Code: "40P01"})))
+	assert.False(t, isRetryable(&pgconn.PgError{Code: "23505"}))
+	assert.False(t, isRetryable(errors.New("serialization failure")))
+}
+
+func TestTxOptions(t *testing.T) {
+	cfg := txConfig{isoLevel: pgx.ReadCommitted, maxRetries: defaultTxRetries}
+	WithIsolation(pgx.Serializable)(&cfg)
+	WithMaxRetries(5)(&cfg)
+	WithReadOnly()(&cfg)
+
+	assert.Equal(t, pgx.Serializable, cfg.isoLevel)
+	assert.Equal(t, 5, cfg.maxRetries)
+	assert.Equal(t, pgx.ReadOnly, cfg.accessMode)
+}
@@ -0,0 +1,201 @@
+package queries
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrErasureRequestNotFound is returned when no unexpired, unconfirmed
+// erasure request matches a confirmation token.
+var ErrErasureRequestNotFound = errors.New("erasure request not found or expired")
+
+const erasureRequestColumns = `id, user_id, message_policy, requested_by, expires_at, confirmed_at, created_at`
+
+func scanErasureRequest(row pgx.Row) (ErasureRequest, error) {
+	var request ErasureRequest
+	err := row.Scan(
+		&request.ID,
+		&request.UserID,
+		&request.MessagePolicy,
+		&request.RequestedBy,
+		&request.ExpiresAt,
+		&request.ConfirmedAt,
+		&request.CreatedAt,
+	)
+	return request, err
+}
+
+// CreateErasureRequest records an unconfirmed request to erase a user that
+// expires after params.TTL.
+func (q Queries) CreateErasureRequest(ctx context.Context, params CreateErasureRequestParams) (ErasureRequest, error) {
+	return scanErasureRequest(q.db.QueryRow(ctx, `
+		INSERT INTO public.erasure_requests (user_id, message_policy, token_hash, requested_by, expires_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP + make_interval(secs => $5))
+		RETURNING `+erasureRequestColumns,
+		params.UserID, params.MessagePolicy, params.TokenHash, params.RequestedBy, params.TTL.Seconds(),
+	))
+}
+
+// ConfirmErasureRequest marks the user's unexpired, unconfirmed erasure
+// request with the given token hash confirmed, or returns ErrErasureRequestNotFound.
+func (q Queries) ConfirmErasureRequest(ctx context.Context, userID int, tokenHash string) (ErasureRequest, error) {
+	request, err := scanErasureRequest(q.db.QueryRow(ctx, `
+		UPDATE public.erasure_requests
+		SET confirmed_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND token_hash = $2 AND confirmed_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING `+erasureRequestColumns,
+		userID, tokenHash,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErasureRequest{}, ErrErasureRequestNotFound
+	}
+	return request, err
+}
+
+// AnonymizeUser replaces a user's username, email and nickname with
+// placeholders derived from their ID and removes their avatar, or returns
+// ErrUserNotFound.
+func (q Queries) AnonymizeUser(ctx context.Context, userID int) (GetUsersQueryRow, error) {
+	tag, err := q.db.Exec(ctx, `
+		UPDATE public.users
+		SET username = 'erased-' || id,
+			email = 'erased-' || id || '@erased.invalid',
+			nickname = NULL,
+			avatar_key = NULL,
+			version = version + 1,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, userID)
+	if err != nil {
+		return GetUsersQueryRow{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		return GetUsersQueryRow{}, ErrUserNotFound
+	}
+	return q.GetUser(ctx, userID)
+}
+
+// RedactUserMessages replaces the content of all of a user's messages and
+// unlinks the mentions and tags parsed from it, so the redacted messages are
+// no longer listed by mention or tag. Returns the number of messages redacted.
+func (q Queries) RedactUserMessages(ctx context.Context, userID int, content string) (int, error) {
+	_, err := q.db.Exec(ctx, `
+		DELETE FROM public.message_mentions
+		WHERE message_id IN (SELECT id FROM public.messages WHERE user_id = $1)
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+	_, err = q.db.Exec(ctx, `
+		DELETE FROM public.message_tags
+		WHERE message_id IN (SELECT id FROM public.messages WHERE user_id = $1)
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+	tag, err := q.db.Exec(ctx, `
+		UPDATE public.messages
+		SET content = $2
+		WHERE user_id = $1
+	`, userID, content)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// DeleteUserMessages deletes all of a user's messages and resets their
+// message count. Returns the number of messages deleted.
+func (q Queries) DeleteUserMessages(ctx context.Context, userID int) (int, error) {
+	tag, err := q.db.Exec(ctx, `
+		DELETE FROM public.messages
+		WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+	_, err = q.db.Exec(ctx, `
+		UPDATE public.users
+		SET message_count = 0
+		WHERE id = $1
+	`, userID)
+	return int(tag.RowsAffected()), err
+}
+
+// DetachUserAttachments detaches the attachments of all of a user's messages,
+// archived or not, so the purge_attachments job deletes their blobs and rows
+// as it does for deleted messages.
+func (q Queries) DetachUserAttachments(ctx context.Context, userID int) error {
+	_, err := q.db.Exec(ctx, `
+		UPDATE public.message_attachments
+		SET message_id = NULL, archived_message_id = NULL
+		WHERE message_id IN (SELECT id FROM public.messages WHERE user_id = $1)
+			OR archived_message_id IN (SELECT id FROM public.archived_messages WHERE user_id = $1)
+	`, userID)
+	return err
+}
+
+// RedactUserArchivedMessages replaces the content of all of a user's archived
+// messages. Returns the number of archived messages redacted.
+func (q Queries) RedactUserArchivedMessages(ctx context.Context, userID int, content string) (int, error) {
+	tag, err := q.db.Exec(ctx, `
+		UPDATE public.archived_messages
+		SET content = $2
+		WHERE user_id = $1
+	`, userID, content)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// DeleteUserArchivedMessages deletes all of a user's archived messages.
+// Returns the number of archived messages deleted.
+func (q Queries) DeleteUserArchivedMessages(ctx context.Context, userID int) (int, error) {
+	tag, err := q.db.Exec(ctx, `
+		DELETE FROM public.archived_messages
+		WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// RedactUserAuditEvents clears the before and after snapshots of audit events
+// on a user and their messages, archived or not, and the IP address of events
+// they made, so the trail keeps what happened but not the personal data
+// involved. Call it before deleting the user's messages.
+func (q Queries) RedactUserAuditEvents(ctx context.Context, userID int) error {
+	_, err := q.db.Exec(ctx, `
+		UPDATE public.audit_events
+		SET before = NULL, after = NULL
+		WHERE (target_type = 'user' AND target_id = $1)
+			OR (target_type = 'message' AND target_id IN (SELECT id FROM public.messages WHERE user_id = $1))
+			OR (target_type = 'message' AND target_id IN (SELECT id FROM public.archived_messages WHERE user_id = $1))
+	`, userID)
+	if err != nil {
+		return err
+	}
+	_, err = q.db.Exec(ctx, `
+		UPDATE public.audit_events
+		SET ip = NULL
+		WHERE actor_id = $1
+	`, userID)
+	return err
+}
+
+// RedactUserOutboxEvents reduces the payloads of user and message events
+// about a user to their IDs, so deliveries still pending or retried later do
+// not carry the user's personal data.
+func (q Queries) RedactUserOutboxEvents(ctx context.Context, userID int) error {
+	_, err := q.db.Exec(ctx, `
+		UPDATE public.outbox_events
+		SET payload = jsonb_strip_nulls(jsonb_build_object('id', payload->'id', 'user_id', payload->'user_id', 'redacted', true))
+		WHERE (event_type IN ('user.created', 'user.updated') AND payload->>'id' = $1::text)
+			OR (event_type = 'message.created' AND payload->>'user_id' = $1::text)
+	`, userID)
+	return err
+}
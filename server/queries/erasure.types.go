@@ -0,0 +1,32 @@
+package queries
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Message policies of an erasure request.
+const (
+	MessagePolicyRedact = "redact"
+	MessagePolicyDelete = "delete"
+)
+
+type ErasureRequest struct {
+	ID            int64              `db:"id"`
+	UserID        int                `db:"user_id"`
+	MessagePolicy string             `db:"message_policy"`
+	RequestedBy   pgtype.Int4        `db:"requested_by"`
+	ExpiresAt     pgtype.Timestamptz `db:"expires_at"`
+	ConfirmedAt   pgtype.Timestamptz `db:"confirmed_at"`
+	CreatedAt     pgtype.Timestamptz `db:"created_at"`
+}
+
+type CreateErasureRequestParams struct {
+	UserID        int
+	MessagePolicy string
+	// TokenHash is the hex SHA-256 hash of the confirmation token.
+	TokenHash   string
+	RequestedBy *int
+	TTL         time.Duration
+}
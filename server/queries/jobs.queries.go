@@ -0,0 +1,201 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// EnqueueJob adds a job and returns its ID. If params.UniqueKey is already
+// taken nothing is added and the returned ID is zero. Call it inside WithTx to
+// enqueue a job only if the rest of the transaction commits.
+func (q Queries) EnqueueJob(ctx context.Context, params EnqueueJobParams) (int64, error) {
+	args := "{}"
+	if len(params.Args) > 0 {
+		args = string(params.Args)
+	}
+
+	var id int64
+	err := q.db.QueryRow(ctx, `
+		INSERT INTO public.jobs (kind, args, max_attempts, run_at, unique_key)
+		VALUES ($1, $2, $3, COALESCE($4, CURRENT_TIMESTAMP), $5)
+		ON CONFLICT (unique_key) DO NOTHING
+		RETURNING id
+	`,
+		params.Kind,
+		args,
+		params.MaxAttempts,
+		pgtype.Timestamptz{Time: params.RunAt, Valid: !params.RunAt.IsZero()},
+		pgtype.Text{String: params.UniqueKey, Valid: params.UniqueKey != ""},
+	).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
+
+// EnqueueJob runs Queries.EnqueueJob on a new connection.
+func EnqueueJob(ctx context.Context, params EnqueueJobParams) (int64, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) (int64, error) {
+		return q.EnqueueJob(ctx, params)
+	})
+}
+
+// DequeueJobs claims up to limit due jobs of the given kinds for workerID,
+// marking them running and counting the attempt. Jobs locked by another
+// worker's dequeue are skipped rather than waited for.
+func (q Queries) DequeueJobs(ctx context.Context, workerID string, kinds []string, limit int) ([]Job, error) {
+	rows, err := q.db.Query(ctx, `
+		WITH due AS (
+			SELECT id
+			FROM public.jobs
+			WHERE status = 'queued' AND run_at <= CURRENT_TIMESTAMP AND kind = ANY($2)
+			ORDER BY run_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE public.jobs j
+		SET status = 'running',
+			attempts = j.attempts + 1,
+			locked_at = CURRENT_TIMESTAMP,
+			locked_by = $1,
+			updated_at = CURRENT_TIMESTAMP
+		FROM due
+		WHERE j.id = due.id
+		RETURNING j.id, j.kind, j.args, j.status, j.attempts, j.max_attempts, j.run_at, j.last_error, j.created_at
+	`, workerID, kinds, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []Job{}
+	for rows.Next() {
+		var job Job
+		if err := rows.Scan(
+			&job.ID,
+			&job.Kind,
+			&job.Args,
+			&job.Status,
+			&job.Attempts,
+			&job.MaxAttempts,
+			&job.RunAt,
+			&job.LastError,
+			&job.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// DequeueJobs runs Queries.DequeueJobs on a new connection.
+func DequeueJobs(ctx context.Context, workerID string, kinds []string, limit int) ([]Job, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]Job, error) {
+		return q.DequeueJobs(ctx, workerID, kinds, limit)
+	})
+}
+
+// CompleteJob marks a running job as succeeded.
+func (q Queries) CompleteJob(ctx context.Context, jobID int64) error {
+	_, err := q.db.Exec(ctx, `
+		UPDATE public.jobs
+		SET status = 'succeeded', locked_at = NULL, locked_by = NULL, last_error = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, jobID)
+	return err
+}
+
+// CompleteJob runs Queries.CompleteJob on a new connection.
+func CompleteJob(ctx context.Context, jobID int64) error {
+	_, err := withConnection(ctx, func(ctx context.Context, q Queries) (struct{}, error) {
+		return struct{}{}, q.CompleteJob(ctx, jobID)
+	})
+	return err
+}
+
+// FailJob records a failed attempt, queueing a retry at params.RetryAt or,
+// if it is zero, marking the job failed.
+func (q Queries) FailJob(ctx context.Context, params FailJobParams) error {
+	status, runAt := JobQueued, pgtype.Timestamptz{Time: params.RetryAt, Valid: true}
+	if params.RetryAt.IsZero() {
+		status, runAt = JobFailed, pgtype.Timestamptz{}
+	}
+
+	_, err := q.db.Exec(ctx, `
+		UPDATE public.jobs
+		SET status = $2,
+			run_at = COALESCE($3, run_at),
+			last_error = $4,
+			locked_at = NULL,
+			locked_by = NULL,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, params.ID, status, runAt, params.Error)
+	return err
+}
+
+// FailJob runs Queries.FailJob on a new connection.
+func FailJob(ctx context.Context, params FailJobParams) error {
+	_, err := withConnection(ctx, func(ctx context.Context, q Queries) (struct{}, error) {
+		return struct{}{}, q.FailJob(ctx, params)
+	})
+	return err
+}
+
+// RequeueStaleJobs returns jobs that have been running for longer than
+// timeout to the queue, assuming their worker died. The interrupted run still
+// counts as an attempt. Returns the number of jobs requeued.
+func (q Queries) RequeueStaleJobs(ctx context.Context, timeout time.Duration) (int, error) {
+	tag, err := q.db.Exec(ctx, `
+		UPDATE public.jobs
+		SET status = CASE WHEN attempts >= max_attempts THEN 'failed' ELSE 'queued' END,
+			last_error = 'worker ' || COALESCE(locked_by, '') || ' timed out',
+			locked_at = NULL,
+			locked_by = NULL,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE status = 'running' AND locked_at < CURRENT_TIMESTAMP - make_interval(secs => $1)
+	`, timeout.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// RequeueStaleJobs runs Queries.RequeueStaleJobs on a new connection.
+func RequeueStaleJobs(ctx context.Context, timeout time.Duration) (int, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) (int, error) {
+		return q.RequeueStaleJobs(ctx, timeout)
+	})
+}
+
+// PurgeOutboxEvents deletes dispatched outbox events older than olderThan
+// whose deliveries have all succeeded, along with those deliveries. Events
+// with pending or dead deliveries are kept.
+// Returns the number of events deleted.
+func (q Queries) PurgeOutboxEvents(ctx context.Context, olderThan time.Duration) (int, error) {
+	tag, err := q.db.Exec(ctx, `
+		DELETE FROM public.outbox_events e
+		WHERE e.dispatched_at < CURRENT_TIMESTAMP - make_interval(secs => $1)
+			AND NOT EXISTS (
+				SELECT 1 FROM public.webhook_deliveries d
+				WHERE d.event_id = e.id AND d.status <> 'succeeded'
+			)
+	`, olderThan.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// PurgeOutboxEvents runs Queries.PurgeOutboxEvents on a new connection.
+func PurgeOutboxEvents(ctx context.Context, olderThan time.Duration) (int, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) (int, error) {
+		return q.PurgeOutboxEvents(ctx, olderThan)
+	})
+}
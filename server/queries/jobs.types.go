@@ -0,0 +1,47 @@
+package queries
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Job statuses.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+type Job struct {
+	ID          int64              `db:"id"`
+	Kind        string             `db:"kind"`
+	Args        json.RawMessage    `db:"args"`
+	Status      string             `db:"status"`
+	Attempts    int32              `db:"attempts"`
+	MaxAttempts int32              `db:"max_attempts"`
+	RunAt       pgtype.Timestamptz `db:"run_at"`
+	LastError   pgtype.Text        `db:"last_error"`
+	CreatedAt   pgtype.Timestamptz `db:"created_at"`
+}
+
+type EnqueueJobParams struct {
+	Kind string
+	Args json.RawMessage
+	// MaxAttempts is how many times the job is tried; it must be positive.
+	MaxAttempts int
+	// RunAt delays the job; zero runs it as soon as possible.
+	RunAt time.Time
+	// UniqueKey, if set, makes the enqueue a no-op when a job with the same
+	// key already exists.
+	UniqueKey string
+}
+
+type FailJobParams struct {
+	ID    int64
+	Error string
+	// RetryAt schedules the next attempt; if zero the job has failed for good.
+	RetryAt time.Time
+}
@@ -0,0 +1,168 @@
+package queries
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// LinkMentions records the users with the given usernames as mentioned in a
+// message. Usernames that match no user are ignored. Returns the mentions
+// recorded, ordered by user ID.
+func (q Queries) LinkMentions(ctx context.Context, messageID int, usernames []string) ([]MessageMention, error) {
+	rows, err := q.db.Query(ctx, `
+		WITH linked AS (
+			INSERT INTO public.message_mentions (message_id, user_id)
+			SELECT $1, id
+			FROM public.users
+			WHERE username = ANY($2)
+			ON CONFLICT DO NOTHING
+			RETURNING message_id, user_id
+		)
+		SELECT l.message_id, l.user_id, u.username
+		FROM linked l
+		JOIN public.users u ON u.id = l.user_id
+		ORDER BY l.user_id
+	`, messageID, usernames)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanMentions(rows)
+}
+
+// LinkTags records tags as used in a message.
+func (q Queries) LinkTags(ctx context.Context, messageID int, tags []string) error {
+	_, err := q.db.Exec(ctx, `
+		INSERT INTO public.message_tags (message_id, tag)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT DO NOTHING
+	`, messageID, tags)
+	return err
+}
+
+// GetMentions retrieves the users mentioned in the given messages in a single
+// query, ordered by message and user ID.
+func (q Queries) GetMentions(ctx context.Context, messageIDs []int) ([]MessageMention, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT mm.message_id, mm.user_id, u.username
+		FROM public.message_mentions mm
+		JOIN public.users u ON u.id = mm.user_id
+		WHERE mm.message_id = ANY($1)
+		ORDER BY mm.message_id, mm.user_id
+	`, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanMentions(rows)
+}
+
+// GetMentions runs Queries.GetMentions on a new connection.
+func GetMentions(ctx context.Context, messageIDs []int) ([]MessageMention, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]MessageMention, error) {
+		return q.GetMentions(ctx, messageIDs)
+	})
+}
+
+// scanMentions reads all mention rows and closes them.
+func scanMentions(rows pgx.Rows) ([]MessageMention, error) {
+	defer rows.Close()
+
+	mentions := []MessageMention{}
+	for rows.Next() {
+		var mention MessageMention
+		if err := rows.Scan(&mention.MessageID, &mention.UserID, &mention.Username); err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, mention)
+	}
+
+	return mentions, rows.Err()
+}
+
+// GetTags retrieves the tags of the given messages in a single query,
+// ordered by message and tag.
+func (q Queries) GetTags(ctx context.Context, messageIDs []int) ([]MessageTag, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT message_id, tag
+		FROM public.message_tags
+		WHERE message_id = ANY($1)
+		ORDER BY message_id, tag
+	`, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []MessageTag{}
+	for rows.Next() {
+		var tag MessageTag
+		if err := rows.Scan(&tag.MessageID, &tag.Tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// GetTags runs Queries.GetTags on a new connection.
+func GetTags(ctx context.Context, messageIDs []int) ([]MessageTag, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]MessageTag, error) {
+		return q.GetTags(ctx, messageIDs)
+	})
+}
+
+// GetMessagesMentioning retrieves up to limit messages mentioning a user,
+// newest first, starting before the message with ID beforeID (zero for the
+// first page).
+func (q Queries) GetMessagesMentioning(ctx context.Context, userID, limit, beforeID int) ([]GetMessagesQueryRow, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT `+messageColumns+`
+		FROM public.messages
+		WHERE id IN (SELECT message_id FROM public.message_mentions WHERE user_id = $1)
+			AND ($2 = 0 OR id < $2)
+			AND `+messageVisible+`
+		ORDER BY id DESC
+		LIMIT $3
+	`, userID, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanMessages(rows)
+}
+
+// GetMessagesMentioning runs Queries.GetMessagesMentioning on a new connection.
+func GetMessagesMentioning(ctx context.Context, userID, limit, beforeID int) ([]GetMessagesQueryRow, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]GetMessagesQueryRow, error) {
+		return q.GetMessagesMentioning(ctx, userID, limit, beforeID)
+	})
+}
+
+// GetMessagesByTag retrieves up to limit messages with a tag, newest first,
+// starting before the message with ID beforeID (zero for the first page).
+func (q Queries) GetMessagesByTag(ctx context.Context, tag string, limit, beforeID int) ([]GetMessagesQueryRow, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT `+messageColumns+`
+		FROM public.messages
+		WHERE id IN (SELECT message_id FROM public.message_tags WHERE tag = $1)
+			AND ($2 = 0 OR id < $2)
+			AND `+messageVisible+`
+		ORDER BY id DESC
+		LIMIT $3
+	`, tag, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanMessages(rows)
+}
+
+// GetMessagesByTag runs Queries.GetMessagesByTag on a new connection.
+func GetMessagesByTag(ctx context.Context, tag string, limit, beforeID int) ([]GetMessagesQueryRow, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]GetMessagesQueryRow, error) {
+		return q.GetMessagesByTag(ctx, tag, limit, beforeID)
+	})
+}
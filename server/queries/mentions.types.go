@@ -0,0 +1,14 @@
+package queries
+
+// MessageMention is a user mentioned in a message.
+type MessageMention struct {
+	MessageID int    `db:"message_id"`
+	UserID    int    `db:"user_id"`
+	Username  string `db:"username"`
+}
+
+// MessageTag is a tag used in a message.
+type MessageTag struct {
+	MessageID int    `db:"message_id"`
+	Tag       string `db:"tag"`
+}
@@ -2,92 +2,362 @@ package queries
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
 )
 
+// ErrMessageNotFound is returned when a message does not exist, has expired
+// or is held for review.
+var ErrMessageNotFound = errors.New("message not found")
+
 // GetMessages retrieves all messages from the database.
 // It returns a slice of GetMessagesQueryRow and an error if any occurs.
-func GetMessages() ([]GetMessagesQueryRow, error) {
-	conn := GetConnection()
-	defer conn.Close(context.TODO())
-
-	rows, err := conn.Query(context.TODO(), `
-		SELECT id, user_id, content, created_at 
+func (q Queries) GetMessages(ctx context.Context) ([]GetMessagesQueryRow, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT `+messageColumns+`
 		FROM public.messages
+		WHERE `+messageVisible+`
 	`)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanMessages(rows)
+}
+
+// GetMessages runs Queries.GetMessages on a new connection.
+func GetMessages(ctx context.Context) ([]GetMessagesQueryRow, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]GetMessagesQueryRow, error) {
+		return q.GetMessages(ctx)
+	})
+}
+
+func (q Queries) CreateMessage(ctx context.Context, params CreateMessageParams) (GetMessagesQueryRow, error) {
+	message, err := scanMessage(q.db.QueryRow(ctx, `
+		INSERT INTO public.messages (user_id, content, expires_at, parent_id, conversation_id, held)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+messageColumns,
+		params.UserID, params.Content, params.ExpiresAt, params.ParentID, params.ConversationID, params.Held,
+	))
+	if err != nil {
+		return GetMessagesQueryRow{}, err
+	}
+
+	return message, nil
+}
+
+// CreateMessage runs Queries.CreateMessage on a new connection.
+func CreateMessage(ctx context.Context, params CreateMessageParams) (GetMessagesQueryRow, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) (GetMessagesQueryRow, error) {
+		return q.CreateMessage(ctx, params)
+	})
+}
+
+// GetMessage retrieves a message by ID, or returns ErrMessageNotFound if it
+// does not exist, has expired or is held for review.
+func (q Queries) GetMessage(ctx context.Context, messageID int) (GetMessagesQueryRow, error) {
+	message, err := scanMessage(q.db.QueryRow(ctx, `
+		SELECT `+messageColumns+`
+		FROM public.messages
+		WHERE id = $1 AND `+messageVisible+`
+	`, messageID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return GetMessagesQueryRow{}, ErrMessageNotFound
+	}
+	return message, err
+}
+
+// GetMessage runs Queries.GetMessage on a new connection.
+func GetMessage(ctx context.Context, messageID int) (GetMessagesQueryRow, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) (GetMessagesQueryRow, error) {
+		return q.GetMessage(ctx, messageID)
+	})
+}
 
+// GetReplies retrieves up to limit direct replies to a message, oldest first,
+// starting after the reply with ID afterID (zero for the first page).
+func (q Queries) GetReplies(ctx context.Context, parentID, limit, afterID int) ([]GetMessagesQueryRow, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT `+messageColumns+`
+		FROM public.messages
+		WHERE parent_id = $1 AND id > $2 AND `+messageVisible+`
+		ORDER BY id
+		LIMIT $3
+	`, parentID, afterID, limit)
 	if err != nil {
 		return nil, err
 	}
 
+	return scanMessages(rows)
+}
+
+// GetReplies runs Queries.GetReplies on a new connection.
+func GetReplies(ctx context.Context, parentID, limit, afterID int) ([]GetMessagesQueryRow, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]GetMessagesQueryRow, error) {
+		return q.GetReplies(ctx, parentID, limit, afterID)
+	})
+}
+
+// GetReplySummaries counts the direct replies to each of the given messages
+// and finds the latest, in a single query. Messages without replies are omitted.
+func (q Queries) GetReplySummaries(ctx context.Context, messageIDs []int) ([]ReplySummary, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT parent_id, COUNT(*), MAX(created_at)
+		FROM public.messages
+		WHERE parent_id = ANY($1) AND `+messageVisible+`
+		GROUP BY parent_id
+	`, messageIDs)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
-	var messages []GetMessagesQueryRow = []GetMessagesQueryRow{}
+	summaries := []ReplySummary{}
 	for rows.Next() {
-		var message GetMessagesQueryRow
-		if err := rows.Scan(
-			&message.ID,
-			&message.UserID,
-			&message.Content,
-			&message.CreatedAt,
-		); err != nil {
+		var summary ReplySummary
+		if err := rows.Scan(&summary.MessageID, &summary.ReplyCount, &summary.LastReplyAt); err != nil {
 			return nil, err
 		}
-		messages = append(messages, message)
+		summaries = append(summaries, summary)
 	}
 
-	return messages, nil
+	return summaries, rows.Err()
 }
 
-func CreateMessage(params CreateMessageParams) (GetMessagesQueryRow, error) {
-	conn := GetConnection()
-	defer conn.Close(context.TODO())
+// GetReplySummaries runs Queries.GetReplySummaries on a new connection.
+func GetReplySummaries(ctx context.Context, messageIDs []int) ([]ReplySummary, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]ReplySummary, error) {
+		return q.GetReplySummaries(ctx, messageIDs)
+	})
+}
 
-	var message GetMessagesQueryRow
-	err := conn.QueryRow(context.TODO(), `
-		INSERT INTO public.messages (user_id, content) 
-		VALUES ($1, $2) 
-		RETURNING id, user_id, content, created_at
-	`, params.UserID, params.Content).Scan(
-		&message.ID,
-		&message.UserID,
-		&message.Content,
-		&message.CreatedAt,
-	)
+func (q Queries) GetMessagesByUser(ctx context.Context, userID int) ([]GetMessagesQueryRow, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT `+messageColumns+`
+		FROM public.messages
+		WHERE user_id = $1 AND `+messageVisible+`
+		ORDER BY created_at DESC
+	`, userID)
 	if err != nil {
-		return GetMessagesQueryRow{}, err
+		return nil, err
 	}
 
-	return message, nil
+	return scanMessages(rows)
 }
 
-func GetMessagesByUser(userID int) ([]GetMessagesQueryRow, error) {
-	conn := GetConnection()
-	defer conn.Close(context.TODO())
+// GetMessagesByUser runs Queries.GetMessagesByUser on a new connection.
+func GetMessagesByUser(ctx context.Context, userID int) ([]GetMessagesQueryRow, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]GetMessagesQueryRow, error) {
+		return q.GetMessagesByUser(ctx, userID)
+	})
+}
 
-	rows, err := conn.Query(context.TODO(), `
-		SELECT id, user_id, content, created_at
+// GetLatestMessagesByUsers retrieves a page of the most recent messages for
+// each of the given users in a single query.
+// Params:
+//   - userIDs: Users whose messages to fetch.
+//   - limit: Maximum number of messages per user.
+//   - offset: Number of most recent messages to skip per user.
+//
+// Returns:
+//   - []GetMessagesQueryRow: Messages grouped by user, newest first within each user.
+//   - error: Database error if query fails.
+func (q Queries) GetLatestMessagesByUsers(ctx context.Context, userIDs []int, limit, offset int) ([]GetMessagesQueryRow, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT `+messageColumns+`
+		FROM (
+			SELECT
+				`+messageColumns+`,
+				ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at DESC, id DESC) AS rn
+			FROM public.messages
+			WHERE user_id = ANY($1) AND `+messageVisible+`
+		) ranked
+		WHERE rn > $3 AND rn <= $2 + $3
+		ORDER BY user_id, rn
+	`, userIDs, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanMessages(rows)
+}
+
+// GetLatestMessagesByUsers runs Queries.GetLatestMessagesByUsers on a new connection.
+func GetLatestMessagesByUsers(ctx context.Context, userIDs []int, limit, offset int) ([]GetMessagesQueryRow, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]GetMessagesQueryRow, error) {
+		return q.GetLatestMessagesByUsers(ctx, userIDs, limit, offset)
+	})
+}
+
+// GetMessagesPage retrieves a page of messages, newest first.
+func (q Queries) GetMessagesPage(ctx context.Context, limit, offset int) ([]GetMessagesQueryRow, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT `+messageColumns+`
 		FROM public.messages
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-	`, userID)
+		WHERE `+messageVisible+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
 	if err != nil {
 		return nil, err
 	}
+
+	return scanMessages(rows)
+}
+
+// GetMessagesPage runs Queries.GetMessagesPage on a new connection.
+func GetMessagesPage(ctx context.Context, limit, offset int) ([]GetMessagesQueryRow, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]GetMessagesQueryRow, error) {
+		return q.GetMessagesPage(ctx, limit, offset)
+	})
+}
+
+// scanMessages reads all message rows and closes them.
+func scanMessages(rows pgx.Rows) ([]GetMessagesQueryRow, error) {
 	defer rows.Close()
 
-	var messages []GetMessagesQueryRow = []GetMessagesQueryRow{}
+	messages := []GetMessagesQueryRow{}
 	for rows.Next() {
-		var message GetMessagesQueryRow
-		if err := rows.Scan(
-			&message.ID,
-			&message.UserID,
-			&message.Content,
-			&message.CreatedAt,
-		); err != nil {
+		message, err := scanMessage(rows)
+		if err != nil {
 			return nil, err
 		}
 		messages = append(messages, message)
 	}
 
-	return messages, nil
+	return messages, rows.Err()
+}
+
+// messageColumns are the columns of GetMessagesQueryRow, in scanMessage order.
+const messageColumns = `id, user_id, content, created_at, expires_at, parent_id, conversation_id, held`
+
+// messageNotExpired excludes messages past their expires_at that the
+// retention sweeper has not yet removed.
+const messageNotExpired = `(expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)`
+
+// messageVisible excludes expired messages and messages held for review.
+const messageVisible = `(NOT held AND ` + messageNotExpired + `)`
+
+func scanMessage(row pgx.Row) (GetMessagesQueryRow, error) {
+	var message GetMessagesQueryRow
+	err := row.Scan(
+		&message.ID,
+		&message.UserID,
+		&message.Content,
+		&message.CreatedAt,
+		&message.ExpiresAt,
+		&message.ParentID,
+		&message.ConversationID,
+		&message.Held,
+	)
+	return message, err
+}
+
+// messageExportBatch is how many rows ExportMessages fetches from its cursor at a time.
+const messageExportBatch = 1000
+
+// ExportMessages calls fn for every unexpired message matching filter, held
+// or not, in ID order, stopping at the first error from fn. Archived messages
+// are included, expired or not, if filter.IncludeArchived is set. It reads
+// through a server-side cursor a batch at a time, so memory use does not
+// grow with the number of messages, and must be called on the Queries passed
+// to a WithTx function.
+func (q Queries) ExportMessages(ctx context.Context, filter MessageExportFilter, fn func(ExportedMessage) error) error {
+	where, args := messageExportConditions(filter)
+	query := `
+		SELECT ` + messageColumns + `, false AS archived
+		FROM public.messages
+		WHERE ` + messageNotExpired + ` AND ` + where
+	if filter.IncludeArchived {
+		query += `
+		UNION ALL
+		SELECT ` + messageColumns + `, true
+		FROM public.archived_messages
+		WHERE ` + where
+	}
+	_, err := q.db.Exec(ctx, `
+		DECLARE message_export NO SCROLL CURSOR FOR
+		`+query+`
+		ORDER BY id
+	`, args...)
+	if err != nil {
+		return err
+	}
+
+	for {
+		rows, err := q.db.Query(ctx, `FETCH FORWARD `+strconv.Itoa(messageExportBatch)+` FROM message_export`)
+		if err != nil {
+			return err
+		}
+		fetched := 0
+		for rows.Next() {
+			var message ExportedMessage
+			err := rows.Scan(
+				&message.ID,
+				&message.UserID,
+				&message.Content,
+				&message.CreatedAt,
+				&message.ExpiresAt,
+				&message.ParentID,
+				&message.ConversationID,
+				&message.Held,
+				&message.Archived,
+			)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			fetched++
+			if err := fn(message); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if fetched < messageExportBatch {
+			break
+		}
+	}
+
+	_, err = q.db.Exec(ctx, `CLOSE message_export`)
+	return err
+}
+
+// ExportMessages runs Queries.ExportMessages in a read-only transaction on a
+// new connection. It is not retried, as fn may already have had side effects.
+func ExportMessages(ctx context.Context, filter MessageExportFilter, fn func(ExportedMessage) error) error {
+	return WithTx(ctx, func(tx Queries) error {
+		return tx.ExportMessages(ctx, filter, fn)
+	}, WithReadOnly(), WithMaxRetries(0))
+}
+
+// messageExportConditions builds the WHERE clause for filter, which applies
+// to archived messages as well. Exports include messages held for review,
+// unlike reads, so they are complete.
+func messageExportConditions(filter MessageExportFilter) (string, []any) {
+	conditions := []string{"TRUE"}
+	args := []any{}
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.UserID != 0 {
+		add("user_id = $%d", filter.UserID)
+	}
+	if !filter.Since.IsZero() {
+		add("created_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		add("created_at < $%d", filter.Until)
+	}
+
+	return strings.Join(conditions, " AND "), args
 }
@@ -108,3 +108,15 @@ func TestGetMessages(t *testing.T) {
 
 	mockService.AssertExpectations(t)
 }
+
+func TestMessageExportConditions(t *testing.T) {
+	where, args := messageExportConditions(MessageExportFilter{})
+	assert.Equal(t, "TRUE", where)
+	assert.Empty(t, args)
+
+	since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	where, args = messageExportConditions(MessageExportFilter{UserID: 3, Since: since, Until: until})
+	assert.Equal(t, "TRUE AND user_id = $1 AND created_at >= $2 AND created_at < $3", where)
+	assert.Equal(t, []any{3, since, until}, args)
+}
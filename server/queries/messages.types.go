@@ -1,15 +1,56 @@
 package queries
 
-import "github.com/jackc/pgx/v5/pgtype"
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
 
 type GetMessagesQueryRow struct {
 	ID        int                `db:"id"`
 	UserID    int                `db:"user_id"`
 	Content   string             `db:"content"`
 	CreatedAt pgtype.Timestamptz `db:"created_at"`
+	// ExpiresAt is when an ephemeral message is removed; null for ordinary messages.
+	ExpiresAt pgtype.Timestamptz `db:"expires_at"`
+	// ParentID is the message a reply was made to; null for messages that are not replies.
+	ParentID       pgtype.Int4 `db:"parent_id"`
+	ConversationID int         `db:"conversation_id"`
+	// Held is set while the message awaits review by a moderator.
+	Held bool `db:"held"`
 }
 
 type CreateMessageParams struct {
-	UserID  int    `db:"user_id"`
-	Content string `db:"content"`
+	UserID    int                `db:"user_id"`
+	Content   string             `db:"content"`
+	ExpiresAt pgtype.Timestamptz `db:"expires_at"`
+	ParentID  *int               `db:"parent_id"`
+	// ConversationID is the conversation to post in; the default if zero.
+	ConversationID int `db:"conversation_id"`
+	// Held stores the message hidden until a moderator approves it.
+	Held bool `db:"held"`
+}
+
+// ReplySummary describes the replies to a message.
+type ReplySummary struct {
+	MessageID   int                `db:"parent_id"`
+	ReplyCount  int32              `db:"reply_count"`
+	LastReplyAt pgtype.Timestamptz `db:"last_reply_at"`
+}
+
+// MessageExportFilter narrows ExportMessages. Zero-valued fields are ignored.
+type MessageExportFilter struct {
+	UserID int
+	Since  time.Time
+	Until  time.Time
+	// IncludeArchived adds the messages moved to public.archived_messages by
+	// retention policies, in ID order with the others.
+	IncludeArchived bool
+}
+
+// ExportedMessage is a message read by ExportMessages.
+type ExportedMessage struct {
+	GetMessagesQueryRow
+	// Archived is set on messages read from public.archived_messages.
+	Archived bool
 }
@@ -0,0 +1,121 @@
+package queries
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrHeldMessageNotFound is returned when a message is not in the moderation
+// queue, because it does not exist, has expired or was never held.
+var ErrHeldMessageNotFound = errors.New("held message not found")
+
+// heldMessages joins held messages to their moderation_queue rows. Selecting
+// the queue's columns in a subquery keeps messageColumns unambiguous.
+const heldMessages = `
+	public.messages
+	JOIN (SELECT message_id, reasons, created_at AS held_at FROM public.moderation_queue) queued
+		ON queued.message_id = id
+	WHERE held AND ` + messageNotExpired
+
+func scanHeldMessage(row pgx.Row) (HeldMessage, error) {
+	var held HeldMessage
+	message := &held.GetMessagesQueryRow
+	err := row.Scan(
+		&message.ID,
+		&message.UserID,
+		&message.Content,
+		&message.CreatedAt,
+		&message.ExpiresAt,
+		&message.ParentID,
+		&message.ConversationID,
+		&message.Held,
+		&held.Reasons,
+		&held.HeldAt,
+	)
+	return held, err
+}
+
+// QueueMessage adds a held message to the moderation queue.
+func (q Queries) QueueMessage(ctx context.Context, messageID int, reasons []string) error {
+	_, err := q.db.Exec(ctx, `
+		INSERT INTO public.moderation_queue (message_id, reasons)
+		VALUES ($1, $2)
+	`, messageID, reasons)
+	return err
+}
+
+// GetModerationQueue retrieves up to limit unexpired held messages, oldest
+// first, starting after the message with ID afterID (zero for the first page).
+func (q Queries) GetModerationQueue(ctx context.Context, limit, afterID int) ([]HeldMessage, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT `+messageColumns+`, reasons, held_at
+		FROM `+heldMessages+` AND id > $1
+		ORDER BY id
+		LIMIT $2
+	`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []HeldMessage{}
+	for rows.Next() {
+		message, err := scanHeldMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+// GetModerationQueue runs Queries.GetModerationQueue on a new connection.
+func GetModerationQueue(ctx context.Context, limit, afterID int) ([]HeldMessage, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]HeldMessage, error) {
+		return q.GetModerationQueue(ctx, limit, afterID)
+	})
+}
+
+// GetHeldMessage retrieves a message in the moderation queue and locks it, so
+// that moderators acting on it at the same time are serialized. Returns
+// ErrHeldMessageNotFound if it is not in the queue.
+func (q Queries) GetHeldMessage(ctx context.Context, messageID int) (HeldMessage, error) {
+	message, err := scanHeldMessage(q.db.QueryRow(ctx, `
+		SELECT `+messageColumns+`, reasons, held_at
+		FROM `+heldMessages+` AND id = $1
+		FOR UPDATE OF messages
+	`, messageID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return HeldMessage{}, ErrHeldMessageNotFound
+	}
+	return message, err
+}
+
+// ApproveMessage clears a message's held flag, stamps it approved and
+// removes it from the moderation queue, returning the now visible message.
+func (q Queries) ApproveMessage(ctx context.Context, messageID int) (GetMessagesQueryRow, error) {
+	return scanMessage(q.db.QueryRow(ctx, `
+		WITH dequeued AS (
+			DELETE FROM public.moderation_queue
+			WHERE message_id = $1
+		)
+		UPDATE public.messages
+		SET held = false, approved_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING `+messageColumns,
+		messageID,
+	))
+}
+
+// DeleteMessage deletes a message. Its queue entry, mentions, tags and
+// reactions go with it; its attachments are detached.
+func (q Queries) DeleteMessage(ctx context.Context, messageID int) error {
+	_, err := q.db.Exec(ctx, `
+		DELETE FROM public.messages
+		WHERE id = $1
+	`, messageID)
+	return err
+}
@@ -0,0 +1,11 @@
+package queries
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+// HeldMessage is a message in the moderation queue.
+type HeldMessage struct {
+	GetMessagesQueryRow
+	// Reasons describe why moderation held the message.
+	Reasons []string           `db:"reasons"`
+	HeldAt  pgtype.Timestamptz `db:"held_at"`
+}
@@ -0,0 +1,275 @@
+package queries
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrDeliveryNotFound is returned when no matching webhook delivery exists.
+var ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+// CreateOutboxEvent adds a domain event to the outbox. Call it in the same
+// transaction as the change the event describes.
+func (q Queries) CreateOutboxEvent(ctx context.Context, eventType string, payload json.RawMessage) error {
+	_, err := q.db.Exec(ctx, `
+		INSERT INTO public.outbox_events (event_type, payload)
+		VALUES ($1, $2)
+	`, eventType, string(payload))
+	return err
+}
+
+// FanOutOutboxEvents creates a pending delivery of up to limit undispatched
+// events for each active webhook subscribed to them, and marks the events
+// dispatched. It is a single statement, so concurrent dispatchers skip each
+// other's events rather than duplicating deliveries.
+// Returns the number of events dispatched.
+func (q Queries) FanOutOutboxEvents(ctx context.Context, limit int) (int, error) {
+	tag, err := q.db.Exec(ctx, `
+		WITH events AS (
+			SELECT id, event_type
+			FROM public.outbox_events
+			WHERE dispatched_at IS NULL
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), deliveries AS (
+			INSERT INTO public.webhook_deliveries (webhook_id, event_id)
+			SELECT w.id, e.id
+			FROM events e
+			JOIN public.webhooks w
+				ON w.active AND (cardinality(w.event_types) = 0 OR e.event_type = ANY(w.event_types))
+			ON CONFLICT (webhook_id, event_id) DO NOTHING
+		)
+		UPDATE public.outbox_events
+		SET dispatched_at = CURRENT_TIMESTAMP
+		WHERE id IN (SELECT id FROM events)
+	`, limit)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// FanOutOutboxEvents runs Queries.FanOutOutboxEvents on a new connection.
+func FanOutOutboxEvents(ctx context.Context, limit int) (int, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) (int, error) {
+		return q.FanOutOutboxEvents(ctx, limit)
+	})
+}
+
+// ClaimWebhookDeliveries claims up to limit pending deliveries that are due,
+// counting the attempt and pushing their next attempt lease into the future
+// so other dispatchers skip them. A dispatcher that crashes mid-delivery
+// leaves the delivery to be retried once the lease expires.
+func (q Queries) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]ClaimedWebhookDelivery, error) {
+	rows, err := q.db.Query(ctx, `
+		WITH claimed AS (
+			SELECT id
+			FROM public.webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE public.webhook_deliveries d
+		SET attempts = d.attempts + 1,
+			next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2),
+			updated_at = CURRENT_TIMESTAMP
+		FROM claimed c, public.outbox_events e, public.webhooks w
+		WHERE d.id = c.id AND e.id = d.event_id AND w.id = d.webhook_id
+		RETURNING d.id, d.webhook_id, d.event_id, d.attempts, e.event_type, e.payload, e.created_at, w.url, w.secret
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []ClaimedWebhookDelivery{}
+	for rows.Next() {
+		var delivery ClaimedWebhookDelivery
+		if err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.EventID,
+			&delivery.Attempts,
+			&delivery.EventType,
+			&delivery.Payload,
+			&delivery.EventCreatedAt,
+			&delivery.URL,
+			&delivery.Secret,
+		); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// ClaimWebhookDeliveries runs Queries.ClaimWebhookDeliveries on a new connection.
+func ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]ClaimedWebhookDelivery, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]ClaimedWebhookDelivery, error) {
+		return q.ClaimWebhookDeliveries(ctx, limit, lease)
+	})
+}
+
+// CompleteWebhookDelivery records a successful attempt and marks the delivery succeeded.
+func (q Queries) CompleteWebhookDelivery(ctx context.Context, deliveryID int64, statusCode int, latency time.Duration) error {
+	_, err := q.db.Exec(ctx, `
+		WITH attempt AS (
+			INSERT INTO public.webhook_delivery_attempts (delivery_id, status_code, latency_ms)
+			VALUES ($1, $2, $3)
+		)
+		UPDATE public.webhook_deliveries
+		SET status = 'succeeded', last_status_code = $2, last_error = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, deliveryID, statusCode, latency.Milliseconds())
+	return err
+}
+
+// CompleteWebhookDelivery runs Queries.CompleteWebhookDelivery on a new connection.
+func CompleteWebhookDelivery(ctx context.Context, deliveryID int64, statusCode int, latency time.Duration) error {
+	_, err := withConnection(ctx, func(ctx context.Context, q Queries) (struct{}, error) {
+		return struct{}{}, q.CompleteWebhookDelivery(ctx, deliveryID, statusCode, latency)
+	})
+	return err
+}
+
+// FailWebhookDelivery records a failed attempt, scheduling a retry at
+// params.RetryAt or, if it is zero, marking the delivery dead.
+func (q Queries) FailWebhookDelivery(ctx context.Context, params FailWebhookDeliveryParams) error {
+	status, retryAt := DeliveryPending, pgtype.Timestamptz{Time: params.RetryAt, Valid: true}
+	if params.RetryAt.IsZero() {
+		status, retryAt = DeliveryDead, pgtype.Timestamptz{}
+	}
+	statusCode := pgtype.Int4{Int32: int32(params.StatusCode), Valid: params.StatusCode != 0}
+
+	_, err := q.db.Exec(ctx, `
+		WITH attempt AS (
+			INSERT INTO public.webhook_delivery_attempts (delivery_id, status_code, latency_ms, error)
+			VALUES ($1, $4, $6, $5)
+		)
+		UPDATE public.webhook_deliveries
+		SET status = $2,
+			next_attempt_at = COALESCE($3, next_attempt_at),
+			last_status_code = $4,
+			last_error = $5,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, params.ID, status, retryAt, statusCode, params.Error, params.Latency.Milliseconds())
+	return err
+}
+
+// FailWebhookDelivery runs Queries.FailWebhookDelivery on a new connection.
+func FailWebhookDelivery(ctx context.Context, params FailWebhookDeliveryParams) error {
+	_, err := withConnection(ctx, func(ctx context.Context, q Queries) (struct{}, error) {
+		return struct{}{}, q.FailWebhookDelivery(ctx, params)
+	})
+	return err
+}
+
+// selectWebhookDeliveries selects the columns of WebhookDelivery from
+// public.webhook_deliveries d joined to its event e.
+const selectWebhookDeliveries = `
+	SELECT
+		d.id,
+		d.webhook_id,
+		d.event_id,
+		e.event_type,
+		e.payload,
+		d.status,
+		d.attempts,
+		d.next_attempt_at,
+		d.last_status_code,
+		d.last_error,
+		d.created_at,
+		d.updated_at
+	FROM public.webhook_deliveries d
+	JOIN public.outbox_events e ON e.id = d.event_id
+`
+
+// GetDeadWebhookDeliveries returns up to limit deliveries that ran out of
+// attempts, newest first. A non-zero beforeID returns only older deliveries.
+func (q Queries) GetDeadWebhookDeliveries(ctx context.Context, limit int, beforeID int64) ([]WebhookDelivery, error) {
+	rows, err := q.db.Query(ctx, selectWebhookDeliveries+`
+		WHERE d.status = 'dead' AND ($2::bigint = 0 OR d.id < $2::bigint)
+		ORDER BY d.id DESC
+		LIMIT $1
+	`, limit, beforeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanWebhookDeliveries(rows)
+}
+
+// GetDeadWebhookDeliveries runs Queries.GetDeadWebhookDeliveries on a new connection.
+func GetDeadWebhookDeliveries(ctx context.Context, limit int, beforeID int64) ([]WebhookDelivery, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]WebhookDelivery, error) {
+		return q.GetDeadWebhookDeliveries(ctx, limit, beforeID)
+	})
+}
+
+// RetryWebhookDelivery returns a dead delivery to the queue with its attempts reset.
+// Returns ErrDeliveryNotFound if there is no dead delivery with that ID.
+func (q Queries) RetryWebhookDelivery(ctx context.Context, deliveryID int64) (WebhookDelivery, error) {
+	tag, err := q.db.Exec(ctx, `
+		UPDATE public.webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'dead'
+	`, deliveryID)
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		return WebhookDelivery{}, ErrDeliveryNotFound
+	}
+
+	rows, err := q.db.Query(ctx, selectWebhookDeliveries+`
+		WHERE d.id = $1
+	`, deliveryID)
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+	deliveries, err := scanWebhookDeliveries(rows)
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+	if len(deliveries) == 0 {
+		return WebhookDelivery{}, ErrDeliveryNotFound
+	}
+	return deliveries[0], nil
+}
+
+func scanWebhookDeliveries(rows pgx.Rows) ([]WebhookDelivery, error) {
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var delivery WebhookDelivery
+		if err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.EventID,
+			&delivery.EventType,
+			&delivery.Payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.NextAttemptAt,
+			&delivery.LastStatusCode,
+			&delivery.LastError,
+			&delivery.CreatedAt,
+			&delivery.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,58 @@
+package queries
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Webhook delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+// ClaimedWebhookDelivery is a delivery claimed by the dispatcher, with
+// everything needed to send it.
+type ClaimedWebhookDelivery struct {
+	ID             int64              `db:"id"`
+	WebhookID      int                `db:"webhook_id"`
+	EventID        int64              `db:"event_id"`
+	Attempts       int32              `db:"attempts"`
+	EventType      string             `db:"event_type"`
+	Payload        json.RawMessage    `db:"payload"`
+	EventCreatedAt pgtype.Timestamptz `db:"event_created_at"`
+	URL            string             `db:"url"`
+	Secret         string             `db:"secret"`
+}
+
+// WebhookDelivery is a delivery and its event, as shown to administrators.
+type WebhookDelivery struct {
+	ID             int64              `db:"id"`
+	WebhookID      int                `db:"webhook_id"`
+	EventID        int64              `db:"event_id"`
+	EventType      string             `db:"event_type"`
+	Payload        json.RawMessage    `db:"payload"`
+	Status         string             `db:"status"`
+	Attempts       int32              `db:"attempts"`
+	NextAttemptAt  pgtype.Timestamptz `db:"next_attempt_at"`
+	LastStatusCode pgtype.Int4        `db:"last_status_code"`
+	LastError      pgtype.Text        `db:"last_error"`
+	CreatedAt      pgtype.Timestamptz `db:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `db:"updated_at"`
+
+	// History holds the delivery's attempts, oldest first, when loaded.
+	History []WebhookDeliveryAttempt `db:"-"`
+}
+
+type FailWebhookDeliveryParams struct {
+	ID int64
+	// StatusCode is the response status, or zero if no response was received.
+	StatusCode int
+	Error      string
+	Latency    time.Duration
+	// RetryAt schedules the next attempt; if zero the delivery is dead.
+	RetryAt time.Time
+}
@@ -0,0 +1,103 @@
+package queries
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrReactionNotFound is returned when a user has not reacted to a message with an emoji.
+var ErrReactionNotFound = errors.New("reaction not found")
+
+// AddReaction records a user's reaction to a message. Reacting twice with
+// the same emoji is not an error; added reports whether the reaction is new.
+// Returns ErrMessageNotFound if the message does not exist or has expired.
+func (q Queries) AddReaction(ctx context.Context, params ReactionParams) (added bool, err error) {
+	var messageID int
+	err = q.db.QueryRow(ctx, `
+		INSERT INTO public.message_reactions (message_id, user_id, emoji)
+		SELECT id, $2, $3
+		FROM public.messages
+		WHERE id = $1 AND `+messageVisible+`
+		ON CONFLICT DO NOTHING
+		RETURNING message_id
+	`, params.MessageID, params.UserID, params.Emoji).Scan(&messageID)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return false, err
+	}
+
+	// Nothing was inserted: either the reaction exists or the message does not.
+	var exists bool
+	err = q.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM public.messages WHERE id = $1 AND `+messageVisible+`)
+	`, params.MessageID).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, ErrMessageNotFound
+	}
+	return false, nil
+}
+
+// RemoveReaction deletes a user's reaction to a message, or returns
+// ErrReactionNotFound if they have not reacted with that emoji.
+func (q Queries) RemoveReaction(ctx context.Context, params ReactionParams) error {
+	tag, err := q.db.Exec(ctx, `
+		DELETE FROM public.message_reactions
+		WHERE message_id = $1 AND user_id = $2 AND emoji = $3
+	`, params.MessageID, params.UserID, params.Emoji)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrReactionNotFound
+	}
+	return nil
+}
+
+// GetReactionSummaries aggregates the reactions to the given messages in a
+// single query, ordered by message and then by when each emoji was first
+// used. ReactedByViewer is set for emoji viewerID reacted with; pass zero
+// when there is no viewer.
+func (q Queries) GetReactionSummaries(ctx context.Context, messageIDs []int, viewerID int) ([]ReactionSummary, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT message_id, emoji, COUNT(*), bool_or(user_id = $2), MAX(created_at)
+		FROM public.message_reactions
+		WHERE message_id = ANY($1)
+		GROUP BY message_id, emoji
+		ORDER BY message_id, MIN(created_at), emoji
+	`, messageIDs, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []ReactionSummary{}
+	for rows.Next() {
+		var summary ReactionSummary
+		if err := rows.Scan(
+			&summary.MessageID,
+			&summary.Emoji,
+			&summary.Count,
+			&summary.ReactedByViewer,
+			&summary.LastReactedAt,
+		); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, rows.Err()
+}
+
+// GetReactionSummaries runs Queries.GetReactionSummaries on a new connection.
+func GetReactionSummaries(ctx context.Context, messageIDs []int, viewerID int) ([]ReactionSummary, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]ReactionSummary, error) {
+		return q.GetReactionSummaries(ctx, messageIDs, viewerID)
+	})
+}
@@ -0,0 +1,20 @@
+package queries
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+// ReactionSummary aggregates the reactions to a message with one emoji.
+type ReactionSummary struct {
+	MessageID int    `db:"message_id"`
+	Emoji     string `db:"emoji"`
+	Count     int32  `db:"count"`
+	// ReactedByViewer is whether the user the summary was read for reacted with Emoji.
+	ReactedByViewer bool `db:"reacted_by_viewer"`
+	// LastReactedAt is when the most recent of the reactions was made.
+	LastReactedAt pgtype.Timestamptz `db:"last_reacted_at"`
+}
+
+type ReactionParams struct {
+	MessageID int
+	UserID    int
+	Emoji     string
+}
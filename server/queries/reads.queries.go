@@ -0,0 +1,92 @@
+package queries
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// messageUnread holds for messages m after the read marker r, or approved
+// since r last moved: a held message keeps the ID it was written with, which
+// the marker may have passed while the message was hidden.
+const messageUnread = `(r.last_read_message_id IS NULL OR m.id > r.last_read_message_id OR m.approved_at > r.updated_at)`
+
+// MarkRead moves a user's read marker in a conversation forward to
+// messageID. A marker is never moved back, so marking an older message read
+// leaves its position unchanged, though marking a message approved since the
+// marker last moved stamps the marker so that message counts as read.
+func (q Queries) MarkRead(ctx context.Context, userID, conversationID, messageID int) (ReadMarker, error) {
+	var marker ReadMarker
+	err := q.db.QueryRow(ctx, `
+		INSERT INTO public.message_read_markers AS r (user_id, conversation_id, last_read_message_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, conversation_id) DO UPDATE
+		SET last_read_message_id = GREATEST(r.last_read_message_id, EXCLUDED.last_read_message_id), updated_at = CURRENT_TIMESTAMP
+		WHERE r.last_read_message_id < EXCLUDED.last_read_message_id
+			OR EXISTS (
+				SELECT 1
+				FROM public.messages m
+				WHERE m.id = EXCLUDED.last_read_message_id AND m.approved_at > r.updated_at
+			)
+		RETURNING user_id, conversation_id, last_read_message_id, updated_at
+	`, userID, conversationID, messageID).Scan(&marker.UserID, &marker.ConversationID, &marker.LastReadMessageID, &marker.UpdatedAt)
+	if err == nil {
+		return marker, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return ReadMarker{}, err
+	}
+
+	// The marker was already past messageID, which was read before then.
+	err = q.db.QueryRow(ctx, `
+		SELECT user_id, conversation_id, last_read_message_id, updated_at
+		FROM public.message_read_markers
+		WHERE user_id = $1 AND conversation_id = $2
+	`, userID, conversationID).Scan(&marker.UserID, &marker.ConversationID, &marker.LastReadMessageID, &marker.UpdatedAt)
+	return marker, err
+}
+
+// GetUnreadCount counts the visible messages by other users in a
+// conversation that are newer than a user's read marker there or were
+// approved since it last moved, or all of them if the user has never read
+// the conversation.
+func (q Queries) GetUnreadCount(ctx context.Context, userID, conversationID int) (int, error) {
+	var count int
+	err := q.db.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM public.messages m
+		LEFT JOIN public.message_read_markers r ON r.user_id = $1 AND r.conversation_id = m.conversation_id
+		WHERE m.conversation_id = $2
+			AND `+messageUnread+`
+			AND m.user_id <> $1
+			AND `+messageVisible+`
+	`, userID, conversationID).Scan(&count)
+	return count, err
+}
+
+// GetUnreadCounts counts a user's unread messages, as GetUnreadCount does,
+// in every conversation with any, in conversation ID order.
+func (q Queries) GetUnreadCounts(ctx context.Context, userID int) ([]ConversationUnreadCount, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT m.conversation_id, COUNT(*)
+		FROM public.messages m
+		LEFT JOIN public.message_read_markers r ON r.user_id = $1 AND r.conversation_id = m.conversation_id
+		WHERE `+messageUnread+`
+			AND m.user_id <> $1
+			AND `+messageVisible+`
+		GROUP BY m.conversation_id
+		ORDER BY m.conversation_id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[ConversationUnreadCount])
+}
+
+// GetUnreadCounts runs Queries.GetUnreadCounts on a new connection.
+func GetUnreadCounts(ctx context.Context, userID int) ([]ConversationUnreadCount, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]ConversationUnreadCount, error) {
+		return q.GetUnreadCounts(ctx, userID)
+	})
+}
@@ -0,0 +1,18 @@
+package queries
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+// ReadMarker records the newest message a user has read in a conversation.
+type ReadMarker struct {
+	UserID            int                `db:"user_id"`
+	ConversationID    int                `db:"conversation_id"`
+	LastReadMessageID int                `db:"last_read_message_id"`
+	UpdatedAt         pgtype.Timestamptz `db:"updated_at"`
+}
+
+// ConversationUnreadCount is the number of messages a user has not read in a
+// conversation.
+type ConversationUnreadCount struct {
+	ConversationID int `db:"conversation_id"`
+	Count          int `db:"count"`
+}
@@ -0,0 +1,237 @@
+package queries
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrRetentionPolicyNotFound is returned when no matching retention policy exists.
+var ErrRetentionPolicyNotFound = errors.New("retention policy not found")
+
+// GetRetentionPolicies returns all retention policies: the global default
+// first, then the user type policies, then the conversation policies.
+func (q Queries) GetRetentionPolicies(ctx context.Context) ([]RetentionPolicy, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT id, user_type, conversation_id, max_age_days, action, updated_at
+		FROM public.retention_policies
+		ORDER BY conversation_id NULLS FIRST, user_type NULLS FIRST
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []RetentionPolicy{}
+	for rows.Next() {
+		var policy RetentionPolicy
+		if err := rows.Scan(
+			&policy.ID,
+			&policy.UserType,
+			&policy.ConversationID,
+			&policy.MaxAgeDays,
+			&policy.Action,
+			&policy.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, rows.Err()
+}
+
+// GetRetentionPolicies runs Queries.GetRetentionPolicies on a new connection.
+func GetRetentionPolicies(ctx context.Context) ([]RetentionPolicy, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]RetentionPolicy, error) {
+		return q.GetRetentionPolicies(ctx)
+	})
+}
+
+// SetRetentionPolicy creates or replaces the retention policy for
+// params.UserType and params.ConversationID.
+func (q Queries) SetRetentionPolicy(ctx context.Context, params SetRetentionPolicyParams) (RetentionPolicy, error) {
+	var policy RetentionPolicy
+	err := q.db.QueryRow(ctx, `
+		INSERT INTO public.retention_policies (user_type, conversation_id, max_age_days, action)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_type, conversation_id) DO UPDATE
+		SET max_age_days = EXCLUDED.max_age_days, action = EXCLUDED.action, updated_at = CURRENT_TIMESTAMP
+		RETURNING id, user_type, conversation_id, max_age_days, action, updated_at
+	`, params.UserType, params.ConversationID, params.MaxAgeDays, params.Action).Scan(
+		&policy.ID,
+		&policy.UserType,
+		&policy.ConversationID,
+		&policy.MaxAgeDays,
+		&policy.Action,
+		&policy.UpdatedAt,
+	)
+	return policy, err
+}
+
+// SetRetentionPolicy runs Queries.SetRetentionPolicy on a new connection.
+func SetRetentionPolicy(ctx context.Context, params SetRetentionPolicyParams) (RetentionPolicy, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) (RetentionPolicy, error) {
+		return q.SetRetentionPolicy(ctx, params)
+	})
+}
+
+// DeleteRetentionPolicy removes the retention policy for userType or
+// conversationID (both nil for the global default), or returns
+// ErrRetentionPolicyNotFound.
+func (q Queries) DeleteRetentionPolicy(ctx context.Context, userType *string, conversationID *int) error {
+	tag, err := q.db.Exec(ctx, `
+		DELETE FROM public.retention_policies
+		WHERE user_type IS NOT DISTINCT FROM $1 AND conversation_id IS NOT DISTINCT FROM $2
+	`, userType, conversationID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRetentionPolicyNotFound
+	}
+	return nil
+}
+
+// DeleteRetentionPolicy runs Queries.DeleteRetentionPolicy on a new connection.
+func DeleteRetentionPolicy(ctx context.Context, userType *string, conversationID *int) error {
+	_, err := withConnection(ctx, func(ctx context.Context, q Queries) (struct{}, error) {
+		return struct{}{}, q.DeleteRetentionPolicy(ctx, userType, conversationID)
+	})
+	return err
+}
+
+// DeleteExpiredMessages deletes up to limit messages whose expires_at has
+// passed, touching their authors, and returns them.
+func (q Queries) DeleteExpiredMessages(ctx context.Context, limit int) ([]RemovedMessage, error) {
+	rows, err := q.db.Query(ctx, `
+		WITH due AS (
+			SELECT id
+			FROM public.messages
+			WHERE expires_at <= CURRENT_TIMESTAMP
+			ORDER BY expires_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		DELETE FROM public.messages m
+		USING due
+		WHERE m.id = due.id
+		RETURNING m.id, m.user_id
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return q.touchAuthors(ctx, rows)
+}
+
+// retainedMessagesDue selects the IDs of up to $2 messages older than the
+// maximum age of the retention policy that applies to them, if its action
+// is $1. A policy for the message's conversation takes precedence over one
+// for the author's user type, which takes precedence over the global default.
+const retainedMessagesDue = `
+	SELECT m.id
+	FROM public.messages m
+	JOIN public.users u ON u.id = m.user_id
+	LEFT JOIN public.retention_policies cp ON cp.conversation_id = m.conversation_id
+	LEFT JOIN public.retention_policies tp ON tp.user_type = u.user_type
+	LEFT JOIN public.retention_policies gp ON gp.user_type IS NULL AND gp.conversation_id IS NULL
+	WHERE COALESCE(cp.action, tp.action, gp.action) = $1
+		AND m.created_at < CURRENT_TIMESTAMP - make_interval(days => COALESCE(cp.max_age_days, tp.max_age_days, gp.max_age_days))
+	ORDER BY m.id
+	LIMIT $2
+	FOR UPDATE OF m SKIP LOCKED
+`
+
+// ApplyRetentionPolicies deletes or archives, according to action, up to
+// limit messages that have outlived the retention policy applying to them
+// and whose action is action, touching their authors. Returns the messages
+// removed from public.messages. Attachments of archived messages move with
+// them, so they are not purged as detached. An archived message whose ID is already in
+// public.archived_messages fails the statement rather than being dropped, so
+// run it in a transaction.
+func (q Queries) ApplyRetentionPolicies(ctx context.Context, action string, limit int) ([]RemovedMessage, error) {
+	query := `
+		WITH due AS (` + retainedMessagesDue + `)
+		DELETE FROM public.messages m
+		USING due
+		WHERE m.id = due.id
+		RETURNING m.id, m.user_id
+	`
+	if action == RetentionArchive {
+		query = `
+			WITH due AS (` + retainedMessagesDue + `), removed AS (
+				DELETE FROM public.messages m
+				USING due
+				WHERE m.id = due.id
+				RETURNING m.id, m.user_id, m.content, m.created_at, m.expires_at, m.parent_id, m.conversation_id, m.held
+			), archived AS (
+				INSERT INTO public.archived_messages (id, user_id, content, created_at, expires_at, parent_id, conversation_id, held)
+				SELECT id, user_id, content, created_at, expires_at, parent_id, conversation_id, held
+				FROM removed
+			), rehomed AS (
+				UPDATE public.message_attachments a
+				SET message_id = NULL, archived_message_id = a.message_id
+				FROM removed
+				WHERE a.message_id = removed.id
+			)
+			SELECT id, user_id FROM removed
+		`
+	}
+
+	rows, err := q.db.Query(ctx, query, action, limit)
+	if err != nil {
+		return nil, err
+	}
+	return q.touchAuthors(ctx, rows)
+}
+
+// touchAuthors collects removed messages and touches their authors.
+func (q Queries) touchAuthors(ctx context.Context, rows pgx.Rows) ([]RemovedMessage, error) {
+	removed, err := pgx.CollectRows(rows, pgx.RowToStructByPos[RemovedMessage])
+	if err != nil || len(removed) == 0 {
+		return removed, err
+	}
+	authors := make([]int, len(removed))
+	for i, message := range removed {
+		authors[i] = message.UserID
+	}
+	return removed, q.TouchUsers(ctx, authors)
+}
+
+// ExportUserArchivedMessages calls fn for each of a user's archived messages
+// in ID order, stopping at the first error from fn.
+func (q Queries) ExportUserArchivedMessages(ctx context.Context, userID int, fn func(ArchivedMessage) error) error {
+	rows, err := q.db.Query(ctx, `
+		SELECT id, user_id, content, created_at, expires_at, parent_id, conversation_id, held, archived_at
+		FROM public.archived_messages
+		WHERE user_id = $1
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var message ArchivedMessage
+		if err := rows.Scan(
+			&message.ID,
+			&message.UserID,
+			&message.Content,
+			&message.CreatedAt,
+			&message.ExpiresAt,
+			&message.ParentID,
+			&message.ConversationID,
+			&message.Held,
+			&message.ArchivedAt,
+		); err != nil {
+			return err
+		}
+		if err := fn(message); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,68 @@
+package queries
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errRollback ends a test transaction without committing it.
+var errRollback = errors.New("rollback")
+
+func TestArchivedAttachmentsAreNotDetached(t *testing.T) {
+	requireDatabase(t)
+	ctx := context.Background()
+
+	err := WithTx(ctx, func(tx Queries) error {
+		suffix := time.Now().UnixNano()
+		user, err := tx.CreateUser(ctx, CreateUserParams{
+			Username: fmt.Sprintf("archiver%d", suffix),
+			Email:    fmt.Sprintf("archiver%d@example.com", suffix),
+			UserType: "UTYPE_USER",
+		})
+		require.NoError(t, err)
+		conversation, err := tx.CreateConversation(ctx, fmt.Sprintf("archive %d", suffix))
+		require.NoError(t, err)
+		message, err := tx.CreateMessage(ctx, CreateMessageParams{UserID: user.ID, Content: "old", ConversationID: conversation.ID})
+		require.NoError(t, err)
+		attachment, err := tx.CreateAttachment(ctx, CreateAttachmentParams{
+			MessageID:   message.ID,
+			StorageKey:  fmt.Sprintf("archive-test/%d", suffix),
+			Filename:    "old.txt",
+			ContentType: "text/plain",
+			SizeBytes:   3,
+		})
+		require.NoError(t, err)
+
+		_, err = tx.db.Exec(ctx, `UPDATE public.messages SET created_at = CURRENT_TIMESTAMP - INTERVAL '2 days' WHERE id = $1`, message.ID)
+		require.NoError(t, err)
+		_, err = tx.SetRetentionPolicy(ctx, SetRetentionPolicyParams{ConversationID: &conversation.ID, MaxAgeDays: 1, Action: RetentionArchive})
+		require.NoError(t, err)
+
+		removed, err := tx.ApplyRetentionPolicies(ctx, RetentionArchive, 1000)
+		require.NoError(t, err)
+		assert.Contains(t, removed, RemovedMessage{ID: message.ID, UserID: user.ID})
+
+		detachedIDs := func() []int {
+			detached, err := tx.GetDetachedAttachments(ctx, 1000)
+			require.NoError(t, err)
+			ids := make([]int, len(detached))
+			for i, a := range detached {
+				ids[i] = a.ID
+			}
+			return ids
+		}
+		assert.NotContains(t, detachedIDs(), attachment.ID, "attachment of an archived message")
+
+		_, err = tx.DeleteUserArchivedMessages(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Contains(t, detachedIDs(), attachment.ID, "attachment of a deleted archived message")
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+}
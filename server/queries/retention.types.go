@@ -0,0 +1,52 @@
+package queries
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+// Retention policy actions.
+const (
+	// RetentionPurge deletes messages older than the policy's maximum age.
+	RetentionPurge = "purge"
+	// RetentionArchive moves them to public.archived_messages.
+	RetentionArchive = "archive"
+)
+
+type RetentionPolicy struct {
+	ID int `db:"id"`
+	// UserType the policy applies to, or null for the global default.
+	UserType pgtype.Text `db:"user_type"`
+	// ConversationID the policy applies to, or null for the global default.
+	ConversationID pgtype.Int4        `db:"conversation_id"`
+	MaxAgeDays     int32              `db:"max_age_days"`
+	Action         string             `db:"action"`
+	UpdatedAt      pgtype.Timestamptz `db:"updated_at"`
+}
+
+// ArchivedMessage is a message moved to public.archived_messages by a
+// retention policy.
+type ArchivedMessage struct {
+	ID             int                `db:"id"`
+	UserID         int                `db:"user_id"`
+	Content        string             `db:"content"`
+	CreatedAt      pgtype.Timestamptz `db:"created_at"`
+	ExpiresAt      pgtype.Timestamptz `db:"expires_at"`
+	ParentID       pgtype.Int4        `db:"parent_id"`
+	ConversationID int                `db:"conversation_id"`
+	// Held is set if the message was still held for review when archived.
+	Held       bool               `db:"held"`
+	ArchivedAt pgtype.Timestamptz `db:"archived_at"`
+}
+
+// RemovedMessage is a message deleted or archived by a retention sweep.
+type RemovedMessage struct {
+	ID     int `db:"id"`
+	UserID int `db:"user_id"`
+}
+
+type SetRetentionPolicyParams struct {
+	// UserType the policy applies to, or nil for the global default.
+	UserType *string
+	// ConversationID the policy applies to, or nil for the global default.
+	ConversationID *int
+	MaxAgeDays     int
+	Action         string
+}
@@ -2,21 +2,28 @@ package queries
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+var (
+	// ErrUserNotFound is returned when no user exists with the requested ID.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrVersionMismatch is returned when a conditional update finds the user
+	// was modified since the version the caller last saw.
+	ErrVersionMismatch = errors.New("user has been modified since it was last retrieved")
+)
+
 // GetUsers retrieves all users from the database with their associated permissions and message counts.
 // Returns:
 //   - []GetUsersQueryRow: Slice of user records with permissions and message counts.
 //   - error: Database error if query fails.
-func GetUsers() ([]GetUsersQueryRow, error) {
-	conn := GetConnection()
-	defer conn.Close(context.TODO())
-
-	rows, err := conn.Query(context.TODO(), `
+func (q Queries) GetUsers(ctx context.Context) ([]GetUsersQueryRow, error) {
+	rows, err := q.db.Query(ctx, `
 		SELECT DISTINCT
 			u.id, 
 			u.username, 
@@ -24,11 +31,14 @@ func GetUsers() ([]GetUsersQueryRow, error) {
 			u.user_type, 
 			u.nickname,
 			ut.permission_bitfield::text,
-			COUNT(m.id) as message_count
+			COUNT(m.id) as message_count,
+			u.version,
+			GREATEST(u.updated_at, MAX(m.created_at), `+lastExpiry+`) as updated_at,
+			u.avatar_key
 		FROM public.users u
 		LEFT JOIN public.user_types ut ON ut.type_key = u.user_type
-		LEFT JOIN public.messages m ON m.user_id = u.id
-		GROUP BY u.id, u.username, u.email, u.user_type, u.nickname, ut.permission_bitfield
+		LEFT JOIN public.messages m ON m.user_id = u.id AND `+messageVisible+`
+		GROUP BY u.id, u.username, u.email, u.user_type, u.nickname, ut.permission_bitfield, u.version, u.updated_at, u.avatar_key
 		ORDER BY u.id
 	`)
 	if err != nil {
@@ -47,6 +57,9 @@ func GetUsers() ([]GetUsersQueryRow, error) {
 			&user.Nickname,
 			&user.PermissionBitfield,
 			&user.MessageCount,
+			&user.Version,
+			&user.UpdatedAt,
+			&user.AvatarKey,
 		); err != nil {
 			return nil, err
 		}
@@ -56,6 +69,130 @@ func GetUsers() ([]GetUsersQueryRow, error) {
 	return users, nil
 }
 
+// GetUsers runs Queries.GetUsers on a new connection.
+func GetUsers(ctx context.Context) ([]GetUsersQueryRow, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]GetUsersQueryRow, error) {
+		return q.GetUsers(ctx)
+	})
+}
+
+// lastExpiry is the latest expires_at of u's messages that has passed: the
+// last time its message count dropped without a write to the row. With
+// TouchUsers and the newest visible message, it keeps updated_at, and so
+// Last-Modified, moving whenever the message count does.
+const lastExpiry = `(SELECT MAX(e.expires_at) FROM public.messages e WHERE e.user_id = u.id AND e.expires_at <= CURRENT_TIMESTAMP)`
+
+// selectUsers selects the columns of GetUsersQueryRow from public.users u.
+// Permissions and message counts are correlated subqueries, so callers can add
+// WHERE, ORDER BY and LIMIT clauses without grouping. Like every user query,
+// it only counts visible messages.
+const selectUsers = `
+	SELECT
+		u.id,
+		u.username,
+		u.email,
+		u.user_type,
+		u.nickname,
+		(SELECT ut.permission_bitfield::text FROM public.user_types ut WHERE ut.type_key = u.user_type LIMIT 1),
+		(SELECT COUNT(*) FROM public.messages m WHERE m.user_id = u.id AND ` + messageVisible + `)::int,
+		u.version,
+		GREATEST(
+			u.updated_at,
+			(SELECT MAX(m.created_at) FROM public.messages m WHERE m.user_id = u.id AND ` + messageVisible + `),
+			` + lastExpiry + `
+		),
+		u.avatar_key
+	FROM public.users u
+`
+
+// GetUsersPage retrieves a page of users ordered by ID.
+// Params:
+//   - limit: Maximum number of users to return.
+//   - offset: Number of users to skip.
+//
+// Returns:
+//   - []GetUsersQueryRow: User records with permissions and message counts.
+//   - error: Database error if query fails.
+func (q Queries) GetUsersPage(ctx context.Context, limit, offset int) ([]GetUsersQueryRow, error) {
+	rows, err := q.db.Query(ctx, selectUsers+`
+		ORDER BY u.id
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanUsers(rows)
+}
+
+// GetUsersPage runs Queries.GetUsersPage on a new connection.
+func GetUsersPage(ctx context.Context, limit, offset int) ([]GetUsersQueryRow, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]GetUsersQueryRow, error) {
+		return q.GetUsersPage(ctx, limit, offset)
+	})
+}
+
+// GetUsersByIDs retrieves the users with the given IDs in a single query.
+// Missing IDs are skipped; results are ordered by ID.
+func (q Queries) GetUsersByIDs(ctx context.Context, userIDs []int) ([]GetUsersQueryRow, error) {
+	rows, err := q.db.Query(ctx, selectUsers+`
+		WHERE u.id = ANY($1)
+		ORDER BY u.id
+	`, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanUsers(rows)
+}
+
+// GetUsersByIDs runs Queries.GetUsersByIDs on a new connection.
+func GetUsersByIDs(ctx context.Context, userIDs []int) ([]GetUsersQueryRow, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]GetUsersQueryRow, error) {
+		return q.GetUsersByIDs(ctx, userIDs)
+	})
+}
+
+// GetUser retrieves a single user with their permissions and message count.
+// Params:
+//   - userID: ID of the user to fetch.
+//
+// Returns:
+//   - GetUsersQueryRow: The user record.
+//   - error: ErrUserNotFound if no such user exists, or a database error.
+func (q Queries) GetUser(ctx context.Context, userID int) (GetUsersQueryRow, error) {
+	var user GetUsersQueryRow
+	err := q.db.QueryRow(ctx, selectUsers+`
+		WHERE u.id = $1
+	`, userID).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.UserType,
+		&user.Nickname,
+		&user.PermissionBitfield,
+		&user.MessageCount,
+		&user.Version,
+		&user.UpdatedAt,
+		&user.AvatarKey,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return GetUsersQueryRow{}, ErrUserNotFound
+	}
+	if err != nil {
+		return GetUsersQueryRow{}, err
+	}
+
+	return user, nil
+}
+
+// GetUser runs Queries.GetUser on a new connection.
+func GetUser(ctx context.Context, userID int) (GetUsersQueryRow, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) (GetUsersQueryRow, error) {
+		return q.GetUser(ctx, userID)
+	})
+}
+
 // CreateUser inserts a new user into the database and returns the created record.
 // Params:
 //   - params: User details (username, email, type, optional nickname).
@@ -63,10 +200,7 @@ func GetUsers() ([]GetUsersQueryRow, error) {
 // Returns:
 //   - GetUsersQueryRow: The newly created user with permissions.
 //   - error: Database error if insertion fails.
-func CreateUser(params CreateUserParams) (GetUsersQueryRow, error) {
-	conn := GetConnection()
-	defer conn.Close(context.TODO())
-
+func (q Queries) CreateUser(ctx context.Context, params CreateUserParams) (GetUsersQueryRow, error) {
 	var nickname pgtype.Text
 	if params.Nickname != nil && *params.Nickname != "" {
 		nickname = pgtype.Text{String: *params.Nickname, Valid: true}
@@ -75,10 +209,10 @@ func CreateUser(params CreateUserParams) (GetUsersQueryRow, error) {
 	}
 
 	var user GetUsersQueryRow
-	err := conn.QueryRow(context.TODO(), `
+	err := q.db.QueryRow(ctx, `
 		INSERT INTO public.users (username, email, user_type, nickname) 
 		VALUES ($1, $2, $3, $4) 
-		RETURNING id, username, email, user_type, nickname, message_count
+		RETURNING id, username, email, user_type, nickname, message_count, version, updated_at, avatar_key
 	`, params.Username, params.Email, params.UserType, nickname).Scan(
 		&user.ID,
 		&user.Username,
@@ -86,12 +220,15 @@ func CreateUser(params CreateUserParams) (GetUsersQueryRow, error) {
 		&user.UserType,
 		&user.Nickname,
 		&user.MessageCount,
+		&user.Version,
+		&user.UpdatedAt,
+		&user.AvatarKey,
 	)
 	if err != nil {
 		return GetUsersQueryRow{}, err
 	}
 
-	err = conn.QueryRow(context.TODO(), `
+	err = q.db.QueryRow(ctx, `
 		SELECT permission_bitfield::text 
 		FROM public.user_types 
 		WHERE type_key = $1
@@ -104,6 +241,13 @@ func CreateUser(params CreateUserParams) (GetUsersQueryRow, error) {
 	return user, nil
 }
 
+// CreateUser runs Queries.CreateUser in a transaction on a new connection.
+func CreateUser(ctx context.Context, params CreateUserParams) (GetUsersQueryRow, error) {
+	return inTx(ctx, func(ctx context.Context, q Queries) (GetUsersQueryRow, error) {
+		return q.CreateUser(ctx, params)
+	})
+}
+
 // UpdateUser modifies an existing user's fields (username, email, type, or nickname).
 // Params:
 //   - userID: ID of the user to update.
@@ -111,11 +255,10 @@ func CreateUser(params CreateUserParams) (GetUsersQueryRow, error) {
 //
 // Returns:
 //   - GetUsersQueryRow: Updated user record with permissions.
-//   - error: Database error or "no fields to update" if params are empty.
-func UpdateUser(userID int, params UpdateUserParams) (GetUsersQueryRow, error) {
-	conn := GetConnection()
-	defer conn.Close(context.TODO())
-
+//   - error: ErrUserNotFound, ErrVersionMismatch if params.MatchVersions does not
+//     include the current version, "no fields to update" if params are empty,
+//     or a database error.
+func (q Queries) UpdateUser(ctx context.Context, userID int, params UpdateUserParams) (GetUsersQueryRow, error) {
 	setParts := []string{}
 	args := []interface{}{}
 	argCount := 1
@@ -151,27 +294,41 @@ func UpdateUser(userID int, params UpdateUserParams) (GetUsersQueryRow, error) {
 		return GetUsersQueryRow{}, fmt.Errorf("no fields to update")
 	}
 
+	setParts = append(setParts, "version = version + 1", "updated_at = CURRENT_TIMESTAMP")
+	where := fmt.Sprintf("id = $%d", argCount)
 	args = append(args, userID)
+	argCount++
+	if len(params.MatchVersions) > 0 {
+		where += fmt.Sprintf(" AND version = ANY($%d)", argCount)
+		args = append(args, params.MatchVersions)
+	}
+
 	query := fmt.Sprintf(`
 		UPDATE public.users 
 		SET %s 
-		WHERE id = $%d 
-		RETURNING id, username, email, user_type, nickname
-	`, strings.Join(setParts, ", "), argCount)
+		WHERE %s 
+		RETURNING id, username, email, user_type, nickname, version, updated_at, avatar_key
+	`, strings.Join(setParts, ", "), where)
 
 	var user GetUsersQueryRow
-	err := conn.QueryRow(context.TODO(), query, args...).Scan(
+	err := q.db.QueryRow(ctx, query, args...).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.UserType,
 		&user.Nickname,
+		&user.Version,
+		&user.UpdatedAt,
+		&user.AvatarKey,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return GetUsersQueryRow{}, q.missingOrModified(ctx, userID)
+	}
 	if err != nil {
 		return GetUsersQueryRow{}, err
 	}
 
-	err = conn.QueryRow(context.TODO(), `
+	err = q.db.QueryRow(ctx, `
 		SELECT permission_bitfield::text 
 		FROM public.user_types 
 		WHERE type_key = $1
@@ -183,3 +340,192 @@ func UpdateUser(userID int, params UpdateUserParams) (GetUsersQueryRow, error) {
 	user.MessageCount = 0 // Optional: can query actual count
 	return user, nil
 }
+
+// missingOrModified explains why a conditional update of userID matched no row.
+func (q Queries) missingOrModified(ctx context.Context, userID int) error {
+	var exists bool
+	err := q.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM public.users WHERE id = $1)
+	`, userID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrVersionMismatch
+	}
+	return ErrUserNotFound
+}
+
+// UpdateUser runs Queries.UpdateUser in a transaction on a new connection.
+func UpdateUser(ctx context.Context, userID int, params UpdateUserParams) (GetUsersQueryRow, error) {
+	return inTx(ctx, func(ctx context.Context, q Queries) (GetUsersQueryRow, error) {
+		return q.UpdateUser(ctx, userID, params)
+	})
+}
+
+// SetUserAvatar sets the blob key of a user's avatar, or clears it if key is
+// null, and returns the updated user. Returns ErrUserNotFound if there is no
+// such user.
+func (q Queries) SetUserAvatar(ctx context.Context, userID int, key pgtype.Text) (GetUsersQueryRow, error) {
+	tag, err := q.db.Exec(ctx, `
+		UPDATE public.users
+		SET avatar_key = $2,
+			version = version + 1,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, userID, key)
+	if err != nil {
+		return GetUsersQueryRow{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		return GetUsersQueryRow{}, ErrUserNotFound
+	}
+	return q.GetUser(ctx, userID)
+}
+
+// TouchUsers sets the updated_at of the given users, which may repeat, to now.
+// Call it when their message counts change other than by a new visible
+// message, e.g. when messages are deleted or approved. The version is left
+// alone, as the user's own fields have not changed.
+func (q Queries) TouchUsers(ctx context.Context, userIDs []int) error {
+	_, err := q.db.Exec(ctx, `
+		UPDATE public.users
+		SET updated_at = CURRENT_TIMESTAMP
+		WHERE id = ANY($1)
+	`, userIDs)
+	return err
+}
+
+// SearchUsers finds users whose username, nickname or email matches the search term.
+// Prefix matches (case-insensitive) rank above trigram (pg_trgm) similarity matches.
+// Params:
+//   - term: Search term entered by the user.
+//   - limit: Maximum number of users to return.
+//
+// Returns:
+//   - []GetUsersQueryRow: Matching users ordered by relevance.
+//   - error: Database error if query fails.
+func (q Queries) SearchUsers(ctx context.Context, term string, limit int) ([]GetUsersQueryRow, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT
+			u.id,
+			u.username,
+			u.email,
+			u.user_type,
+			u.nickname,
+			ut.permission_bitfield::text,
+			COUNT(m.id) as message_count,
+			u.version,
+			GREATEST(u.updated_at, MAX(m.created_at), `+lastExpiry+`) as updated_at,
+			u.avatar_key
+		FROM public.users u
+		LEFT JOIN (
+			SELECT DISTINCT ON (type_key) type_key, permission_bitfield
+			FROM public.user_types
+		) ut ON ut.type_key = u.user_type
+		LEFT JOIN public.messages m ON m.user_id = u.id AND `+messageVisible+`
+		WHERE u.username ILIKE $2 || '%'
+			OR u.nickname ILIKE $2 || '%'
+			OR u.email ILIKE $2 || '%'
+			OR u.username % $1
+			OR u.nickname % $1
+			OR u.email % $1
+		GROUP BY u.id, u.username, u.email, u.user_type, u.nickname, ut.permission_bitfield, u.version, u.updated_at, u.avatar_key
+		ORDER BY
+			(u.username ILIKE $2 || '%' OR u.nickname ILIKE $2 || '%' OR u.email ILIKE $2 || '%') DESC,
+			GREATEST(
+				similarity(u.username, $1),
+				similarity(COALESCE(u.nickname, ''), $1),
+				similarity(u.email, $1)
+			) DESC,
+			u.id
+		LIMIT $3
+	`, term, escapeLikePattern(term), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanUsers(rows)
+}
+
+// SearchUsers runs Queries.SearchUsers on a new connection.
+func SearchUsers(ctx context.Context, term string, limit int) ([]GetUsersQueryRow, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]GetUsersQueryRow, error) {
+		return q.SearchUsers(ctx, term, limit)
+	})
+}
+
+// ExportUsers calls fn for every user in ID order, streaming rows from the
+// database rather than loading them all. It stops at the first error from fn.
+func (q Queries) ExportUsers(ctx context.Context, fn func(GetUsersQueryRow) error) error {
+	rows, err := q.db.Query(ctx, selectUsers+`
+		ORDER BY u.id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user GetUsersQueryRow
+		if err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.UserType,
+			&user.Nickname,
+			&user.PermissionBitfield,
+			&user.MessageCount,
+			&user.Version,
+			&user.UpdatedAt,
+			&user.AvatarKey,
+		); err != nil {
+			return err
+		}
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// ExportUsers runs Queries.ExportUsers on a new connection.
+func ExportUsers(ctx context.Context, fn func(GetUsersQueryRow) error) error {
+	_, err := withConnection(ctx, func(ctx context.Context, q Queries) (struct{}, error) {
+		return struct{}{}, q.ExportUsers(ctx, fn)
+	})
+	return err
+}
+
+// scanUsers reads all rows of a selectUsers-shaped query and closes them.
+func scanUsers(rows pgx.Rows) ([]GetUsersQueryRow, error) {
+	defer rows.Close()
+
+	users := []GetUsersQueryRow{}
+	for rows.Next() {
+		var user GetUsersQueryRow
+		if err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.UserType,
+			&user.Nickname,
+			&user.PermissionBitfield,
+			&user.MessageCount,
+			&user.Version,
+			&user.UpdatedAt,
+			&user.AvatarKey,
+		); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// escapeLikePattern escapes LIKE wildcards so user input is matched literally.
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -2,6 +2,7 @@ package queries
 
 import (
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/jackc/pgx/v5/pgtype"
@@ -104,3 +105,18 @@ func TestUpdateUser(t *testing.T) {
 
 	mockService.AssertExpectations(t)
 }
+
+func TestEscapeLikePattern(t *testing.T) {
+	assert.Equal(t, "liam", escapeLikePattern("liam"))
+	assert.Equal(t, `100\%`, escapeLikePattern("100%"))
+	assert.Equal(t, `a\_b`, escapeLikePattern("a_b"))
+	assert.Equal(t, `c:\\\\`, escapeLikePattern(`c:\\`))
+}
+
+func TestSelectUsersCountsVisibleMessages(t *testing.T) {
+	assert.Equal(t, 2, strings.Count(selectUsers, "m.user_id = u.id AND "+messageVisible))
+}
+
+func TestSelectUsersUpdatedAtIncludesLastExpiry(t *testing.T) {
+	assert.Contains(t, selectUsers, lastExpiry)
+}
@@ -10,6 +10,11 @@ type GetUsersQueryRow struct {
 	Nickname           pgtype.Text `db:"nickname"`
 	PermissionBitfield string      `db:"permission_bitfield"`
 	MessageCount       int32       `db:"message_count"`
+	Version            int32       `db:"version"`
+	// UpdatedAt is when the user or their messages last changed.
+	UpdatedAt pgtype.Timestamptz `db:"updated_at"`
+	// AvatarKey is the blob key of the user's avatar, if they have one.
+	AvatarKey pgtype.Text `db:"avatar_key"`
 }
 
 type CreateUserParams struct {
@@ -25,4 +30,8 @@ type UpdateUserParams struct {
 	Email    *string `json:"email,omitempty"`
 	UserType *string `json:"user_type,omitempty"`
 	Nickname *string `json:"nickname"` // Nullable on purpose
+
+	// MatchVersions, if non-empty, makes the update apply only when the
+	// user's current version is one of these (optimistic concurrency).
+	MatchVersions []int32 `json:"-"`
 }
@@ -0,0 +1,247 @@
+package queries
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrWebhookNotFound is returned when no webhook exists with the requested ID.
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// CreateWebhook registers a webhook and returns it.
+func (q Queries) CreateWebhook(ctx context.Context, params CreateWebhookParams) (Webhook, error) {
+	var webhook Webhook
+	err := q.db.QueryRow(ctx, `
+		INSERT INTO public.webhooks (url, secret, event_types)
+		VALUES ($1, $2, $3)
+		RETURNING id, url, secret, event_types, active, created_at
+	`, params.URL, params.Secret, params.EventTypes).Scan(
+		&webhook.ID,
+		&webhook.URL,
+		&webhook.Secret,
+		&webhook.EventTypes,
+		&webhook.Active,
+		&webhook.CreatedAt,
+	)
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+// GetWebhooks retrieves all webhooks ordered by ID.
+func (q Queries) GetWebhooks(ctx context.Context) ([]Webhook, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT id, url, secret, event_types, active, created_at
+		FROM public.webhooks
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		var webhook Webhook
+		if err := rows.Scan(
+			&webhook.ID,
+			&webhook.URL,
+			&webhook.Secret,
+			&webhook.EventTypes,
+			&webhook.Active,
+			&webhook.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// GetWebhooks runs Queries.GetWebhooks on a new connection.
+func GetWebhooks(ctx context.Context) ([]Webhook, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]Webhook, error) {
+		return q.GetWebhooks(ctx)
+	})
+}
+
+// DeleteWebhook removes a webhook along with its deliveries and returns it.
+// Returns ErrWebhookNotFound if no such webhook exists.
+func (q Queries) DeleteWebhook(ctx context.Context, webhookID int) (Webhook, error) {
+	var webhook Webhook
+	err := q.db.QueryRow(ctx, `
+		DELETE FROM public.webhooks WHERE id = $1
+		RETURNING id, url, secret, event_types, active, created_at
+	`, webhookID).Scan(
+		&webhook.ID,
+		&webhook.URL,
+		&webhook.Secret,
+		&webhook.EventTypes,
+		&webhook.Active,
+		&webhook.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Webhook{}, ErrWebhookNotFound
+	}
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+// CreateTestDelivery adds an already-dispatched event of eventType and a
+// delivery of it to webhookID only, claimed for lease as if by
+// ClaimWebhookDeliveries so the caller can send it immediately.
+// Returns ErrWebhookNotFound if no such webhook exists.
+func (q Queries) CreateTestDelivery(ctx context.Context, webhookID int, eventType string, payload json.RawMessage, lease time.Duration) (ClaimedWebhookDelivery, error) {
+	var delivery ClaimedWebhookDelivery
+	err := q.db.QueryRow(ctx, `
+		WITH event AS (
+			INSERT INTO public.outbox_events (event_type, payload, dispatched_at)
+			SELECT $2, $3, CURRENT_TIMESTAMP
+			WHERE EXISTS (SELECT 1 FROM public.webhooks WHERE id = $1)
+			RETURNING id, event_type, payload, created_at
+		), delivery AS (
+			INSERT INTO public.webhook_deliveries (webhook_id, event_id, attempts, next_attempt_at)
+			SELECT $1, event.id, 1, CURRENT_TIMESTAMP + make_interval(secs => $4)
+			FROM event
+			RETURNING id, webhook_id, event_id, attempts
+		)
+		SELECT d.id, d.webhook_id, d.event_id, d.attempts, e.event_type, e.payload, e.created_at, w.url, w.secret
+		FROM delivery d
+		JOIN event e ON e.id = d.event_id
+		JOIN public.webhooks w ON w.id = d.webhook_id
+	`, webhookID, eventType, string(payload), lease.Seconds()).Scan(
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.EventID,
+		&delivery.Attempts,
+		&delivery.EventType,
+		&delivery.Payload,
+		&delivery.EventCreatedAt,
+		&delivery.URL,
+		&delivery.Secret,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ClaimedWebhookDelivery{}, ErrWebhookNotFound
+	}
+	if err != nil {
+		return ClaimedWebhookDelivery{}, err
+	}
+
+	return delivery, nil
+}
+
+// CreateTestDelivery runs Queries.CreateTestDelivery on a new connection.
+func CreateTestDelivery(ctx context.Context, webhookID int, eventType string, payload json.RawMessage, lease time.Duration) (ClaimedWebhookDelivery, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) (ClaimedWebhookDelivery, error) {
+		return q.CreateTestDelivery(ctx, webhookID, eventType, payload, lease)
+	})
+}
+
+// GetWebhookDeliveries returns up to limit of a webhook's deliveries, newest
+// first. A non-zero beforeID returns only older deliveries.
+// Returns ErrWebhookNotFound if no such webhook exists.
+func (q Queries) GetWebhookDeliveries(ctx context.Context, webhookID, limit int, beforeID int64) ([]WebhookDelivery, error) {
+	var exists bool
+	err := q.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM public.webhooks WHERE id = $1)
+	`, webhookID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrWebhookNotFound
+	}
+
+	rows, err := q.db.Query(ctx, selectWebhookDeliveries+`
+		WHERE d.webhook_id = $1 AND ($3::bigint = 0 OR d.id < $3::bigint)
+		ORDER BY d.id DESC
+		LIMIT $2
+	`, webhookID, limit, beforeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanWebhookDeliveries(rows)
+}
+
+// GetWebhookDeliveries runs Queries.GetWebhookDeliveries on a new connection.
+func GetWebhookDeliveries(ctx context.Context, webhookID, limit int, beforeID int64) ([]WebhookDelivery, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]WebhookDelivery, error) {
+		return q.GetWebhookDeliveries(ctx, webhookID, limit, beforeID)
+	})
+}
+
+// GetWebhookDelivery retrieves a single delivery, or ErrDeliveryNotFound.
+func (q Queries) GetWebhookDelivery(ctx context.Context, deliveryID int64) (WebhookDelivery, error) {
+	rows, err := q.db.Query(ctx, selectWebhookDeliveries+`
+		WHERE d.id = $1
+	`, deliveryID)
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+	deliveries, err := scanWebhookDeliveries(rows)
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+	if len(deliveries) == 0 {
+		return WebhookDelivery{}, ErrDeliveryNotFound
+	}
+	return deliveries[0], nil
+}
+
+// GetWebhookDelivery runs Queries.GetWebhookDelivery on a new connection.
+func GetWebhookDelivery(ctx context.Context, deliveryID int64) (WebhookDelivery, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) (WebhookDelivery, error) {
+		return q.GetWebhookDelivery(ctx, deliveryID)
+	})
+}
+
+// GetWebhookDeliveryAttempts retrieves the attempts of the given deliveries
+// in a single query, oldest first.
+func (q Queries) GetWebhookDeliveryAttempts(ctx context.Context, deliveryIDs []int64) ([]WebhookDeliveryAttempt, error) {
+	rows, err := q.db.Query(ctx, `
+		SELECT id, delivery_id, status_code, latency_ms, error, attempted_at
+		FROM public.webhook_delivery_attempts
+		WHERE delivery_id = ANY($1)
+		ORDER BY id
+	`, deliveryIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []WebhookDeliveryAttempt{}
+	for rows.Next() {
+		var attempt WebhookDeliveryAttempt
+		if err := rows.Scan(
+			&attempt.ID,
+			&attempt.DeliveryID,
+			&attempt.StatusCode,
+			&attempt.LatencyMS,
+			&attempt.Error,
+			&attempt.AttemptedAt,
+		); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, rows.Err()
+}
+
+// GetWebhookDeliveryAttempts runs Queries.GetWebhookDeliveryAttempts on a new connection.
+func GetWebhookDeliveryAttempts(ctx context.Context, deliveryIDs []int64) ([]WebhookDeliveryAttempt, error) {
+	return withConnection(ctx, func(ctx context.Context, q Queries) ([]WebhookDeliveryAttempt, error) {
+		return q.GetWebhookDeliveryAttempts(ctx, deliveryIDs)
+	})
+}
@@ -0,0 +1,28 @@
+package queries
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+type Webhook struct {
+	ID     int    `db:"id"`
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+	// EventTypes the webhook receives; empty means all.
+	EventTypes []string           `db:"event_types"`
+	Active     bool               `db:"active"`
+	CreatedAt  pgtype.Timestamptz `db:"created_at"`
+}
+
+type CreateWebhookParams struct {
+	URL        string   `db:"url"`
+	Secret     string   `db:"secret"`
+	EventTypes []string `db:"event_types"`
+}
+
+type WebhookDeliveryAttempt struct {
+	ID          int64              `db:"id"`
+	DeliveryID  int64              `db:"delivery_id"`
+	StatusCode  pgtype.Int4        `db:"status_code"`
+	LatencyMS   int32              `db:"latency_ms"`
+	Error       pgtype.Text        `db:"error"`
+	AttemptedAt pgtype.Timestamptz `db:"attempted_at"`
+}
@@ -0,0 +1,788 @@
+package router
+
+import (
+	"expvar"
+	"main/graphqlapi"
+	"main/handlers"
+	"main/openapi"
+	"main/service"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listCacheControl is sent with list responses. Clients may reuse them but must
+// revalidate (cheaply, via ETag / Last-Modified) before each use.
+const listCacheControl = "private, no-cache"
+
+// apiRoutes returns the API route table for the given API version. It drives
+// both router registration and the OpenAPI document served at /openapi.json,
+// so the two cannot drift apart. Handlers are shared between versions; only
+// the documented user representation differs.
+func apiRoutes(version int) []openapi.Route {
+	var userResponse, usersResponse any = handlers.UserResponse{}, handlers.GetUsersResponse{}
+	if version >= 2 {
+		userResponse, usersResponse = handlers.UserResponseV2{}, handlers.GetUsersResponseV2{}
+	}
+
+	return []openapi.Route{
+		{
+			Method:     http.MethodGet,
+			Path:       "/users",
+			Handler:    handlers.GetUsers,
+			Middleware: []gin.HandlerFunc{handlers.ConditionalGET(listCacheControl)},
+			Summary:    "List all users with their message counts",
+			Tags:       []string{"users"},
+			Responses: map[int]any{
+				http.StatusOK:         usersResponse,
+				http.StatusBadRequest: handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/users/search",
+			Handler: handlers.SearchUsers,
+			Summary: "Search users by username, nickname or email",
+			Tags:    []string{"users"},
+			Params: []openapi.Param{
+				{Name: "q", In: "query", Type: "string", Required: true, Description: "Search term"},
+				{Name: "limit", In: "query", Type: "integer", Description: "Maximum number of results (default 10, max 25)"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:         usersResponse,
+				http.StatusBadRequest: handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodPost,
+			Path:       "/users/import",
+			Handler:    handlers.ImportUsers,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_ADMIN")},
+			Summary:    "Import users from CSV or NDJSON (admins only)",
+			Tags:       []string{"users"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be an admin"},
+				{Name: "format", In: "query", Type: "string", Description: "csv or ndjson (default from Content-Type)"},
+				{Name: "mode", In: "query", Type: "string", Description: "all_or_nothing (default) or best_effort"},
+				{Name: "dry_run", In: "query", Type: "boolean", Description: "Validate and report without creating any users"},
+			},
+			Request: openapi.Raw{MediaTypes: []string{"text/csv", "application/x-ndjson"}},
+			Responses: map[int]any{
+				http.StatusOK:                    handlers.ImportUsersResponse{},
+				http.StatusBadRequest:            handlers.ErrorResponse{},
+				http.StatusUnauthorized:          handlers.ErrorResponse{},
+				http.StatusForbidden:             handlers.ErrorResponse{},
+				http.StatusRequestEntityTooLarge: handlers.ErrorResponse{},
+				http.StatusUnprocessableEntity:   handlers.ImportUsersResponse{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/users/export",
+			Handler:    handlers.ExportUsers,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_ADMIN")},
+			Summary:    "Export all users as CSV or NDJSON (admins only)",
+			Tags:       []string{"users"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be an admin"},
+				{Name: "format", In: "query", Type: "string", Description: "csv (default) or ndjson"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:           openapi.Raw{MediaTypes: []string{"text/csv", "application/x-ndjson"}},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/users/:user_id",
+			Handler: handlers.GetUser,
+			Summary: "Get a user with their message count",
+			Tags:    []string{"users"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Description: "ID of the acting user; the user themselves also gets unread_count and unread_by_conversation"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:         userResponse,
+				http.StatusBadRequest: handlers.ErrorResponse{},
+				http.StatusNotFound:   handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:  http.MethodPost,
+			Path:    "/users",
+			Handler: handlers.CreateUser,
+			Summary: "Create a user",
+			Tags:    []string{"users"},
+			Request: handlers.CreateUserRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:    userResponse,
+				http.StatusBadRequest: handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:  http.MethodPatch,
+			Path:    "/users/:user_id",
+			Handler: handlers.UpdateUser,
+			Summary: "Update a user",
+			Tags:    []string{"users"},
+			Params: []openapi.Param{
+				{Name: "If-Match", In: "header", Type: "string", Description: "ETag from a previous response; the update fails with 412 if the user has changed since"},
+			},
+			Request: handlers.UpdateUserParams{},
+			Responses: map[int]any{
+				http.StatusOK:                 userResponse,
+				http.StatusBadRequest:         handlers.ErrorResponse{},
+				http.StatusNotFound:           handlers.ErrorResponse{},
+				http.StatusPreconditionFailed: handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodPut,
+			Path:       "/users/:user_id/avatar",
+			Handler:    handlers.SetAvatar,
+			Middleware: []gin.HandlerFunc{handlers.RequireSelfOrUserType("UTYPE_ADMIN")},
+			Summary:    "Upload a user's avatar image (the user or admins)",
+			Tags:       []string{"users"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be this user or an admin"},
+			},
+			Request: openapi.Raw{MediaTypes: []string{"multipart/form-data"}},
+			Responses: map[int]any{
+				http.StatusOK:                    userResponse,
+				http.StatusBadRequest:            handlers.ErrorResponse{},
+				http.StatusUnauthorized:          handlers.ErrorResponse{},
+				http.StatusForbidden:             handlers.ErrorResponse{},
+				http.StatusNotFound:              handlers.ErrorResponse{},
+				http.StatusRequestEntityTooLarge: handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodPost,
+			Path:       "/users/:user_id/data-export",
+			Handler:    handlers.ExportUserData,
+			Middleware: []gin.HandlerFunc{handlers.RequireSelfOrUserType("UTYPE_ADMIN")},
+			Summary:    "Download a zip archive of a user's profile, messages and audit entries (the user or admins)",
+			Tags:       []string{"privacy"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be this user or an admin"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:           openapi.Raw{MediaTypes: []string{"application/zip"}},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+				http.StatusNotFound:     handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodPost,
+			Path:       "/users/:user_id/erase",
+			Handler:    handlers.EraseUser,
+			Middleware: []gin.HandlerFunc{handlers.RequireSelfOrUserType("UTYPE_ADMIN")},
+			Summary:    "Request erasure of a user, or confirm it with the returned token (the user or admins)",
+			Tags:       []string{"privacy"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be this user or an admin"},
+			},
+			Request: handlers.EraseUserRequest{},
+			Responses: map[int]any{
+				http.StatusOK:           handlers.EraseUserResponse{},
+				http.StatusAccepted:     handlers.ErasureRequestResponse{},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+				http.StatusNotFound:     handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/messages",
+			Handler:    handlers.GetMessages,
+			Middleware: []gin.HandlerFunc{handlers.ConditionalGET(listCacheControl)},
+			Summary:    "List all messages",
+			Tags:       []string{"messages"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Description: "ID of the acting user, for reacted_by_me"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:         handlers.GetMessagesResponse{},
+				http.StatusBadRequest: handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:  http.MethodPost,
+			Path:    "/messages",
+			Handler: handlers.CreateMessage,
+			Summary: "Create a message, which moderation may mask, hold for review (202) or reject (422)",
+			Tags:    []string{"messages"},
+			Request: handlers.CreateMessageRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:             handlers.MessageResponse{},
+				http.StatusAccepted:            handlers.MessageResponse{},
+				http.StatusBadRequest:          handlers.ErrorResponse{},
+				http.StatusUnprocessableEntity: handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/users/:user_id/messages",
+			Handler:    handlers.GetMessagesByUser,
+			Middleware: []gin.HandlerFunc{handlers.ConditionalGET(listCacheControl)},
+			Summary:    "List a user's messages, newest first",
+			Tags:       []string{"messages"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Description: "ID of the acting user, for reacted_by_me"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:         handlers.GetMessagesResponse{},
+				http.StatusBadRequest: handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/conversations",
+			Handler:    handlers.GetConversations,
+			Middleware: []gin.HandlerFunc{handlers.ConditionalGET(listCacheControl)},
+			Summary:    "List all conversations, oldest first",
+			Tags:       []string{"conversations"},
+			Responses: map[int]any{
+				http.StatusOK:         handlers.GetConversationsResponse{},
+				http.StatusBadRequest: handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodPost,
+			Path:       "/conversations",
+			Handler:    handlers.CreateConversation,
+			Middleware: []gin.HandlerFunc{handlers.RequireUser()},
+			Summary:    "Start a conversation",
+			Tags:       []string{"conversations"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user"},
+			},
+			Request: handlers.CreateConversationRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:      handlers.ConversationResponse{},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodPost,
+			Path:       "/conversations/:conversation_id/read",
+			Handler:    handlers.MarkRead,
+			Middleware: []gin.HandlerFunc{handlers.RequireUser()},
+			Summary:    "Mark messages in a conversation up to one read by the acting user",
+			Tags:       []string{"conversations"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who has read the messages"},
+			},
+			Request: handlers.MarkReadRequest{},
+			Responses: map[int]any{
+				http.StatusOK:           handlers.ReadMarkerResponse{},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusNotFound:     handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/conversations/:conversation_id/messages",
+			Handler:    handlers.GetConversationMessages,
+			Middleware: []gin.HandlerFunc{handlers.ConditionalGET(listCacheControl)},
+			Summary:    "List the messages in a conversation, newest first",
+			Tags:       []string{"conversations"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Description: "ID of the acting user, for reacted_by_me"},
+				{Name: "before_id", In: "query", Type: "integer", Description: "Only messages older than this ID, for paging"},
+				{Name: "limit", In: "query", Type: "integer", Description: "Maximum number of messages (default 50, max 200)"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:         handlers.GetMessagesResponse{},
+				http.StatusBadRequest: handlers.ErrorResponse{},
+				http.StatusNotFound:   handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/users/:user_id/mentions",
+			Handler:    handlers.GetMentions,
+			Middleware: []gin.HandlerFunc{handlers.ConditionalGET(listCacheControl)},
+			Summary:    "List the messages mentioning a user, newest first",
+			Tags:       []string{"messages"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Description: "ID of the acting user, for reacted_by_me"},
+				{Name: "before_id", In: "query", Type: "integer", Description: "Only messages older than this ID, for paging"},
+				{Name: "limit", In: "query", Type: "integer", Description: "Maximum number of messages (default 50, max 200)"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:         handlers.GetMessagesResponse{},
+				http.StatusBadRequest: handlers.ErrorResponse{},
+				http.StatusNotFound:   handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/tags/:tag/messages",
+			Handler:    handlers.GetTagMessages,
+			Middleware: []gin.HandlerFunc{handlers.ConditionalGET(listCacheControl)},
+			Summary:    "List the messages with a #tag, newest first",
+			Tags:       []string{"messages"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Description: "ID of the acting user, for reacted_by_me"},
+				{Name: "before_id", In: "query", Type: "integer", Description: "Only messages older than this ID, for paging"},
+				{Name: "limit", In: "query", Type: "integer", Description: "Maximum number of messages (default 50, max 200)"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:         handlers.GetMessagesResponse{},
+				http.StatusBadRequest: handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/messages/:message_id/replies",
+			Handler:    handlers.GetReplies,
+			Middleware: []gin.HandlerFunc{handlers.ConditionalGET(listCacheControl)},
+			Summary:    "List the replies to a message, oldest first",
+			Tags:       []string{"messages"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Description: "ID of the acting user, for reacted_by_me"},
+				{Name: "after_id", In: "query", Type: "integer", Description: "Only replies newer than this ID, for paging"},
+				{Name: "limit", In: "query", Type: "integer", Description: "Maximum number of replies (default 50, max 200)"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:         handlers.GetMessagesResponse{},
+				http.StatusBadRequest: handlers.ErrorResponse{},
+				http.StatusNotFound:   handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodPost,
+			Path:       "/messages/:message_id/reactions",
+			Handler:    handlers.AddReaction,
+			Middleware: []gin.HandlerFunc{handlers.RequireUser()},
+			Summary:    "React to a message with an emoji as the acting user",
+			Tags:       []string{"messages"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who is reacting"},
+			},
+			Request: handlers.AddReactionRequest{},
+			Responses: map[int]any{
+				http.StatusOK:           handlers.MessageReactionsResponse{},
+				http.StatusCreated:      handlers.MessageReactionsResponse{},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusNotFound:     handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodPost,
+			Path:       "/messages/:message_id/attachments",
+			Handler:    handlers.AddAttachment,
+			Middleware: []gin.HandlerFunc{handlers.RequireUser()},
+			Summary:    "Attach a file to a message written by the acting user",
+			Tags:       []string{"messages"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be the message's author"},
+			},
+			Request: openapi.Raw{MediaTypes: []string{"multipart/form-data"}},
+			Responses: map[int]any{
+				http.StatusCreated:               handlers.AttachmentResponse{},
+				http.StatusBadRequest:            handlers.ErrorResponse{},
+				http.StatusUnauthorized:          handlers.ErrorResponse{},
+				http.StatusForbidden:             handlers.ErrorResponse{},
+				http.StatusNotFound:              handlers.ErrorResponse{},
+				http.StatusRequestEntityTooLarge: handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodDelete,
+			Path:       "/messages/:message_id/reactions/:emoji",
+			Handler:    handlers.RemoveReaction,
+			Middleware: []gin.HandlerFunc{handlers.RequireUser()},
+			Summary:    "Remove the acting user's reaction to a message",
+			Tags:       []string{"messages"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, whose reaction is removed"},
+			},
+			Responses: map[int]any{
+				http.StatusNoContent:    nil,
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusNotFound:     handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/messages/export",
+			Handler:    handlers.ExportMessages,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_ADMIN")},
+			Summary:    "Export messages, including held and archived ones, in ID order as CSV, NDJSON or zipped JSON (admins only)",
+			Tags:       []string{"messages"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be an admin"},
+				{Name: "format", In: "query", Type: "string", Description: "csv (default), ndjson, or zip for a zip archive of a JSON array"},
+				{Name: "since", In: "query", Type: "string", Description: "Only messages created at or after this RFC 3339 time"},
+				{Name: "until", In: "query", Type: "string", Description: "Only messages created before this RFC 3339 time"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:           openapi.Raw{MediaTypes: []string{"text/csv", "application/x-ndjson", "application/zip"}},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/users/:user_id/messages/export",
+			Handler:    handlers.ExportUserMessages,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_ADMIN")},
+			Summary:    "Export a user's messages in ID order as CSV, NDJSON or zipped JSON (admins only)",
+			Tags:       []string{"messages"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be an admin"},
+				{Name: "format", In: "query", Type: "string", Description: "csv (default), ndjson, or zip for a zip archive of a JSON array"},
+				{Name: "since", In: "query", Type: "string", Description: "Only messages created at or after this RFC 3339 time"},
+				{Name: "until", In: "query", Type: "string", Description: "Only messages created before this RFC 3339 time"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:           openapi.Raw{MediaTypes: []string{"text/csv", "application/x-ndjson", "application/zip"}},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+				http.StatusNotFound:     handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/moderation/queue",
+			Handler:    handlers.GetModerationQueue,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_MODERATOR")},
+			Summary:    "List the messages held for review, oldest first (moderators only)",
+			Tags:       []string{"moderation"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be a moderator"},
+				{Name: "after_id", In: "query", Type: "integer", Description: "Only messages newer than this ID, for paging"},
+				{Name: "limit", In: "query", Type: "integer", Description: "Maximum number of messages (default 50, max 200)"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:           handlers.ModerationQueueResponse{},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodPost,
+			Path:       "/moderation/queue/:message_id/approve",
+			Handler:    handlers.ApproveMessage,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_MODERATOR")},
+			Summary:    "Approve a held message, posting it (moderators only)",
+			Tags:       []string{"moderation"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be a moderator"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:           handlers.MessageResponse{},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+				http.StatusNotFound:     handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodPost,
+			Path:       "/moderation/queue/:message_id/reject",
+			Handler:    handlers.RejectMessage,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_MODERATOR")},
+			Summary:    "Reject a held message, deleting it (moderators only)",
+			Tags:       []string{"moderation"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be a moderator"},
+			},
+			Responses: map[int]any{
+				http.StatusNoContent:    nil,
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+				http.StatusNotFound:     handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/audit",
+			Handler:    handlers.GetAuditEvents,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_ADMIN")},
+			Summary:    "List audit events, newest first (admins only)",
+			Tags:       []string{"audit"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be an admin"},
+				{Name: "actor_id", In: "query", Type: "integer", Description: "Only events by this user"},
+				{Name: "action", In: "query", Type: "string", Description: "Only this action, e.g. user.update"},
+				{Name: "target_type", In: "query", Type: "string", Description: "Only events on this kind of target, e.g. user"},
+				{Name: "target_id", In: "query", Type: "integer", Description: "Only events on this target"},
+				{Name: "since", In: "query", Type: "string", Description: "Only events at or after this RFC 3339 time"},
+				{Name: "until", In: "query", Type: "string", Description: "Only events before this RFC 3339 time"},
+				{Name: "before_id", In: "query", Type: "integer", Description: "Only events older than this ID, for paging"},
+				{Name: "limit", In: "query", Type: "integer", Description: "Maximum number of results (default 50, max 200)"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:           handlers.GetAuditEventsResponse{},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodPost,
+			Path:       "/webhooks",
+			Handler:    handlers.CreateWebhook,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_ADMIN")},
+			Summary:    "Register a webhook (admins only)",
+			Tags:       []string{"webhooks"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be an admin"},
+			},
+			Request: handlers.CreateWebhookRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:      handlers.WebhookResponse{},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/webhooks",
+			Handler:    handlers.GetWebhooks,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_ADMIN")},
+			Summary:    "List webhooks (admins only)",
+			Tags:       []string{"webhooks"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be an admin"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:           handlers.GetWebhooksResponse{},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodDelete,
+			Path:       "/webhooks/:webhook_id",
+			Handler:    handlers.DeleteWebhook,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_ADMIN")},
+			Summary:    "Delete a webhook and its deliveries (admins only)",
+			Tags:       []string{"webhooks"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be an admin"},
+			},
+			Responses: map[int]any{
+				http.StatusNoContent:    nil,
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+				http.StatusNotFound:     handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodPost,
+			Path:       "/webhooks/:webhook_id/test",
+			Handler:    handlers.TestWebhook,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_ADMIN")},
+			Summary:    "Send a sample delivery to a webhook now (admins only)",
+			Tags:       []string{"webhooks"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be an admin"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:           handlers.WebhookDeliveryResponse{},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+				http.StatusNotFound:     handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/webhooks/:webhook_id/deliveries",
+			Handler:    handlers.GetWebhookDeliveries,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_ADMIN")},
+			Summary:    "List a webhook's deliveries with their attempt history (admins only)",
+			Tags:       []string{"webhooks"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be an admin"},
+				{Name: "before_id", In: "query", Type: "integer", Description: "Only deliveries older than this ID, for paging"},
+				{Name: "limit", In: "query", Type: "integer", Description: "Maximum number of results (default 50, max 200)"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:           handlers.GetWebhookDeliveriesResponse{},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+				http.StatusNotFound:     handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/webhooks/dead-letters",
+			Handler:    handlers.GetDeadLetters,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_ADMIN")},
+			Summary:    "List webhook deliveries that ran out of attempts (admins only)",
+			Tags:       []string{"webhooks"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be an admin"},
+				{Name: "before_id", In: "query", Type: "integer", Description: "Only deliveries older than this ID, for paging"},
+				{Name: "limit", In: "query", Type: "integer", Description: "Maximum number of results (default 50, max 200)"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:           handlers.GetWebhookDeliveriesResponse{},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodPost,
+			Path:       "/webhooks/dead-letters/:delivery_id/retry",
+			Handler:    handlers.RetryDeadLetter,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_ADMIN")},
+			Summary:    "Queue a dead webhook delivery to be sent again (admins only)",
+			Tags:       []string{"webhooks"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be an admin"},
+			},
+			Responses: map[int]any{
+				http.StatusAccepted:     handlers.WebhookDeliveryResponse{},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+				http.StatusNotFound:     handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/retention-policies",
+			Handler:    handlers.GetRetentionPolicies,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_ADMIN")},
+			Summary:    "List message retention policies (admins only)",
+			Tags:       []string{"retention"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be an admin"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:           handlers.GetRetentionPoliciesResponse{},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodPut,
+			Path:       "/retention-policies/:scope",
+			Handler:    handlers.SetRetentionPolicy,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_ADMIN")},
+			Summary:    "Set the retention policy for default, a user type or a conversation (admins only)",
+			Tags:       []string{"retention"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be an admin"},
+			},
+			Request: handlers.SetRetentionPolicyRequest{},
+			Responses: map[int]any{
+				http.StatusOK:           handlers.RetentionPolicyResponse{},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+				http.StatusNotFound:     handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodDelete,
+			Path:       "/retention-policies/:scope",
+			Handler:    handlers.DeleteRetentionPolicy,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_ADMIN")},
+			Summary:    "Delete the retention policy for default, a user type or a conversation (admins only)",
+			Tags:       []string{"retention"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be an admin"},
+			},
+			Responses: map[int]any{
+				http.StatusNoContent:    nil,
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+				http.StatusNotFound:     handlers.ErrorResponse{},
+			},
+		},
+		{
+			Method:     http.MethodPost,
+			Path:       "/retention-policies/sweep",
+			Handler:    handlers.SweepMessages,
+			Middleware: []gin.HandlerFunc{handlers.RequireUserType("UTYPE_ADMIN")},
+			Summary:    "Remove expired messages and apply retention policies now (admins only)",
+			Tags:       []string{"retention"},
+			Params: []openapi.Param{
+				{Name: handlers.UserIDHeader, In: "header", Type: "integer", Required: true, Description: "ID of the acting user, who must be an admin"},
+			},
+			Responses: map[int]any{
+				http.StatusOK:           handlers.SweepMessagesResponse{},
+				http.StatusBadRequest:   handlers.ErrorResponse{},
+				http.StatusUnauthorized: handlers.ErrorResponse{},
+				http.StatusForbidden:    handlers.ErrorResponse{},
+			},
+		},
+	}
+}
+
+var apiInfo = openapi.Info{
+	Title:   "Golang Challenge API",
+	Version: "1.0.0",
+}
+
+// New builds the Gin engine with the API routes and the documentation endpoints.
+func New() *gin.Engine {
+	r := gin.Default()
+	r.Use(handlers.RequestID(), handlers.Actor())
+
+	var documented []openapi.Route
+	register := func(group *gin.RouterGroup, prefix string, routes []openapi.Route, deprecated bool) {
+		for _, route := range routes {
+			group.Handle(route.Method, route.Path, append(route.Middleware, route.Handler)...)
+
+			route.Path = strings.TrimSuffix(group.BasePath(), "/") + route.Path
+			route.OperationID = prefix + openapi.HandlerName(route.Handler)
+			route.Deprecated = deprecated
+			documented = append(documented, route)
+		}
+	}
+
+	// endpoints
+	register(r.Group("/v1", handlers.APIVersion(1)), "v1", apiRoutes(1), false)
+	register(r.Group("/v2", handlers.APIVersion(2)), "v2", apiRoutes(2), false)
+
+	// unversioned aliases of /v1, kept for existing clients
+	register(r.Group("/", handlers.APIVersion(1), handlers.Deprecated("/v1")), "legacy", apiRoutes(1), true)
+
+	r.POST("/graphql", graphqlapi.Handler())
+
+	// avatar URLs, which redirect to signed blob URLs
+	r.GET("/avatars/*key", handlers.GetAvatar)
+
+	// signed blob downloads, when blobs are stored locally rather than in S3
+	if blobs, ok := service.BlobStore().(http.Handler); ok {
+		r.GET("/blobs/*key", gin.WrapH(http.StripPrefix("/blobs", blobs)))
+	}
+
+	// documentation
+	r.GET("/openapi.json", openapi.Handler(openapi.Generate(apiInfo, documented)))
+	r.GET("/docs", openapi.SwaggerUI("/openapi.json"))
+
+	// runtime metrics, including user cache hits and misses
+	r.GET("/debug/vars", gin.WrapH(expvar.Handler()))
+
+	return r
+}
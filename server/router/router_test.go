@@ -0,0 +1,120 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"main/openapi"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// documentedOperation is what TestOpenAPIMatchesRouter compares between a
+// served route and its entry in the spec.
+type documentedOperation struct {
+	OperationID string
+	Deprecated  bool
+	PathParams  []string
+}
+
+// TestOpenAPIMatchesRouter checks the served spec against the routes the
+// engine actually registered, under /v1, /v2 and the unversioned aliases. It
+// fails when a route is served but not documented, documented but not
+// served, or documented with another handler, path parameters or deprecation.
+func TestOpenAPIMatchesRouter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := New()
+
+	req, _ := http.NewRequest(http.MethodGet, "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var doc openapi.Document
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+
+	documented := map[string]documentedOperation{}
+	for path, ops := range doc.Paths {
+		for method, op := range ops {
+			operation := documentedOperation{OperationID: op.OperationID, Deprecated: op.Deprecated}
+			for _, param := range op.Parameters {
+				if param.In == "path" {
+					operation.PathParams = append(operation.PathParams, param.Name)
+				}
+			}
+			documented[strings.ToUpper(method)+" "+path] = operation
+		}
+	}
+
+	served := map[string]documentedOperation{}
+	mounts := map[string][]string{}
+	for _, info := range router.Routes() {
+		switch info.Path {
+		case "/openapi.json", "/docs", "/graphql", "/debug/vars", "/avatars/*key", "/blobs/*key":
+			continue
+		}
+
+		prefix, path := "legacy", info.Path
+		for _, version := range []string{"v1", "v2"} {
+			if rest, ok := strings.CutPrefix(info.Path, "/"+version+"/"); ok {
+				prefix, path = version, "/"+rest
+			}
+		}
+		mounts[prefix] = append(mounts[prefix], info.Method+" "+path)
+
+		handler := info.Handler[strings.LastIndex(info.Handler, ".")+1:]
+		operation := documentedOperation{
+			OperationID: prefix + strings.TrimSuffix(handler, "-fm"),
+			Deprecated:  prefix == "legacy",
+		}
+		for _, segment := range strings.Split(info.Path, "/") {
+			if name, ok := strings.CutPrefix(segment, ":"); ok {
+				operation.PathParams = append(operation.PathParams, name)
+			}
+		}
+		served[info.Method+" "+openapi.SpecPath(info.Path)] = operation
+	}
+
+	assert.Equal(t, served, documented)
+	assert.NotEmpty(t, mounts["v1"])
+	assert.ElementsMatch(t, mounts["v1"], mounts["v2"], "/v2 serves the routes of /v1")
+	assert.ElementsMatch(t, mounts["v1"], mounts["legacy"], "the unversioned aliases serve the routes of /v1")
+}
+
+func TestSwaggerUI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := New()
+
+	req, _ := http.NewRequest(http.MethodGet, "/docs", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "/openapi.json")
+}
+
+func TestLegacyRoutesAreDeprecated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := New()
+
+	req, _ := http.NewRequest(http.MethodGet, "/users/search", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "true", w.Header().Get("Deprecation"))
+	assert.Equal(t, `</v1/users/search>; rel="successor-version"`, w.Header().Get("Link"))
+
+	for _, path := range []string{"/v1/users/search", "/v2/users/search"} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		assert.Empty(t, w.Header().Get("Deprecation"), path)
+	}
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"main/queries"
+	"main/storage"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+const (
+	// MaxAttachmentBytes limits the size of a single attachment.
+	MaxAttachmentBytes = 10 << 20
+	// MaxAttachmentsPerMessage limits how many files can be attached to a message.
+	MaxAttachmentsPerMessage = 10
+	// AttachmentURLExpiry is how long the signed download URLs of attachments are valid.
+	AttachmentURLExpiry = 15 * time.Minute
+
+	maxFilenameLength = 255
+)
+
+// AttachmentTypes lists the content types that can be attached to messages.
+// The type is detected from the file's contents, not taken from the client.
+var AttachmentTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"application/pdf",
+	"text/plain",
+}
+
+// ErrNotMessageAuthor is returned when someone other than a message's
+// author tries to change it.
+var ErrNotMessageAuthor = errors.New("not the message's author")
+
+var (
+	blobMu    sync.RWMutex
+	blobStore storage.Store
+)
+
+// ConfigureBlobStore sets where attachments are stored. Until it is called,
+// uploads fail and attachments are listed without download URLs.
+func ConfigureBlobStore(store storage.Store) {
+	blobMu.Lock()
+	defer blobMu.Unlock()
+	blobStore = store
+}
+
+// BlobStore returns the store set by ConfigureBlobStore, or nil.
+func BlobStore() storage.Store {
+	blobMu.RLock()
+	defer blobMu.RUnlock()
+	return blobStore
+}
+
+// Attachment is a file attached to a message with a signed URL it can be
+// downloaded from until URLExpiresAt.
+type Attachment struct {
+	queries.MessageAttachment
+	URL          string
+	URLExpiresAt time.Time
+}
+
+// AddAttachment stores size bytes read from r as a file named filename and
+// attaches it to a message written by the actor attached to ctx. Returns
+// queries.ErrMessageNotFound if there is no such message and
+// ErrNotMessageAuthor if the actor did not write it.
+func AddAttachment(ctx context.Context, messageID int, filename string, r io.Reader, size int64) (Attachment, error) {
+	actor := ActorFrom(ctx)
+	if actor.UserID == 0 {
+		return Attachment{}, invalid("Attachments require an acting user")
+	}
+	filename, err := cleanFilename(filename)
+	if err != nil {
+		return Attachment{}, err
+	}
+	if size <= 0 {
+		return Attachment{}, invalid("File is empty")
+	}
+	if size > MaxAttachmentBytes {
+		return Attachment{}, invalid("Attachments are limited to " + strconv.Itoa(MaxAttachmentBytes>>20) + "MB")
+	}
+	store := BlobStore()
+	if store == nil {
+		return Attachment{}, errors.New("attachment storage is not configured")
+	}
+
+	// Check before uploading, and again once the message is locked.
+	message, err := queries.GetMessage(ctx, messageID)
+	if err != nil {
+		return Attachment{}, err
+	}
+	existing, err := queries.GetAttachments(ctx, []int{messageID})
+	if err != nil {
+		return Attachment{}, err
+	}
+	if err := checkAttachable(message, len(existing), actor.UserID); err != nil {
+		return Attachment{}, err
+	}
+
+	mtype, body, err := detectAttachmentType(r)
+	if err != nil {
+		return Attachment{}, err
+	}
+
+	suffix := make([]byte, 16)
+	if _, err := rand.Read(suffix); err != nil {
+		return Attachment{}, err
+	}
+	key := "attachments/" + strconv.Itoa(messageID) + "/" + hex.EncodeToString(suffix) + mtype.Extension()
+	if err := store.Put(ctx, key, body, size, mtype.String()); err != nil {
+		return Attachment{}, err
+	}
+
+	var attachment queries.MessageAttachment
+	err = queries.WithTx(ctx, func(tx queries.Queries) error {
+		count, err := tx.CountAttachments(ctx, messageID)
+		if err != nil {
+			return err
+		}
+		message, err := tx.GetMessage(ctx, messageID)
+		if err != nil {
+			return err
+		}
+		if err := checkAttachable(message, count, actor.UserID); err != nil {
+			return err
+		}
+		attachment, err = tx.CreateAttachment(ctx, queries.CreateAttachmentParams{
+			MessageID:   messageID,
+			StorageKey:  key,
+			Filename:    filename,
+			ContentType: mtype.String(),
+			SizeBytes:   size,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, AuditAttachmentCreate, "message", messageID, nil, attachment)
+	})
+	if err != nil {
+		if err := store.Delete(context.WithoutCancel(ctx), key); err != nil {
+			log.Printf("attachments: failed to delete unused blob %s: %v", key, err)
+		}
+		return Attachment{}, err
+	}
+	return signAttachment(ctx, store, attachment), nil
+}
+
+// checkAttachable checks that userID wrote message and that it has room for
+// another attachment besides the count it already has.
+func checkAttachable(message queries.GetMessagesQueryRow, count, userID int) error {
+	if message.UserID != userID {
+		return ErrNotMessageAuthor
+	}
+	if count >= MaxAttachmentsPerMessage {
+		return invalid("A message can have at most " + strconv.Itoa(MaxAttachmentsPerMessage) + " attachments")
+	}
+	return nil
+}
+
+// detectAttachmentType detects the content type of the file read from r and
+// checks that it is one of AttachmentTypes. Only the detected type itself is
+// checked, not the types it is a special case of, so that for instance HTML
+// is not accepted as text/plain. body reads the whole file, including the
+// part read for detection.
+func detectAttachmentType(r io.Reader) (mtype *mimetype.MIME, body io.Reader, err error) {
+	head := make([]byte, 3072)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, nil, err
+	}
+	head = head[:n]
+
+	mtype = mimetype.Detect(head)
+	if !mimetype.EqualsAny(mtype.String(), AttachmentTypes...) {
+		return nil, nil, invalid("Unsupported file type " + strings.SplitN(mtype.String(), ";", 2)[0])
+	}
+	return mtype, io.MultiReader(bytes.NewReader(head), r), nil
+}
+
+// cleanFilename reduces a client-supplied filename to its base name and
+// checks that it is printable and not too long.
+func cleanFilename(filename string) (string, error) {
+	filename = strings.TrimSpace(filepath.Base(strings.ReplaceAll(filename, `\`, "/")))
+	if filename == "" || filename == "." || filename == "/" {
+		return "", invalid("Filename is required")
+	}
+	if len(filename) > maxFilenameLength {
+		return "", invalid("Filename is limited to " + strconv.Itoa(maxFilenameLength) + " bytes")
+	}
+	if strings.ContainsFunc(filename, unicode.IsControl) {
+		return "", invalid("Filename must not contain control characters")
+	}
+	return filename, nil
+}
+
+// MessageAttachments returns the attachments of the given messages with
+// fresh signed URLs, keyed by message ID, read in one query.
+func MessageAttachments(ctx context.Context, messageIDs []int) (map[int][]Attachment, error) {
+	attachments := map[int][]Attachment{}
+	if len(messageIDs) == 0 {
+		return attachments, nil
+	}
+
+	rows, err := queries.GetAttachments(ctx, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+	store := BlobStore()
+	for _, row := range rows {
+		messageID := int(row.MessageID.Int32)
+		attachments[messageID] = append(attachments[messageID], signAttachment(ctx, store, row))
+	}
+	return attachments, nil
+}
+
+// signAttachment adds a signed URL to attachment. The URL is left empty if
+// there is no store or signing fails, so one bad blob does not break a list.
+func signAttachment(ctx context.Context, store storage.Store, attachment queries.MessageAttachment) Attachment {
+	signed := Attachment{MessageAttachment: attachment}
+	if store == nil {
+		return signed
+	}
+	url, err := store.SignedURL(ctx, attachment.StorageKey, AttachmentURLExpiry)
+	if err != nil {
+		log.Printf("attachments: failed to sign URL for %s: %v", attachment.StorageKey, err)
+		return signed
+	}
+	signed.URL = url
+	signed.URLExpiresAt = time.Now().Add(AttachmentURLExpiry)
+	return signed
+}
+
+// PurgeDetachedAttachments deletes the blobs and rows of up to limit
+// attachments whose messages have been deleted, and returns how many it
+// deleted. Attachments whose blob cannot be deleted are kept for a later run.
+func PurgeDetachedAttachments(ctx context.Context, limit int) (int, error) {
+	store := BlobStore()
+	if store == nil {
+		return 0, nil
+	}
+
+	purged := 0
+	err := queries.WithTx(ctx, func(tx queries.Queries) error {
+		detached, err := tx.GetDetachedAttachments(ctx, limit)
+		if err != nil {
+			return err
+		}
+		for _, attachment := range detached {
+			if err := store.Delete(ctx, attachment.StorageKey); err != nil {
+				log.Printf("attachments: failed to delete blob %s: %v", attachment.StorageKey, err)
+				continue
+			}
+			if err := tx.DeleteAttachment(ctx, attachment.ID); err != nil {
+				return err
+			}
+			purged++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
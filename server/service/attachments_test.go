@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"main/queries"
+	"main/storage"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+var pngHeader = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00"
+
+func TestCleanFilename(t *testing.T) {
+	for input, want := range map[string]string{
+		"photo.png":                "photo.png",
+		"  notes.txt ":             "notes.txt",
+		"../../etc/passwd":         "passwd",
+		`C:\Users\me\report.pdf`:   "report.pdf",
+		"dir/sub/ünïcödé name.gif": "ünïcödé name.gif",
+	} {
+		got, err := cleanFilename(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	for input, message := range map[string]string{
+		"":                       "Filename is required",
+		".":                      "Filename is required",
+		strings.Repeat("a", 256): "Filename is limited to 255 bytes",
+		"bad\x00name.png":        "Filename must not contain control characters",
+	} {
+		_, err := cleanFilename(input)
+		assert.EqualError(t, err, message, input)
+	}
+}
+
+func TestDetectAttachmentType(t *testing.T) {
+	mtype, body, err := detectAttachmentType(strings.NewReader(pngHeader + "rest"))
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", mtype.String())
+	assert.Equal(t, ".png", mtype.Extension())
+	read, _ := io.ReadAll(body)
+	assert.Equal(t, pngHeader+"rest", string(read))
+
+	mtype, _, err = detectAttachmentType(strings.NewReader("just some notes\n"))
+	assert.NoError(t, err)
+	assert.True(t, mtype.Is("text/plain"))
+
+	_, _, err = detectAttachmentType(strings.NewReader("<html><script>alert(1)</script></html>"))
+	assert.EqualError(t, err, "Unsupported file type text/html")
+
+	_, _, err = detectAttachmentType(strings.NewReader("MZ\x90\x00\x03\x00\x00\x00"))
+	assert.EqualError(t, err, "Unsupported file type application/vnd.microsoft.portable-executable")
+}
+
+func TestCheckAttachable(t *testing.T) {
+	message := queries.GetMessagesQueryRow{ID: 1, UserID: 2}
+	assert.NoError(t, checkAttachable(message, 0, 2))
+	assert.ErrorIs(t, checkAttachable(message, 0, 3), ErrNotMessageAuthor)
+	assert.EqualError(t, checkAttachable(message, MaxAttachmentsPerMessage, 2), "A message can have at most 10 attachments")
+}
+
+func TestAddAttachmentValidation(t *testing.T) {
+	_, err := AddAttachment(context.Background(), 1, "a.png", strings.NewReader(pngHeader), int64(len(pngHeader)))
+	assert.EqualError(t, err, "Attachments require an acting user")
+
+	ctx := WithActor(context.Background(), Actor{UserID: 1})
+	_, err = AddAttachment(ctx, 1, "", strings.NewReader(pngHeader), int64(len(pngHeader)))
+	assert.EqualError(t, err, "Filename is required")
+
+	_, err = AddAttachment(ctx, 1, "a.png", strings.NewReader(""), 0)
+	assert.EqualError(t, err, "File is empty")
+
+	_, err = AddAttachment(ctx, 1, "a.png", strings.NewReader(pngHeader), MaxAttachmentBytes+1)
+	assert.EqualError(t, err, "Attachments are limited to 10MB")
+}
+
+func TestSignAttachment(t *testing.T) {
+	attachment := queries.MessageAttachment{
+		ID:         4,
+		MessageID:  pgtype.Int4{Int32: 1, Valid: true},
+		StorageKey: "attachments/1/abc.png",
+	}
+	assert.Empty(t, signAttachment(context.Background(), nil, attachment).URL)
+
+	store, err := storage.NewLocal(t.TempDir(), "http://localhost:8080/blobs", []byte("secret"))
+	assert.NoError(t, err)
+	signed := signAttachment(context.Background(), store, attachment)
+	assert.Equal(t, attachment, signed.MessageAttachment)
+	assert.True(t, strings.HasPrefix(signed.URL, "http://localhost:8080/blobs/attachments/1/abc.png?"), signed.URL)
+	assert.WithinDuration(t, time.Now().Add(AttachmentURLExpiry), signed.URLExpiresAt, time.Minute)
+}
+
+func TestMessageAttachmentsEmpty(t *testing.T) {
+	attachments, err := MessageAttachments(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, attachments)
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"main/queries"
+)
+
+// Audited actions, grouped by the type of their target.
+const (
+	AuditUserCreate       = "user.create"
+	AuditUserUpdate       = "user.update"
+	AuditUserDataExport   = "user.data_export"
+	AuditUserEraseRequest = "user.erase_request"
+	AuditUserErase        = "user.erase"
+
+	AuditMessageCreate    = "message.create"
+	AuditMessageApprove   = "message.approve"
+	AuditMessageReject    = "message.reject"
+	AuditReactionAdd      = "reaction.add"
+	AuditReactionRemove   = "reaction.remove"
+	AuditAttachmentCreate = "attachment.create"
+	// Retention sweeps record one event per batch, listing the message IDs.
+	AuditMessageExpire  = "message.expire"
+	AuditMessagePurge   = "message.purge"
+	AuditMessageArchive = "message.archive"
+
+	AuditConversationCreate = "conversation.create"
+
+	AuditWebhookCreate        = "webhook.create"
+	AuditWebhookDelete        = "webhook.delete"
+	AuditWebhookDeliveryRetry = "webhook_delivery.retry"
+
+	AuditRetentionPolicySet    = "retention_policy.set"
+	AuditRetentionPolicyDelete = "retention_policy.delete"
+)
+
+const (
+	DefaultAuditLimit = 50
+	MaxAuditLimit     = 200
+)
+
+// Actor describes who is making a request, for the audit log.
+type Actor struct {
+	// UserID is the acting user, or zero if unknown.
+	UserID    int
+	IP        string
+	RequestID string
+}
+
+type actorKey struct{}
+
+// WithActor returns a context carrying actor. Transports attach it before
+// calling the service's write functions.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor attached to ctx, if any.
+func ActorFrom(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
+
+// auditedUser is the audited representation of a user.
+type auditedUser struct {
+	Username string  `json:"username"`
+	Email    string  `json:"email"`
+	UserType string  `json:"user_type"`
+	Nickname *string `json:"nickname"`
+	// Avatar is the blob key of the avatar.
+	Avatar *string `json:"avatar,omitempty"`
+}
+
+func newAuditedUser(row queries.GetUsersQueryRow) auditedUser {
+	user := auditedUser{Username: row.Username, Email: row.Email, UserType: row.UserType}
+	if row.Nickname.Valid {
+		user.Nickname = &row.Nickname.String
+	}
+	if row.AvatarKey.Valid {
+		user.Avatar = &row.AvatarKey.String
+	}
+	return user
+}
+
+// auditedMessage is the audited representation of a message.
+type auditedMessage struct {
+	UserID   int    `json:"user_id"`
+	Content  string `json:"content"`
+	ParentID *int   `json:"parent_id,omitempty"`
+	// ConversationID is omitted for messages in the default conversation.
+	ConversationID int `json:"conversation_id,omitempty"`
+	// Held is set while the message awaits review by a moderator.
+	Held bool `json:"held,omitempty"`
+}
+
+func newAuditedMessage(row queries.GetMessagesQueryRow) auditedMessage {
+	message := auditedMessage{UserID: row.UserID, Content: row.Content, Held: row.Held}
+	if row.ConversationID != queries.DefaultConversationID {
+		message.ConversationID = row.ConversationID
+	}
+	if row.ParentID.Valid {
+		parentID := int(row.ParentID.Int32)
+		message.ParentID = &parentID
+	}
+	return message
+}
+
+// auditedConversation is the audited representation of a conversation.
+type auditedConversation struct {
+	Title string `json:"title"`
+}
+
+// auditedReaction is the audited representation of a reaction. The reacting
+// user is the event's actor.
+type auditedReaction struct {
+	Emoji string `json:"emoji"`
+}
+
+// auditedSweep lists the messages removed by a batch of a retention sweep.
+type auditedSweep struct {
+	MessageIDs []int `json:"message_ids"`
+}
+
+// auditedWebhook is the audited representation of a webhook. The secret is
+// never recorded.
+type auditedWebhook struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+}
+
+func newAuditedWebhook(row queries.Webhook) auditedWebhook {
+	return auditedWebhook{URL: row.URL, EventTypes: row.EventTypes}
+}
+
+// recordAudit writes an audit event for a change to a target from before to
+// after using tx. Either snapshot may be nil (create / delete); otherwise only
+// the fields that differ are recorded.
+func recordAudit(ctx context.Context, tx queries.Queries, action, targetType string, targetID int, before, after any) error {
+	beforeJSON, afterJSON, err := auditDiff(before, after)
+	if err != nil {
+		return err
+	}
+
+	actor := ActorFrom(ctx)
+	params := queries.CreateAuditEventParams{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Before:     beforeJSON,
+		After:      afterJSON,
+		IP:         actor.IP,
+		RequestID:  actor.RequestID,
+	}
+	if actor.UserID > 0 {
+		params.ActorID = &actor.UserID
+	}
+	return tx.CreateAuditEvent(ctx, params)
+}
+
+// auditDiff encodes the fields of before and after that differ.
+func auditDiff(before, after any) (json.RawMessage, json.RawMessage, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if beforeFields != nil && afterFields != nil {
+		for key, value := range beforeFields {
+			if reflect.DeepEqual(value, afterFields[key]) {
+				delete(beforeFields, key)
+				delete(afterFields, key)
+			}
+		}
+	}
+
+	beforeJSON, err := marshalFields(beforeFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterJSON, err := marshalFields(afterFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	return beforeJSON, afterJSON, nil
+}
+
+func auditFields(snapshot any) (map[string]any, error) {
+	if snapshot == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	err = json.Unmarshal(data, &fields)
+	return fields, err
+}
+
+func marshalFields(fields map[string]any) (json.RawMessage, error) {
+	if fields == nil {
+		return nil, nil
+	}
+	return json.Marshal(fields)
+}
+
+// AuditFilter selects audit events for ListAuditEvents.
+type AuditFilter struct {
+	ActorID    int
+	Action     string
+	TargetType string
+	TargetID   int
+	Since      time.Time
+	Until      time.Time
+	BeforeID   int64
+	Limit      int
+}
+
+// ListAuditEvents returns audit events matching filter, newest first.
+// A limit of zero or less uses DefaultAuditLimit; limits are capped at MaxAuditLimit.
+func ListAuditEvents(ctx context.Context, filter AuditFilter) ([]queries.AuditEvent, error) {
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Until.After(filter.Since) {
+		return nil, invalid("until must be after since")
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultAuditLimit
+	}
+
+	return queries.GetAuditEvents(ctx, queries.AuditEventFilter{
+		ActorID:    filter.ActorID,
+		Action:     filter.Action,
+		TargetType: filter.TargetType,
+		TargetID:   filter.TargetID,
+		Since:      filter.Since,
+		Until:      filter.Until,
+		BeforeID:   filter.BeforeID,
+		Limit:      min(filter.Limit, MaxAuditLimit),
+	})
+}
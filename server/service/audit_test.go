@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"main/queries"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditDiff(t *testing.T) {
+	nickname := "L dawg"
+	before := auditedUser{Username: "liam", Email: "liam@email.com", UserType: "UTYPE_USER", Nickname: &nickname}
+	after := auditedUser{Username: "liam", Email: "liam@email.com", UserType: "UTYPE_ADMIN"}
+
+	b, a, err := auditDiff(before, after)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"user_type":"UTYPE_USER","nickname":"L dawg"}`, string(b))
+	assert.JSONEq(t, `{"user_type":"UTYPE_ADMIN","nickname":null}`, string(a))
+}
+
+func TestAuditDiffCreate(t *testing.T) {
+	b, a, err := auditDiff(nil, auditedMessage{UserID: 1, Content: "hi"})
+	assert.NoError(t, err)
+	assert.Nil(t, b)
+	assert.JSONEq(t, `{"user_id":1,"content":"hi"}`, string(a))
+}
+
+func TestAuditedWebhookOmitsSecret(t *testing.T) {
+	webhook := queries.Webhook{ID: 1, URL: "https://example.com/hook", Secret: "0123456789abcdef", EventTypes: []string{"user.created"}}
+
+	_, a, err := auditDiff(nil, newAuditedWebhook(webhook))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"url":"https://example.com/hook","event_types":["user.created"]}`, string(a))
+}
+
+func TestActorFrom(t *testing.T) {
+	assert.Equal(t, Actor{}, ActorFrom(context.Background()))
+
+	actor := Actor{UserID: 1, IP: "10.0.0.1", RequestID: "abc"}
+	assert.Equal(t, actor, ActorFrom(WithActor(context.Background(), actor)))
+}
+
+func TestListAuditEventsValidation(t *testing.T) {
+	now := time.Now()
+	_, err := ListAuditEvents(context.Background(), AuditFilter{Since: now, Until: now.Add(-time.Hour)})
+	assert.EqualError(t, err, "until must be after since")
+}
@@ -0,0 +1,366 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"main/queries"
+	"main/storage"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// MaxAvatarBytes limits the size of an uploaded avatar image.
+	MaxAvatarBytes = 5 << 20
+	// MaxAvatarPixels limits the dimensions of an uploaded avatar image, so
+	// that small files cannot decode to huge images.
+	MaxAvatarPixels = 40_000_000
+	// AvatarURLExpiry is how long the signed URLs that avatar URLs redirect to are valid.
+	AvatarURLExpiry = time.Hour
+)
+
+// AvatarSizes are the widths, in pixels, of the square sizes each avatar is
+// stored in, largest first. Images smaller than a size are not scaled up.
+var AvatarSizes = []int{512, 128, 48}
+
+// AvatarTypes lists the image types accepted as avatars, detected from the
+// file's contents. GIFs are reduced to their first frame.
+var AvatarTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// avatarKeyPattern matches the blob keys of avatar sizes: the largest size
+// is stored under the key in users.avatar_key and the others under it with
+// "_<size>" before the extension.
+var avatarKeyPattern = regexp.MustCompile(`^avatars/[0-9]+/[0-9a-f]{32}(_[0-9]+)?\.(png|jpg)$`)
+
+var (
+	avatarMu      sync.RWMutex
+	avatarBaseURL string
+)
+
+// ConfigureAvatarBaseURL sets the public URL of the server, which avatar
+// URLs start with. Until it is called, avatar URLs are relative.
+func ConfigureAvatarBaseURL(baseURL string) {
+	avatarMu.Lock()
+	defer avatarMu.Unlock()
+	avatarBaseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+// AvatarURL returns the URL of the given size of the avatar stored under key.
+// The URL does not expire: it redirects to a signed URL of the blob.
+func AvatarURL(key string, size int) string {
+	avatarMu.RLock()
+	defer avatarMu.RUnlock()
+	return avatarBaseURL + "/" + avatarSizeKey(key, size)
+}
+
+// AvatarURLs returns the URL of each of AvatarSizes of the avatar stored under key.
+func AvatarURLs(key string) map[int]string {
+	urls := make(map[int]string, len(AvatarSizes))
+	for _, size := range AvatarSizes {
+		urls[size] = AvatarURL(key, size)
+	}
+	return urls
+}
+
+// avatarSizeKey returns the blob key of the given size of the avatar stored under key.
+func avatarSizeKey(key string, size int) string {
+	if size == AvatarSizes[0] {
+		return key
+	}
+	dot := strings.LastIndex(key, ".")
+	return key[:dot] + "_" + strconv.Itoa(size) + key[dot:]
+}
+
+// AvatarDownloadURL returns a signed URL of the avatar size stored under key,
+// as requested through a URL from AvatarURL. Returns storage.ErrNotFound if
+// key is not an avatar key or there is no blob store.
+func AvatarDownloadURL(ctx context.Context, key string) (string, error) {
+	store := BlobStore()
+	if store == nil || !avatarKeyPattern.MatchString(key) {
+		return "", storage.ErrNotFound
+	}
+	return store.SignedURL(ctx, key, AvatarURLExpiry)
+}
+
+// SetAvatar replaces a user's avatar with the image of size bytes read from
+// r. The image is checked, cropped to a square, re-encoded without its
+// metadata and stored in each of AvatarSizes. The change is audited as made
+// by the actor attached to ctx and published as a user.updated event. The
+// old avatar's blobs are deleted once the new one is in place.
+func SetAvatar(ctx context.Context, userID int, r io.Reader, size int64) (queries.GetUsersQueryRow, error) {
+	if size <= 0 {
+		return queries.GetUsersQueryRow{}, invalid("Image is empty")
+	}
+	if size > MaxAvatarBytes {
+		return queries.GetUsersQueryRow{}, invalid("Avatars are limited to " + strconv.Itoa(MaxAvatarBytes>>20) + "MB")
+	}
+	data, err := io.ReadAll(io.LimitReader(r, MaxAvatarBytes))
+	if err != nil {
+		return queries.GetUsersQueryRow{}, err
+	}
+	avatar, err := processAvatar(data)
+	if err != nil {
+		return queries.GetUsersQueryRow{}, err
+	}
+	store := BlobStore()
+	if store == nil {
+		return queries.GetUsersQueryRow{}, errors.New("avatar storage is not configured")
+	}
+
+	suffix := make([]byte, 16)
+	if _, err := rand.Read(suffix); err != nil {
+		return queries.GetUsersQueryRow{}, err
+	}
+	key := "avatars/" + strconv.Itoa(userID) + "/" + hex.EncodeToString(suffix) + avatar.extension
+	var stored []string
+	deleteStored := func() {
+		for _, sizeKey := range stored {
+			if err := store.Delete(context.WithoutCancel(ctx), sizeKey); err != nil {
+				log.Printf("avatars: failed to delete unused blob %s: %v", sizeKey, err)
+			}
+		}
+	}
+	for _, size := range AvatarSizes {
+		sizeKey := avatarSizeKey(key, size)
+		encoded := avatar.sizes[size]
+		if err := store.Put(ctx, sizeKey, bytes.NewReader(encoded), int64(len(encoded)), avatar.contentType); err != nil {
+			deleteStored()
+			return queries.GetUsersQueryRow{}, err
+		}
+		stored = append(stored, sizeKey)
+	}
+
+	var before, user queries.GetUsersQueryRow
+	err = queries.WithTx(ctx, func(tx queries.Queries) error {
+		var err error
+		before, err = tx.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		user, err = tx.SetUserAvatar(ctx, userID, pgtype.Text{String: key, Valid: true})
+		if err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, tx, AuditUserUpdate, "user", userID, newAuditedUser(before), newAuditedUser(user)); err != nil {
+			return err
+		}
+		return publishEvent(ctx, tx, EventUserUpdated, newUserEventData(user))
+	})
+	if err != nil {
+		deleteStored()
+		return queries.GetUsersQueryRow{}, err
+	}
+
+	invalidateUser(userID)
+	if before.AvatarKey.Valid {
+		deleteAvatar(ctx, before.AvatarKey.String)
+	}
+	return user, nil
+}
+
+// deleteAvatar deletes the blobs of every size of the avatar stored under
+// key. Failures are logged rather than returned, since the avatar is no
+// longer referenced.
+func deleteAvatar(ctx context.Context, key string) {
+	store := BlobStore()
+	if store == nil {
+		return
+	}
+	for _, size := range AvatarSizes {
+		sizeKey := avatarSizeKey(key, size)
+		if err := store.Delete(context.WithoutCancel(ctx), sizeKey); err != nil {
+			log.Printf("avatars: failed to delete blob %s: %v", sizeKey, err)
+		}
+	}
+}
+
+// processedAvatar is an avatar image encoded in each of AvatarSizes.
+type processedAvatar struct {
+	sizes       map[int][]byte
+	contentType string
+	extension   string
+}
+
+// processAvatar checks that data is an image of one of AvatarTypes within
+// MaxAvatarPixels, applies its EXIF orientation, crops it to a centred
+// square and encodes it in each of AvatarSizes. JPEGs stay JPEGs and other
+// types become PNGs, keeping their transparency. Encoding from the decoded
+// pixels leaves out EXIF and any other metadata.
+func processAvatar(data []byte) (processedAvatar, error) {
+	mtype := mimetype.Detect(data)
+	if !mimetype.EqualsAny(mtype.String(), AvatarTypes...) {
+		return processedAvatar{}, invalid("Unsupported image type " + strings.SplitN(mtype.String(), ";", 2)[0])
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return processedAvatar{}, invalid("Invalid image: " + err.Error())
+	}
+	if config.Width*config.Height > MaxAvatarPixels {
+		return processedAvatar{}, invalid("Images are limited to " + strconv.Itoa(MaxAvatarPixels/1_000_000) + " megapixels")
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return processedAvatar{}, invalid("Invalid image: " + err.Error())
+	}
+
+	avatar := processedAvatar{sizes: map[int][]byte{}, contentType: "image/png", extension: ".png"}
+	isJPEG := mtype.Is("image/jpeg")
+	if isJPEG {
+		avatar.contentType, avatar.extension = "image/jpeg", ".jpg"
+	}
+
+	// Cropping to the centre and scaling commute with the orientation
+	// transforms, so orient the largest size rather than the full image.
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	crop := image.Rect(0, 0, side, side).Add(bounds.Min).Add(image.Pt((bounds.Dx()-side)/2, (bounds.Dy()-side)/2))
+	largest := scaleSquare(img, crop, min(side, AvatarSizes[0]))
+	if isJPEG {
+		largest = orient(largest, exifOrientation(data))
+	}
+
+	for _, size := range AvatarSizes {
+		scaled := largest
+		if size < largest.Bounds().Dx() {
+			scaled = scaleSquare(largest, largest.Bounds(), size)
+		}
+		var buf bytes.Buffer
+		if isJPEG {
+			err = jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&buf, scaled)
+		}
+		if err != nil {
+			return processedAvatar{}, err
+		}
+		avatar.sizes[size] = buf.Bytes()
+	}
+	return avatar, nil
+}
+
+// scaleSquare scales the square rect of src to a size by size image.
+func scaleSquare(src image.Image, rect image.Rectangle, size int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, rect, draw.Src, nil)
+	return dst
+}
+
+// orient transforms img as its EXIF orientation (1 to 8) prescribes, so that
+// it displays upright without the tag.
+func orient(img *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				sx, sy = w-1-x, y
+			case 3: // rotated 180°
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // needs rotating 90° clockwise
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // needs rotating 90° anticlockwise
+				sx, sy = w-1-y, x
+			}
+			dst.SetNRGBA(x, y, img.NRGBAAt(img.Bounds().Min.X+sx, img.Bounds().Min.Y+sy))
+		}
+	}
+	return dst
+}
+
+// exifOrientation returns the orientation tag of the EXIF data in a JPEG,
+// or 1 (upright) if there is none or it cannot be read.
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	// Walk the segments before the image data, looking for APP1 (EXIF).
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 { // start of scan / end of image
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag (0x0112) from the first IFD of
+// TIFF-structured EXIF data, or returns 1 if it is absent or malformed.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset : offset+2]))
+	for n := 0; n < entries; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
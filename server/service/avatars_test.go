@@ -0,0 +1,202 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"main/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// halves returns a width by height image whose left half is red and right half blue.
+func halves(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBA{R: 255, A: 255}
+			if x >= width/2 {
+				c = color.NRGBA{B: 255, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// withExifOrientation inserts an APP1 segment with the given orientation
+// after the start-of-image marker of a JPEG.
+func withExifOrientation(jpg []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3) // SHORT
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	tiff = append(append(tiff, entry...), 0, 0, 0, 0)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+	return append(append([]byte{0xFF, 0xD8}, segment...), jpg[2:]...)
+}
+
+func encodePNG(img image.Image) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func TestProcessAvatarPNG(t *testing.T) {
+	avatar, err := processAvatar(encodePNG(halves(300, 200)))
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", avatar.contentType)
+	assert.Equal(t, ".png", avatar.extension)
+
+	// The 200 pixel square is not scaled up to 512.
+	for size, width := range map[int]int{512: 200, 128: 128, 48: 48} {
+		img, format, err := image.Decode(bytes.NewReader(avatar.sizes[size]))
+		assert.NoError(t, err)
+		assert.Equal(t, "png", format)
+		assert.Equal(t, image.Rect(0, 0, width, width), img.Bounds(), size)
+	}
+}
+
+func TestProcessAvatarJPEGOrientation(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&buf, halves(80, 40), &jpeg.Options{Quality: 95}))
+	data := withExifOrientation(buf.Bytes(), 6)
+	assert.Equal(t, 6, exifOrientation(data))
+
+	avatar, err := processAvatar(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/jpeg", avatar.contentType)
+	assert.Equal(t, ".jpg", avatar.extension)
+
+	largest := avatar.sizes[512]
+	assert.NotContains(t, string(largest), "Exif")
+	assert.Equal(t, 1, exifOrientation(largest))
+
+	// Rotating clockwise turns the red left half into the top half.
+	img, err := jpeg.Decode(bytes.NewReader(largest))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 40, 40), img.Bounds())
+	r, _, b, _ := img.At(20, 5).RGBA()
+	assert.Greater(t, r, b)
+	r, _, b, _ = img.At(20, 35).RGBA()
+	assert.Greater(t, b, r)
+}
+
+func TestProcessAvatarInvalid(t *testing.T) {
+	_, err := processAvatar([]byte("not an image"))
+	assert.EqualError(t, err, "Unsupported image type text/plain")
+
+	_, err = processAvatar([]byte("%PDF-1.4\n"))
+	assert.EqualError(t, err, "Unsupported image type application/pdf")
+
+	_, err = processAvatar([]byte("\x89PNG\r\n\x1a\ngarbage"))
+	assert.ErrorContains(t, err, "Invalid image: ")
+
+	// A valid header claiming 10000 by 5000 pixels is rejected before decoding.
+	huge := encodePNG(halves(1, 1))
+	binary.BigEndian.PutUint32(huge[16:], 10000)
+	binary.BigEndian.PutUint32(huge[20:], 5000)
+	binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+	_, err = processAvatar(huge)
+	assert.EqualError(t, err, "Images are limited to 40 megapixels")
+}
+
+func TestOrient(t *testing.T) {
+	// 0 1 2
+	// 3 4 5
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for i := 0; i < 6; i++ {
+		src.SetNRGBA(i%3, i/3, color.NRGBA{R: uint8(i), A: 255})
+	}
+	pixels := func(img *image.NRGBA) []uint8 {
+		var values []uint8
+		for y := 0; y < img.Bounds().Dy(); y++ {
+			for x := 0; x < img.Bounds().Dx(); x++ {
+				values = append(values, img.NRGBAAt(x, y).R)
+			}
+		}
+		return values
+	}
+
+	for orientation, want := range map[int][]uint8{
+		1: {0, 1, 2, 3, 4, 5},
+		2: {2, 1, 0, 5, 4, 3},
+		3: {5, 4, 3, 2, 1, 0},
+		4: {3, 4, 5, 0, 1, 2},
+		5: {0, 3, 1, 4, 2, 5},
+		6: {3, 0, 4, 1, 5, 2},
+		7: {5, 2, 4, 1, 3, 0},
+		8: {2, 5, 1, 4, 0, 3},
+	} {
+		assert.Equal(t, want, pixels(orient(src, orientation)), orientation)
+	}
+}
+
+func TestExifOrientationMalformed(t *testing.T) {
+	for _, data := range [][]byte{
+		nil,
+		[]byte("\xFF\xD8"),
+		[]byte("\xFF\xD8\xFF\xE1\x00\xFFExif\x00\x00"),
+		[]byte("\xFF\xD8\xFF\xE1\x00\x10Exif\x00\x00XX\x00\x2a\x00\x00"),
+		withExifOrientation([]byte("\xFF\xD8"), 9),
+	} {
+		assert.Equal(t, 1, exifOrientation(data))
+	}
+}
+
+func TestAvatarURLs(t *testing.T) {
+	ConfigureAvatarBaseURL("https://chat.example.com/")
+	t.Cleanup(func() { ConfigureAvatarBaseURL("") })
+
+	key := "avatars/2/" + strings.Repeat("ab", 16) + ".png"
+	assert.Equal(t, map[int]string{
+		512: "https://chat.example.com/" + key,
+		128: "https://chat.example.com/avatars/2/" + strings.Repeat("ab", 16) + "_128.png",
+		48:  "https://chat.example.com/avatars/2/" + strings.Repeat("ab", 16) + "_48.png",
+	}, AvatarURLs(key))
+}
+
+func TestAvatarDownloadURL(t *testing.T) {
+	key := "avatars/2/" + strings.Repeat("ab", 16) + "_128.png"
+	_, err := AvatarDownloadURL(context.Background(), key)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	store, err := storage.NewLocal(t.TempDir(), "http://localhost:8080/blobs", []byte("secret"))
+	assert.NoError(t, err)
+	ConfigureBlobStore(store)
+	t.Cleanup(func() { ConfigureBlobStore(nil) })
+
+	url, err := AvatarDownloadURL(context.Background(), key)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(url, "http://localhost:8080/blobs/"+key+"?"), url)
+
+	for _, bad := range []string{"attachments/1/" + strings.Repeat("ab", 16) + ".png", "avatars/2/../secret.png", "avatars/2/abc.png"} {
+		_, err := AvatarDownloadURL(context.Background(), bad)
+		assert.ErrorIs(t, err, storage.ErrNotFound, bad)
+	}
+}
+
+func TestSetAvatarValidation(t *testing.T) {
+	_, err := SetAvatar(context.Background(), 1, strings.NewReader(""), 0)
+	assert.EqualError(t, err, "Image is empty")
+
+	_, err = SetAvatar(context.Background(), 1, strings.NewReader("x"), MaxAvatarBytes+1)
+	assert.EqualError(t, err, "Avatars are limited to 5MB")
+
+	_, err = SetAvatar(context.Background(), 1, strings.NewReader("hello"), 5)
+	assert.EqualError(t, err, "Unsupported image type text/plain")
+}
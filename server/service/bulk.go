@@ -0,0 +1,316 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"main/queries"
+)
+
+const (
+	// ImportAllOrNothing commits an import only if every row is valid.
+	ImportAllOrNothing = "all_or_nothing"
+	// ImportBestEffort commits the valid rows of an import and reports the rest.
+	ImportBestEffort = "best_effort"
+
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	// FormatZip is a zip archive holding a single JSON array.
+	FormatZip = "zip"
+
+	MaxImportRows = 10000
+
+	maxImportLineBytes = 1 << 20
+)
+
+// ImportModes are the valid values for an import's mode.
+var ImportModes = []string{ImportAllOrNothing, ImportBestEffort}
+
+// UserExportFormats are the formats users can be imported from and exported to.
+var UserExportFormats = []string{FormatCSV, FormatNDJSON}
+
+// MessageExportFormats are the formats messages can be exported to.
+var MessageExportFormats = []string{FormatCSV, FormatNDJSON, FormatZip}
+
+// importRecord is a user as read from an NDJSON import line. Its keys match
+// NDJSON exports, whose other fields are ignored, so exports can be re-imported.
+type importRecord struct {
+	Username string  `json:"username"`
+	Email    string  `json:"email"`
+	UserType string  `json:"user_type"`
+	Nickname *string `json:"nickname"`
+}
+
+// ImportRow is one user read from an import file. Err is set if the row
+// could not be parsed.
+type ImportRow struct {
+	Line   int
+	Params queries.CreateUserParams
+	Err    error
+}
+
+// ImportRowError reports why the row on a line of an import was not created.
+type ImportRowError struct {
+	Line    int
+	Message string
+}
+
+// ImportResult summarises an import. Created counts the users created, or
+// that would have been in a dry run; it is zero if an all-or-nothing import
+// had errors.
+type ImportResult struct {
+	DryRun  bool
+	Mode    string
+	Total   int
+	Created int
+	Errors  []ImportRowError
+}
+
+// errImportRolledBack aborts the transaction of a dry run or failed
+// all-or-nothing import once every row has been tried.
+var errImportRolledBack = errors.New("import rolled back")
+
+// ReadUserImport parses users from r in the given format. CSV input needs a
+// header row naming at least the username, email and user_type columns;
+// nickname is optional and other columns are ignored. NDJSON input has one
+// JSON object per line with the same keys. Rows that cannot be parsed are
+// returned with Err set; only malformed files and files over MaxImportRows
+// rows fail as a whole. Errors reading r are returned unchanged.
+func ReadUserImport(r io.Reader, format string) ([]ImportRow, error) {
+	switch format {
+	case FormatCSV:
+		return readCSVImport(r)
+	case FormatNDJSON:
+		return readNDJSONImport(r)
+	default:
+		return nil, invalid("Invalid format. Must be one of: " + strings.Join(UserExportFormats, ", "))
+	}
+}
+
+func readCSVImport(r io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	var parseErr *csv.ParseError
+	switch {
+	case errors.Is(err, io.EOF):
+		return nil, invalid("Import is empty")
+	case errors.As(err, &parseErr):
+		return nil, invalid("Invalid CSV: " + err.Error())
+	case err != nil:
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"username", "email", "user_type"} {
+		if _, ok := columns[name]; !ok {
+			return nil, invalid("CSV header must include username, email and user_type")
+		}
+	}
+	nickname, hasNickname := columns["nickname"]
+
+	rows := []ImportRow{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		switch {
+		case errors.As(err, &parseErr) && errors.Is(err, csv.ErrFieldCount):
+			rows = append(rows, ImportRow{Line: parseErr.StartLine, Err: invalid("Expected " + strconv.Itoa(len(header)) + " fields, got " + strconv.Itoa(len(record)))})
+		case errors.As(err, &parseErr):
+			return nil, invalid("Invalid CSV: " + err.Error())
+		case err != nil:
+			return nil, err
+		default:
+			line, _ := reader.FieldPos(0)
+			row := ImportRow{Line: line, Params: queries.CreateUserParams{
+				Username: record[columns["username"]],
+				Email:    record[columns["email"]],
+				UserType: record[columns["user_type"]],
+			}}
+			if hasNickname && record[nickname] != "" {
+				row.Params.Nickname = &record[nickname]
+			}
+			rows = append(rows, row)
+		}
+		if len(rows) > MaxImportRows {
+			return nil, invalid("Import is limited to " + strconv.Itoa(MaxImportRows) + " rows")
+		}
+	}
+	if len(rows) == 0 {
+		return nil, invalid("Import is empty")
+	}
+	return rows, nil
+}
+
+func readNDJSONImport(r io.Reader) ([]ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineBytes)
+
+	rows := []ImportRow{}
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var record importRecord
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			rows = append(rows, ImportRow{Line: line, Err: invalid("Invalid JSON: " + err.Error())})
+		} else {
+			rows = append(rows, ImportRow{Line: line, Params: queries.CreateUserParams{
+				Username: record.Username,
+				Email:    record.Email,
+				UserType: record.UserType,
+				Nickname: record.Nickname,
+			}})
+		}
+		if len(rows) > MaxImportRows {
+			return nil, invalid("Import is limited to " + strconv.Itoa(MaxImportRows) + " rows")
+		}
+	}
+	if err := scanner.Err(); errors.Is(err, bufio.ErrTooLong) {
+		return nil, invalid("Invalid NDJSON: lines are limited to " + strconv.Itoa(maxImportLineBytes) + " bytes")
+	} else if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, invalid("Import is empty")
+	}
+	return rows, nil
+}
+
+// ImportUsers validates and creates users in a single transaction, each row
+// in its own savepoint so a failed row does not abort the rest. Rows are
+// validated first; an all-or-nothing import with invalid rows reports them
+// without touching the database. Otherwise every valid row is inserted and
+// any database errors reported, then a dry run, or an all-or-nothing import
+// with errors, is rolled back. Each user created is audited and published as
+// if created by CreateUser.
+func ImportUsers(ctx context.Context, rows []ImportRow, mode string, dryRun bool) (ImportResult, error) {
+	if mode == "" {
+		mode = ImportAllOrNothing
+	}
+	if !slices.Contains(ImportModes, mode) {
+		return ImportResult{}, invalid("Invalid mode. Must be one of: " + strings.Join(ImportModes, ", "))
+	}
+	if len(rows) > MaxImportRows {
+		return ImportResult{}, invalid("Import is limited to " + strconv.Itoa(MaxImportRows) + " rows")
+	}
+
+	result := ImportResult{DryRun: dryRun, Mode: mode, Total: len(rows), Errors: []ImportRowError{}}
+	valid := make([]ImportRow, 0, len(rows))
+	usernames, emails := map[string]int{}, map[string]int{}
+	for _, row := range rows {
+		err := row.Err
+		if err == nil {
+			err = validateImportRow(row, usernames, emails)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, ImportRowError{Line: row.Line, Message: importErrorMessage(err)})
+			continue
+		}
+		valid = append(valid, row)
+	}
+	if len(valid) == 0 || (mode == ImportAllOrNothing && len(result.Errors) > 0) {
+		return result, nil
+	}
+
+	validationErrors := result.Errors
+	var created []int
+	err := queries.WithTx(ctx, func(tx queries.Queries) error {
+		result.Created, result.Errors, created = 0, slices.Clone(validationErrors), nil
+		for _, row := range valid {
+			var user queries.GetUsersQueryRow
+			err := tx.Savepoint(ctx, func(sp queries.Queries) error {
+				var err error
+				user, err = createUser(ctx, sp, row.Params)
+				return err
+			})
+			if err != nil {
+				result.Errors = append(result.Errors, ImportRowError{Line: row.Line, Message: importErrorMessage(err)})
+				continue
+			}
+			created = append(created, user.ID)
+			result.Created++
+		}
+
+		if mode == ImportAllOrNothing && len(result.Errors) > 0 {
+			result.Created = 0
+			return errImportRolledBack
+		}
+		if dryRun {
+			return errImportRolledBack
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportRolledBack) {
+		return ImportResult{}, err
+	}
+	slices.SortStableFunc(result.Errors, func(a, b ImportRowError) int { return a.Line - b.Line })
+
+	if err == nil && result.Created > 0 {
+		invalidateUsers(created)
+	}
+	return result, nil
+}
+
+// validateImportRow applies CreateUser's validation, checks the user type and
+// rejects usernames and emails already used earlier in the import, which are
+// recorded in seen maps keyed by value with the line they were first seen on.
+func validateImportRow(row ImportRow, usernames, emails map[string]int) error {
+	if err := validateNewUser(row.Params); err != nil {
+		return err
+	}
+	if !slices.Contains(UserTypes, row.Params.UserType) {
+		return invalid("Invalid user type. Must be one of: " + strings.Join(UserTypes, ", "))
+	}
+	if line, ok := usernames[row.Params.Username]; ok {
+		return invalid("Duplicate username, first used on line " + strconv.Itoa(line))
+	}
+	if line, ok := emails[row.Params.Email]; ok {
+		return invalid("Duplicate email, first used on line " + strconv.Itoa(line))
+	}
+	usernames[row.Params.Username] = row.Line
+	emails[row.Params.Email] = row.Line
+	return nil
+}
+
+func importErrorMessage(err error) string {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return validationErr.Message
+	}
+	return "Failed to create user: " + err.Error()
+}
+
+// ExportUsers calls fn for every user in ID order without loading them all
+// into memory, stopping at the first error from fn.
+func ExportUsers(ctx context.Context, fn func(queries.GetUsersQueryRow) error) error {
+	return queries.ExportUsers(ctx, fn)
+}
+
+// ExportMessages calls fn for every message matching filter in ID order
+// without loading them all into memory, stopping at the first error from fn.
+// Returns queries.ErrUserNotFound if filter.UserID names no user.
+func ExportMessages(ctx context.Context, filter queries.MessageExportFilter, fn func(queries.ExportedMessage) error) error {
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Until.After(filter.Since) {
+		return invalid("until must be after since")
+	}
+	if filter.UserID != 0 {
+		if _, err := GetUser(ctx, filter.UserID); err != nil {
+			return err
+		}
+	}
+	return queries.ExportMessages(ctx, filter, fn)
+}
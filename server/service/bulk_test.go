@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"main/queries"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadUserImportCSV(t *testing.T) {
+	rows, err := ReadUserImport(strings.NewReader(
+		"id,username,email,user_type,nickname\n"+
+			"1,alice,alice@example.com,UTYPE_USER,Al\n"+
+			"2,bob,bob@example.com,UTYPE_ADMIN,\n"+
+			"3,carol\n",
+	), FormatCSV)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, "alice", rows[0].Params.Username)
+	assert.Equal(t, "alice@example.com", rows[0].Params.Email)
+	assert.Equal(t, "UTYPE_USER", rows[0].Params.UserType)
+	assert.Equal(t, "Al", *rows[0].Params.Nickname)
+	assert.Nil(t, rows[1].Params.Nickname)
+
+	assert.Equal(t, 4, rows[2].Line)
+	assert.EqualError(t, rows[2].Err, "Expected 5 fields, got 2")
+}
+
+func TestReadUserImportCSVHeader(t *testing.T) {
+	_, err := ReadUserImport(strings.NewReader("username,email\nalice,alice@example.com\n"), FormatCSV)
+	assert.EqualError(t, err, "CSV header must include username, email and user_type")
+
+	_, err = ReadUserImport(strings.NewReader("username,email,user_type\n"), FormatCSV)
+	assert.EqualError(t, err, "Import is empty")
+
+	_, err = ReadUserImport(strings.NewReader(`username,email,user_type`+"\n"+`"alice,a@example.com,UTYPE_USER`), FormatCSV)
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+}
+
+func TestReadUserImportNDJSON(t *testing.T) {
+	rows, err := ReadUserImport(strings.NewReader(
+		`{"id":1,"username":"alice","email":"alice@example.com","user_type":"UTYPE_USER","nickname":"Al","message_count":3}`+"\n"+
+			"\n"+
+			`{"username":`+"\n",
+	), FormatNDJSON)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+
+	assert.Equal(t, 1, rows[0].Line)
+	assert.Equal(t, "alice", rows[0].Params.Username)
+	assert.Equal(t, "Al", *rows[0].Params.Nickname)
+
+	assert.Equal(t, 3, rows[1].Line)
+	assert.ErrorContains(t, rows[1].Err, "Invalid JSON")
+}
+
+func TestReadUserImportLimits(t *testing.T) {
+	_, err := ReadUserImport(strings.NewReader("a"), "xml")
+	assert.EqualError(t, err, "Invalid format. Must be one of: csv, ndjson")
+
+	_, err = ReadUserImport(strings.NewReader(strings.Repeat("{}\n", MaxImportRows+1)), FormatNDJSON)
+	assert.EqualError(t, err, "Import is limited to 10000 rows")
+}
+
+func TestImportUsersValidation(t *testing.T) {
+	_, err := ImportUsers(context.Background(), nil, "some", false)
+	assert.EqualError(t, err, "Invalid mode. Must be one of: all_or_nothing, best_effort")
+
+	rows, err := ReadUserImport(strings.NewReader(
+		"username,email,user_type\n"+
+			"alice,alice@example.com,UTYPE_USER\n"+
+			",bob@example.com,UTYPE_USER\n"+
+			"carol,carol@example.com,UTYPE_ROOT\n"+
+			"alice,alice2@example.com,UTYPE_USER\n"+
+			"dave,alice@example.com,UTYPE_USER\n",
+	), FormatCSV)
+	assert.NoError(t, err)
+
+	// Invalid rows fail an all-or-nothing import before it reaches the database.
+	result, err := ImportUsers(context.Background(), rows, "", true)
+	assert.NoError(t, err)
+	assert.Equal(t, ImportAllOrNothing, result.Mode)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, 5, result.Total)
+	assert.Equal(t, 0, result.Created)
+	assert.Equal(t, []ImportRowError{
+		{Line: 3, Message: "Username is required"},
+		{Line: 4, Message: "Invalid user type. Must be one of: UTYPE_USER, UTYPE_ADMIN, UTYPE_MODERATOR"},
+		{Line: 5, Message: "Duplicate username, first used on line 2"},
+		{Line: 6, Message: "Duplicate email, first used on line 2"},
+	}, result.Errors)
+}
+
+func TestExportMessagesValidation(t *testing.T) {
+	since := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	err := ExportMessages(context.Background(), queries.MessageExportFilter{Since: since, Until: since}, nil)
+	assert.EqualError(t, err, "until must be after since")
+}
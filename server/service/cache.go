@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"time"
+
+	"main/cache"
+	"main/queries"
+)
+
+const (
+	DefaultUserCacheSize = 1000
+	DefaultUserCacheTTL  = 30 * time.Second
+
+	usersListKey = "users:list"
+)
+
+// userCache serves ListUsers and GetUser. Writes that change a user's row or
+// message count invalidate the affected keys; the TTL bounds staleness from
+// writes made by other processes sharing the database but not the cache.
+var userCache = cache.New("user_cache", cache.NewLRU(DefaultUserCacheSize), DefaultUserCacheTTL)
+
+// ConfigureUserCache replaces the user cache's store and TTL. Call it before
+// serving requests, e.g. to share a Redis store between instances.
+func ConfigureUserCache(store cache.Store, ttl time.Duration) {
+	userCache = cache.New("user_cache", store, ttl)
+}
+
+// UserCacheStats returns the user cache's hit and miss counts.
+func UserCacheStats() (hits, misses int64) {
+	return userCache.Stats()
+}
+
+func userKey(userID int) string {
+	return "users:" + strconv.Itoa(userID)
+}
+
+// invalidateUser drops the cached list and, if userID is set, that user's entry.
+// It runs after the write has committed, so it ignores the request's context
+// rather than leave a stale entry behind if the client has gone away.
+func invalidateUser(userID int) {
+	keys := []string{usersListKey}
+	if userID > 0 {
+		keys = append(keys, userKey(userID))
+	}
+	userCache.Invalidate(context.Background(), keys...)
+}
+
+// invalidateUsers drops the cached list and the entries of userIDs, which may
+// repeat.
+func invalidateUsers(userIDs []int) {
+	keys := []string{usersListKey}
+	for _, userID := range slices.Compact(slices.Sorted(slices.Values(userIDs))) {
+		keys = append(keys, userKey(userID))
+	}
+	userCache.Invalidate(context.Background(), keys...)
+}
+
+func cachedUsers(ctx context.Context, load func() ([]queries.GetUsersQueryRow, error)) ([]queries.GetUsersQueryRow, error) {
+	return cache.GetOrLoad(ctx, userCache, usersListKey, load)
+}
+
+func cachedUser(ctx context.Context, userID int, load func() (queries.GetUsersQueryRow, error)) (queries.GetUsersQueryRow, error) {
+	return cache.GetOrLoad(ctx, userCache, userKey(userID), load)
+}
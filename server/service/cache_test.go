@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"main/cache"
+	"main/queries"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedUserRoundTrip(t *testing.T) {
+	ConfigureUserCache(cache.NewLRU(10), time.Minute)
+	t.Cleanup(func() { ConfigureUserCache(cache.NewLRU(DefaultUserCacheSize), DefaultUserCacheTTL) })
+
+	row := queries.GetUsersQueryRow{
+		ID:           7,
+		Username:     "liam",
+		Nickname:     pgtype.Text{String: "li", Valid: true},
+		MessageCount: 3,
+		Version:      2,
+		UpdatedAt:    pgtype.Timestamptz{Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true},
+	}
+	loads := 0
+	load := func() (queries.GetUsersQueryRow, error) {
+		loads++
+		return row, nil
+	}
+
+	cachedUser(context.Background(), 7, load)
+	got, err := cachedUser(context.Background(), 7, load)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, loads)
+	assert.Equal(t, row.Nickname, got.Nickname)
+	assert.True(t, row.UpdatedAt.Time.Equal(got.UpdatedAt.Time))
+
+	invalidateUser(7)
+	cachedUser(context.Background(), 7, load)
+	assert.Equal(t, 2, loads)
+}
+
+func TestCachedUserSkipsNotFound(t *testing.T) {
+	ConfigureUserCache(cache.NewLRU(10), time.Minute)
+	t.Cleanup(func() { ConfigureUserCache(cache.NewLRU(DefaultUserCacheSize), DefaultUserCacheTTL) })
+
+	_, err := cachedUser(context.Background(), 8, func() (queries.GetUsersQueryRow, error) {
+		return queries.GetUsersQueryRow{}, queries.ErrUserNotFound
+	})
+	assert.True(t, errors.Is(err, queries.ErrUserNotFound))
+
+	_, misses := UserCacheStats()
+	cachedUser(context.Background(), 8, func() (queries.GetUsersQueryRow, error) { return queries.GetUsersQueryRow{ID: 8}, nil })
+	_, after := UserCacheStats()
+	assert.Equal(t, misses+1, after)
+}
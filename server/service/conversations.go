@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"main/queries"
+)
+
+// MaxConversationTitleLength is the longest conversation title, in characters.
+const MaxConversationTitleLength = 100
+
+// CreateConversation validates and starts a conversation, recording it in
+// the audit log as made by the actor attached to ctx.
+func CreateConversation(ctx context.Context, title string) (queries.Conversation, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return queries.Conversation{}, invalid("Title is required")
+	}
+	if utf8.RuneCountInString(title) > MaxConversationTitleLength {
+		return queries.Conversation{}, invalid("Title is limited to " + strconv.Itoa(MaxConversationTitleLength) + " characters")
+	}
+
+	var conversation queries.Conversation
+	err := queries.WithTx(ctx, func(tx queries.Queries) error {
+		var err error
+		conversation, err = tx.CreateConversation(ctx, title)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, AuditConversationCreate, "conversation", conversation.ID, nil, auditedConversation{Title: title})
+	})
+	if err != nil {
+		return queries.Conversation{}, err
+	}
+	return conversation, nil
+}
+
+// ListConversations returns all conversations, oldest first.
+func ListConversations(ctx context.Context) ([]queries.Conversation, error) {
+	return queries.GetConversations(ctx)
+}
+
+// ListConversationMessages returns a page of the messages in a conversation,
+// newest first, starting before the message with ID beforeID (zero for the
+// first page). Limits are as for ListReplies. Returns
+// queries.ErrConversationNotFound if there is no such conversation.
+func ListConversationMessages(ctx context.Context, conversationID, limit, beforeID int) ([]queries.GetMessagesQueryRow, error) {
+	if _, err := queries.GetConversation(ctx, conversationID); err != nil {
+		return nil, err
+	}
+	return queries.GetConversationMessages(ctx, conversationID, messageLimit(limit), beforeID)
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"main/queries"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateConversationValidation(t *testing.T) {
+	var validationErr *ValidationError
+	_, err := CreateConversation(context.Background(), "  ")
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "Title is required", validationErr.Message)
+
+	_, err = CreateConversation(context.Background(), strings.Repeat("a", MaxConversationTitleLength+1))
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "Title is limited to 100 characters", validationErr.Message)
+
+	_, err = CreateMessage(context.Background(), queries.CreateMessageParams{UserID: 1, Content: "hi", ConversationID: -1})
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "Invalid conversation ID", validationErr.Message)
+}
@@ -0,0 +1,17 @@
+// Package service holds the business logic shared by the REST handlers and
+// the gRPC server, so both transports validate and behave identically.
+package service
+
+// ValidationError reports invalid input. Transports map it to a client error
+// (400 Bad Request / InvalidArgument).
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func invalid(message string) error {
+	return &ValidationError{Message: message}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"main/queries"
+)
+
+// Domain events written to the outbox and delivered to webhooks.
+const (
+	EventUserCreated    = "user.created"
+	EventUserUpdated    = "user.updated"
+	EventMessageCreated = "message.created"
+	EventUserErased     = "user.erased"
+)
+
+// EventTypes are the valid domain event types.
+var EventTypes = []string{EventUserCreated, EventUserUpdated, EventMessageCreated, EventUserErased}
+
+// UserEventData is the data of user.created and user.updated events.
+type UserEventData struct {
+	ID       int     `json:"id"`
+	Username string  `json:"username"`
+	Email    string  `json:"email"`
+	UserType string  `json:"user_type"`
+	Nickname *string `json:"nickname"`
+	Version  int32   `json:"version"`
+	// AvatarURL is the URL of the largest size of the user's avatar, if they have one.
+	AvatarURL string `json:"avatar_url,omitempty"`
+}
+
+func newUserEventData(row queries.GetUsersQueryRow) UserEventData {
+	data := UserEventData{
+		ID:       row.ID,
+		Username: row.Username,
+		Email:    row.Email,
+		UserType: row.UserType,
+		Version:  row.Version,
+	}
+	if row.Nickname.Valid {
+		data.Nickname = &row.Nickname.String
+	}
+	if row.AvatarKey.Valid {
+		data.AvatarURL = AvatarURL(row.AvatarKey.String, AvatarSizes[0])
+	}
+	return data
+}
+
+// MessageEventData is the data of message.created events.
+type MessageEventData struct {
+	ID        int    `json:"id"`
+	UserID    int    `json:"user_id"`
+	Content   string `json:"content"`
+	CreatedAt string `json:"created_at"`
+	// ParentID is set on replies.
+	ParentID       *int `json:"parent_id,omitempty"`
+	ConversationID int  `json:"conversation_id"`
+	// Held is set in data exports on messages held for review by moderation.
+	Held bool `json:"held,omitempty"`
+	// MentionedUserIDs and Tags are set on message.created events for messages with @mentions and #tags.
+	MentionedUserIDs []int    `json:"mentioned_user_ids,omitempty"`
+	Tags             []string `json:"tags,omitempty"`
+}
+
+func newMessageEventData(row queries.GetMessagesQueryRow) MessageEventData {
+	data := MessageEventData{
+		ID:             row.ID,
+		UserID:         row.UserID,
+		Content:        row.Content,
+		CreatedAt:      row.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		ConversationID: row.ConversationID,
+		Held:           row.Held,
+	}
+	if row.ParentID.Valid {
+		parentID := int(row.ParentID.Int32)
+		data.ParentID = &parentID
+	}
+	return data
+}
+
+// UserErasedEventData is the data of user.erased events. Receivers holding
+// copies of the user's personal data should erase them too.
+type UserErasedEventData struct {
+	ID            int    `json:"id"`
+	MessagePolicy string `json:"message_policy"`
+}
+
+// publishEvent adds an event to the outbox using tx, so it is delivered if
+// and only if the transaction commits.
+func publishEvent(ctx context.Context, tx queries.Queries, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return tx.CreateOutboxEvent(ctx, eventType, payload)
+}
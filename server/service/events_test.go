@@ -0,0 +1,32 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"main/queries"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventData(t *testing.T) {
+	user, _ := json.Marshal(newUserEventData(queries.GetUsersQueryRow{
+		ID:       1,
+		Username: "liam",
+		Email:    "liam@email.com",
+		UserType: "UTYPE_ADMIN",
+		Version:  2,
+	}))
+	assert.JSONEq(t, `{"id":1,"username":"liam","email":"liam@email.com","user_type":"UTYPE_ADMIN","nickname":null,"version":2}`, string(user))
+
+	message, _ := json.Marshal(newMessageEventData(queries.GetMessagesQueryRow{
+		ID:             3,
+		UserID:         1,
+		Content:        "hi",
+		CreatedAt:      pgtype.Timestamptz{Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true},
+		ConversationID: 1,
+	}))
+	assert.JSONEq(t, `{"id":3,"user_id":1,"content":"hi","created_at":"2025-01-02T03:04:05Z","conversation_id":1}`, string(message))
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"main/jobs"
+	"main/queries"
+)
+
+// Background jobs run by the worker.
+var (
+	// PurgeOutboxJob deletes delivered outbox events older than the given age.
+	PurgeOutboxJob = jobs.NewKind[PurgeOutboxArgs]("purge_outbox")
+	// SweepMessagesJob removes expired messages and applies retention policies.
+	SweepMessagesJob = jobs.NewKind[struct{}]("sweep_messages")
+	// PurgeAttachmentsJob deletes the blobs of attachments whose messages were deleted.
+	PurgeAttachmentsJob = jobs.NewKind[struct{}]("purge_attachments")
+)
+
+// PurgeOutboxArgs are the arguments of PurgeOutboxJob.
+type PurgeOutboxArgs struct {
+	OlderThanHours int `json:"older_than_hours"`
+}
+
+// RegisterJobs registers the job handlers and their schedules with r.
+func RegisterJobs(r *jobs.Runner) error {
+	jobs.Register(r, PurgeOutboxJob, purgeOutbox)
+	jobs.Register(r, SweepMessagesJob, sweepMessages)
+	jobs.Register(r, PurgeAttachmentsJob, purgeAttachments)
+
+	if err := jobs.Schedule(r, "*/5 * * * *", SweepMessagesJob, struct{}{}); err != nil {
+		return err
+	}
+	if err := jobs.Schedule(r, "15 * * * *", PurgeAttachmentsJob, struct{}{}); err != nil {
+		return err
+	}
+	return jobs.Schedule(r, "30 3 * * *", PurgeOutboxJob, PurgeOutboxArgs{OlderThanHours: 7 * 24})
+}
+
+func purgeOutbox(ctx context.Context, args PurgeOutboxArgs) error {
+	if args.OlderThanHours <= 0 {
+		return invalid("older_than_hours must be positive")
+	}
+	purged, err := queries.PurgeOutboxEvents(ctx, time.Duration(args.OlderThanHours)*time.Hour)
+	if err != nil {
+		return err
+	}
+	log.Printf("jobs: purged %d outbox events", purged)
+	return nil
+}
+
+func sweepMessages(ctx context.Context, _ struct{}) error {
+	result, err := SweepMessages(ctx)
+	if result.Total() > 0 {
+		log.Printf("jobs: swept %d messages (%d expired, %d purged, %d archived)",
+			result.Total(), result.Expired, result.Purged, result.Archived)
+	}
+	return err
+}
+
+func purgeAttachments(ctx context.Context, _ struct{}) error {
+	total := 0
+	for {
+		purged, err := PurgeDetachedAttachments(ctx, 100)
+		total += purged
+		if err != nil || purged < 100 {
+			if total > 0 {
+				log.Printf("jobs: purged %d detached attachments", total)
+			}
+			return err
+		}
+	}
+}
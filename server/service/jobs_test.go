@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"main/jobs"
+	"main/queries"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingStore is a jobs.Store that records what is enqueued and dequeued
+// and never has any jobs to run.
+type recordingStore struct {
+	mu       sync.Mutex
+	enqueued map[string]int
+	dequeued [][]string
+	dequeues chan struct{}
+}
+
+func (s *recordingStore) EnqueueJob(ctx context.Context, params queries.EnqueueJobParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enqueued[params.Kind]++
+	return int64(len(s.enqueued)), nil
+}
+
+func (s *recordingStore) DequeueJobs(ctx context.Context, workerID string, kinds []string, limit int) ([]queries.Job, error) {
+	s.mu.Lock()
+	s.dequeued = append(s.dequeued, kinds)
+	s.mu.Unlock()
+	s.dequeues <- struct{}{}
+	return nil, nil
+}
+
+func (s *recordingStore) CompleteJob(ctx context.Context, jobID int64) error { return nil }
+
+func (s *recordingStore) FailJob(ctx context.Context, params queries.FailJobParams) error { return nil }
+
+func (s *recordingStore) RequeueStaleJobs(ctx context.Context, timeout time.Duration) (int, error) {
+	return 0, nil
+}
+
+func TestRegisterJobs(t *testing.T) {
+	store := &recordingStore{enqueued: map[string]int{}, dequeues: make(chan struct{}, 16)}
+	r := jobs.NewRunner()
+	r.Store = store
+
+	// The first pass starts the schedules; the second is a day later.
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	now := start
+	r.Now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	assert.NoError(t, RegisterJobs(r))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx, time.Millisecond)
+		close(done)
+	}()
+	<-store.dequeues
+	mu.Lock()
+	now = start.Add(24 * time.Hour)
+	mu.Unlock()
+	<-store.dequeues
+	<-store.dequeues
+	cancel()
+	for {
+		select {
+		case <-store.dequeues:
+			continue
+		case <-done:
+		}
+		break
+	}
+
+	assert.ElementsMatch(t, []string{"purge_outbox", "sweep_messages", "purge_attachments"}, store.dequeued[0])
+	assert.Equal(t, map[string]int{
+		"sweep_messages":    24 * 12,
+		"purge_attachments": 24,
+		"purge_outbox":      1,
+	}, store.enqueued)
+}
+
+func TestPurgeOutboxValidation(t *testing.T) {
+	err := purgeOutbox(context.Background(), PurgeOutboxArgs{})
+	assert.EqualError(t, err, "older_than_hours must be positive")
+}
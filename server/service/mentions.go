@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"main/queries"
+)
+
+const (
+	// MaxMentions and MaxTags limit how many of each are linked to one message;
+	// any further ones are left as plain text.
+	MaxMentions = 50
+	MaxTags     = 20
+
+	// MaxTagLength is the longest tag linked, in characters.
+	MaxTagLength = 64
+)
+
+var (
+	// mentionPattern matches @username at the start of the text or after a
+	// character that cannot be part of a word or email address.
+	mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@-])@([\p{L}\p{N}_.-]+)`)
+	// tagPattern matches #tag likewise.
+	tagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#])#([\p{L}\p{N}_]+)`)
+	// tagFormat is what a whole tag must look like.
+	tagFormat = regexp.MustCompile(`^[\p{L}\p{N}_]+$`)
+)
+
+// parseMentions returns the distinct usernames @mentioned in content, in the
+// order first mentioned. Trailing dots and hyphens, as at the end of a
+// sentence, are not part of a mention.
+func parseMentions(content string) []string {
+	return distinctMatches(mentionPattern, content, MaxMentions, func(username string) string {
+		return strings.TrimRight(username, ".-")
+	})
+}
+
+// parseTags returns the distinct #tags in content, lowercased, in the order
+// first used. Tags longer than MaxTagLength are ignored.
+func parseTags(content string) []string {
+	return distinctMatches(tagPattern, content, MaxTags, func(tag string) string {
+		if len([]rune(tag)) > MaxTagLength {
+			return ""
+		}
+		return strings.ToLower(tag)
+	})
+}
+
+// distinctMatches returns up to limit distinct, non-empty results of
+// normalize applied to the first capture group of each match of pattern.
+func distinctMatches(pattern *regexp.Regexp, content string, limit int, normalize func(string) string) []string {
+	seen := map[string]bool{}
+	values := []string{}
+	for _, match := range pattern.FindAllStringSubmatch(content, -1) {
+		value := normalize(match[1])
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		values = append(values, value)
+		if len(values) == limit {
+			break
+		}
+	}
+	return values
+}
+
+// NormalizeTag lowercases tag, with any leading #, or returns a validation
+// error if it is not a valid tag.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.TrimPrefix(tag, "#")
+	if !tagFormat.MatchString(tag) || len([]rune(tag)) > MaxTagLength {
+		return "", invalid("Invalid tag")
+	}
+	return strings.ToLower(tag), nil
+}
+
+// ListMentions returns a page of the messages mentioning a user, newest
+// first, starting before the message with ID beforeID (zero for the first
+// page). Limits are as for ListReplies. Returns queries.ErrUserNotFound if
+// there is no such user.
+func ListMentions(ctx context.Context, userID, limit, beforeID int) ([]queries.GetMessagesQueryRow, error) {
+	if _, err := GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	return queries.GetMessagesMentioning(ctx, userID, messageLimit(limit), beforeID)
+}
+
+// ListTagMessages returns a page of the messages with a tag, newest first,
+// starting before the message with ID beforeID (zero for the first page).
+// Limits are as for ListReplies.
+func ListTagMessages(ctx context.Context, tag string, limit, beforeID int) ([]queries.GetMessagesQueryRow, error) {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return nil, err
+	}
+	return queries.GetMessagesByTag(ctx, tag, messageLimit(limit), beforeID)
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMentions(t *testing.T) {
+	cases := map[string][]string{
+		"hi @jon":                          {"jon"},
+		"@jon, @arya and @jon again":       {"jon", "arya"},
+		"thanks @sam.tarly.":               {"sam.tarly"},
+		"(cc @bran_stark)":                 {"bran_stark"},
+		"@josé":                            {"josé"},
+		"mail jon@example.com or @@nobody": {},
+		"no mentions here":                 {},
+	}
+	for content, want := range cases {
+		assert.Equal(t, want, parseMentions(content), content)
+	}
+}
+
+func TestParseMentionsLimit(t *testing.T) {
+	var content strings.Builder
+	for i := range MaxMentions + 10 {
+		content.WriteString("@user" + strconv.Itoa(i) + " ")
+	}
+	assert.Len(t, parseMentions(content.String()), MaxMentions)
+}
+
+func TestParseTags(t *testing.T) {
+	cases := map[string][]string{
+		"#Go is #fun, #go!":           {"go", "fun"},
+		"issue#12 and &#39; entities": {},
+		"#café #日本":                   {"café", "日本"},
+		"##double":                    {},
+		"#" + strings.Repeat("a", 65): {},
+	}
+	for content, want := range cases {
+		assert.Equal(t, want, parseTags(content), content)
+	}
+}
+
+func TestNormalizeTag(t *testing.T) {
+	tag, err := NormalizeTag("#GoLang")
+	assert.NoError(t, err)
+	assert.Equal(t, "golang", tag)
+
+	for _, bad := range []string{"", "#", "two words", "a-b", strings.Repeat("a", 65)} {
+		_, err := NormalizeTag(bad)
+		assert.EqualError(t, err, "Invalid tag", bad)
+	}
+}
+
+func TestLoadMessageDetailsEmpty(t *testing.T) {
+	details, err := LoadMessageDetails(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, details.Reactions)
+	assert.Empty(t, details.Replies)
+	assert.Empty(t, details.Mentions)
+	assert.Empty(t, details.Tags)
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"main/moderation"
+	"main/queries"
+)
+
+// ListMessages returns all messages.
+func ListMessages(ctx context.Context) ([]queries.GetMessagesQueryRow, error) {
+	return queries.GetMessages(ctx)
+}
+
+// Page sizes of paged message lists.
+const (
+	DefaultMessageLimit = 50
+	MaxMessageLimit     = 200
+)
+
+// messageLimit applies DefaultMessageLimit to limits of zero or less and
+// caps limits at MaxMessageLimit.
+func messageLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultMessageLimit
+	}
+	return min(limit, MaxMessageLimit)
+}
+
+// ListReplies returns a page of the direct replies to a message, oldest
+// first, starting after the reply with ID afterID (zero for the first page).
+// A limit of zero or less uses DefaultMessageLimit; limits are capped at
+// MaxMessageLimit. Returns queries.ErrMessageNotFound if there is no such message.
+func ListReplies(ctx context.Context, messageID, limit, afterID int) ([]queries.GetMessagesQueryRow, error) {
+	if _, err := queries.GetMessage(ctx, messageID); err != nil {
+		return nil, err
+	}
+	return queries.GetReplies(ctx, messageID, messageLimit(limit), afterID)
+}
+
+// ReplySummaries returns the reply counts and latest reply times of the
+// given messages, keyed by message ID, read in one query. Messages without
+// replies are omitted.
+func ReplySummaries(ctx context.Context, messageIDs []int) (map[int]queries.ReplySummary, error) {
+	replies := map[int]queries.ReplySummary{}
+	if len(messageIDs) == 0 {
+		return replies, nil
+	}
+
+	summaries, err := queries.GetReplySummaries(ctx, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, summary := range summaries {
+		replies[summary.MessageID] = summary
+	}
+	return replies, nil
+}
+
+// MessageDetails holds what message lists show alongside the messages
+// themselves, keyed by message ID. Messages without a detail have no entry.
+type MessageDetails struct {
+	Reactions   map[int][]queries.ReactionSummary
+	Replies     map[int]queries.ReplySummary
+	Mentions    map[int][]queries.MessageMention
+	Tags        map[int][]string
+	Attachments map[int][]Attachment
+}
+
+// LoadMessageDetails reads the details of the given messages with one query
+// per kind of detail, however many messages there are. Reactions are read
+// for the actor attached to ctx, as for MessageReactions.
+func LoadMessageDetails(ctx context.Context, messageIDs []int) (MessageDetails, error) {
+	details := MessageDetails{
+		Mentions:    map[int][]queries.MessageMention{},
+		Tags:        map[int][]string{},
+		Attachments: map[int][]Attachment{},
+	}
+	var err error
+	if details.Reactions, err = MessageReactions(ctx, messageIDs); err != nil {
+		return MessageDetails{}, err
+	}
+	if details.Replies, err = ReplySummaries(ctx, messageIDs); err != nil {
+		return MessageDetails{}, err
+	}
+	if len(messageIDs) == 0 {
+		return details, nil
+	}
+
+	mentions, err := queries.GetMentions(ctx, messageIDs)
+	if err != nil {
+		return MessageDetails{}, err
+	}
+	for _, mention := range mentions {
+		details.Mentions[mention.MessageID] = append(details.Mentions[mention.MessageID], mention)
+	}
+	tags, err := queries.GetTags(ctx, messageIDs)
+	if err != nil {
+		return MessageDetails{}, err
+	}
+	for _, tag := range tags {
+		details.Tags[tag.MessageID] = append(details.Tags[tag.MessageID], tag.Tag)
+	}
+	if details.Attachments, err = MessageAttachments(ctx, messageIDs); err != nil {
+		return MessageDetails{}, err
+	}
+	return details, nil
+}
+
+// ListUserMessages returns a user's messages, newest first.
+func ListUserMessages(ctx context.Context, userID int) ([]queries.GetMessagesQueryRow, error) {
+	return queries.GetMessagesByUser(ctx, userID)
+}
+
+// CreateMessage validates, moderates and stores a message, recording it in
+// the audit log as made by the actor attached to ctx. A message rejected by
+// moderation returns a *MessageRejectedError. A held message is stored hidden
+// and queued for review; it is announced when ApproveMessage approves it.
+func CreateMessage(ctx context.Context, params queries.CreateMessageParams) (queries.GetMessagesQueryRow, error) {
+	if params.UserID <= 0 {
+		return queries.GetMessagesQueryRow{}, invalid("User ID is required")
+	}
+	if strings.TrimSpace(params.Content) == "" {
+		return queries.GetMessagesQueryRow{}, invalid("Content is required")
+	}
+	if params.ExpiresAt.Valid && !params.ExpiresAt.Time.After(time.Now()) {
+		return queries.GetMessagesQueryRow{}, invalid("expires_at must be in the future")
+	}
+	if params.ParentID != nil && *params.ParentID <= 0 {
+		return queries.GetMessagesQueryRow{}, invalid("Invalid parent ID")
+	}
+	if params.ConversationID < 0 {
+		return queries.GetMessagesQueryRow{}, invalid("Invalid conversation ID")
+	}
+
+	// The moderator may mask parts of the content, hold it or reject it.
+	verdict, err := moderate(ctx, params.Content)
+	if err != nil {
+		return queries.GetMessagesQueryRow{}, err
+	}
+	if verdict.Action == moderation.Reject {
+		return queries.GetMessagesQueryRow{}, &MessageRejectedError{Reasons: verdict.Reasons}
+	}
+	params.Content = verdict.Content
+	params.Held = verdict.Action == moderation.Hold
+
+	var message queries.GetMessagesQueryRow
+	err = queries.WithTx(ctx, func(tx queries.Queries) error {
+		// A reply goes in its parent's conversation, and may not name another.
+		// Other messages go in the default conversation unless one is given.
+		create := params
+		if create.ParentID != nil {
+			parent, err := tx.GetMessage(ctx, *create.ParentID)
+			if errors.Is(err, queries.ErrMessageNotFound) {
+				return invalid("Parent message not found")
+			}
+			if err != nil {
+				return err
+			}
+			if create.ConversationID == 0 {
+				create.ConversationID = parent.ConversationID
+			}
+			if create.ConversationID != parent.ConversationID {
+				return invalid("Parent message is in a different conversation")
+			}
+		}
+		if create.ConversationID == 0 {
+			create.ConversationID = queries.DefaultConversationID
+		} else if create.ParentID == nil {
+			_, err := tx.GetConversation(ctx, create.ConversationID)
+			if errors.Is(err, queries.ErrConversationNotFound) {
+				return invalid("Conversation not found")
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		var err error
+		message, err = tx.CreateMessage(ctx, create)
+		if err != nil {
+			return err
+		}
+		// Link @mentions of existing users and #tags in the content.
+		event := newMessageEventData(message)
+		if usernames := parseMentions(message.Content); len(usernames) > 0 {
+			mentions, err := tx.LinkMentions(ctx, message.ID, usernames)
+			if err != nil {
+				return err
+			}
+			for _, mention := range mentions {
+				event.MentionedUserIDs = append(event.MentionedUserIDs, mention.UserID)
+			}
+		}
+		if tags := parseTags(message.Content); len(tags) > 0 {
+			if err := tx.LinkTags(ctx, message.ID, tags); err != nil {
+				return err
+			}
+			event.Tags = tags
+		}
+
+		if message.Held {
+			if err := tx.QueueMessage(ctx, message.ID, verdict.Reasons); err != nil {
+				return err
+			}
+		}
+
+		err = recordAudit(ctx, tx, AuditMessageCreate, "message", message.ID, nil, newAuditedMessage(message))
+		if err != nil {
+			return err
+		}
+		// ApproveMessage publishes the event of a held message.
+		if message.Held {
+			return nil
+		}
+		return publishEvent(ctx, tx, EventMessageCreated, event)
+	})
+	if err != nil {
+		return queries.GetMessagesQueryRow{}, err
+	}
+
+	// The author's message count changed.
+	invalidateUser(params.UserID)
+	if !message.Held {
+		NewMessages.Publish(message)
+	}
+	return message, nil
+}
+
+// NewMessages receives every message created through this process.
+var NewMessages = &Broker{}
+
+// Broker fans out newly created messages to in-process subscribers such as
+// gRPC streams. Slow subscribers miss messages rather than block publishers.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[chan queries.GetMessagesQueryRow]struct{}
+}
+
+// Subscribe returns a channel of new messages and a function that ends the subscription.
+func (b *Broker) Subscribe() (<-chan queries.GetMessagesQueryRow, func()) {
+	ch := make(chan queries.GetMessagesQueryRow, 16)
+
+	b.mu.Lock()
+	if b.subscribers == nil {
+		b.subscribers = map[chan queries.GetMessagesQueryRow]struct{}{}
+	}
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish sends message to all current subscribers.
+func (b *Broker) Publish(message queries.GetMessagesQueryRow) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- message:
+		default:
+		}
+	}
+}
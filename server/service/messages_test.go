@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"main/queries"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroker(t *testing.T) {
+	broker := &Broker{}
+	first, unsubscribeFirst := broker.Subscribe()
+	second, unsubscribeSecond := broker.Subscribe()
+	defer unsubscribeSecond()
+
+	broker.Publish(queries.GetMessagesQueryRow{ID: 1})
+	assert.Equal(t, 1, (<-first).ID)
+	assert.Equal(t, 1, (<-second).ID)
+
+	unsubscribeFirst()
+	unsubscribeFirst()
+	_, open := <-first
+	assert.False(t, open)
+
+	broker.Publish(queries.GetMessagesQueryRow{ID: 2})
+	assert.Equal(t, 2, (<-second).ID)
+}
+
+func TestCreateMessageValidation(t *testing.T) {
+	_, err := CreateMessage(context.Background(), queries.CreateMessageParams{UserID: 0, Content: "hi"})
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+
+	_, err = CreateMessage(context.Background(), queries.CreateMessageParams{UserID: 1, Content: "   "})
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "Content is required", validationErr.Message)
+
+	_, err = CreateMessage(context.Background(), queries.CreateMessageParams{
+		UserID:    1,
+		Content:   "hi",
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true},
+	})
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "expires_at must be in the future", validationErr.Message)
+
+	parentID := 0
+	_, err = CreateMessage(context.Background(), queries.CreateMessageParams{UserID: 1, Content: "hi", ParentID: &parentID})
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "Invalid parent ID", validationErr.Message)
+}
+
+func TestReplySummariesEmpty(t *testing.T) {
+	replies, err := ReplySummaries(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, replies)
+}
+
+func TestNewMessageEventDataParentID(t *testing.T) {
+	assert.Nil(t, newMessageEventData(queries.GetMessagesQueryRow{ID: 2}).ParentID)
+
+	data := newMessageEventData(queries.GetMessagesQueryRow{ID: 2, ParentID: pgtype.Int4{Int32: 1, Valid: true}})
+	assert.Equal(t, 1, *data.ParentID)
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"main/moderation"
+	"main/queries"
+)
+
+// MessageRejectedError is returned when moderation rejects a message.
+type MessageRejectedError struct {
+	Reasons []string
+}
+
+func (e *MessageRejectedError) Error() string {
+	return "Message rejected by moderation: " + strings.Join(e.Reasons, "; ")
+}
+
+var (
+	moderatorMu sync.RWMutex
+	moderator   moderation.Moderator
+)
+
+// ConfigureModeration sets the moderator new messages pass through. Until it
+// is called, or after it is called with nil, every message is allowed.
+func ConfigureModeration(m moderation.Moderator) {
+	moderatorMu.Lock()
+	defer moderatorMu.Unlock()
+	moderator = m
+}
+
+// moderate runs content through the configured moderator.
+func moderate(ctx context.Context, content string) (moderation.Verdict, error) {
+	moderatorMu.RLock()
+	m := moderator
+	moderatorMu.RUnlock()
+	if m == nil {
+		return moderation.Verdict{Action: moderation.Allow, Content: content}, nil
+	}
+	return m.Moderate(ctx, content)
+}
+
+// ModerationQueue returns a page of the messages held for review, oldest
+// first, starting after the message with ID afterID (zero for the first
+// page). A limit of zero or less uses DefaultMessageLimit; limits are capped
+// at MaxMessageLimit.
+func ModerationQueue(ctx context.Context, limit, afterID int) ([]queries.HeldMessage, error) {
+	return queries.GetModerationQueue(ctx, messageLimit(limit), afterID)
+}
+
+// ApproveMessage makes a held message visible, recording the approval in the
+// audit log as made by the actor attached to ctx. The message.created event
+// is published now rather than when the message was written, and the message
+// is published to NewMessages. Returns queries.ErrHeldMessageNotFound if the
+// message is not held.
+func ApproveMessage(ctx context.Context, messageID int) (queries.GetMessagesQueryRow, error) {
+	var message queries.GetMessagesQueryRow
+	err := queries.WithTx(ctx, func(tx queries.Queries) error {
+		held, err := tx.GetHeldMessage(ctx, messageID)
+		if err != nil {
+			return err
+		}
+		message, err = tx.ApproveMessage(ctx, messageID)
+		if err != nil {
+			return err
+		}
+		// The message is counted from now on, but its created_at is older.
+		if err := tx.TouchUsers(ctx, []int{message.UserID}); err != nil {
+			return err
+		}
+
+		// Mentions and tags were linked when the message was written.
+		event := newMessageEventData(message)
+		mentions, err := tx.GetMentions(ctx, []int{messageID})
+		if err != nil {
+			return err
+		}
+		for _, mention := range mentions {
+			event.MentionedUserIDs = append(event.MentionedUserIDs, mention.UserID)
+		}
+		tags, err := tx.GetTags(ctx, []int{messageID})
+		if err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			event.Tags = append(event.Tags, tag.Tag)
+		}
+
+		err = recordAudit(ctx, tx, AuditMessageApprove, "message", messageID,
+			newAuditedMessage(held.GetMessagesQueryRow), newAuditedMessage(message))
+		if err != nil {
+			return err
+		}
+		return publishEvent(ctx, tx, EventMessageCreated, event)
+	})
+	if err != nil {
+		return queries.GetMessagesQueryRow{}, err
+	}
+
+	// The author's message count changed.
+	invalidateUser(message.UserID)
+	NewMessages.Publish(message)
+	return message, nil
+}
+
+// RejectMessage deletes a held message, recording the rejection and the
+// deleted message in the audit log as made by the actor attached to ctx.
+// Returns queries.ErrHeldMessageNotFound if the message is not held.
+func RejectMessage(ctx context.Context, messageID int) error {
+	var held queries.HeldMessage
+	err := queries.WithTx(ctx, func(tx queries.Queries) error {
+		var err error
+		held, err = tx.GetHeldMessage(ctx, messageID)
+		if err != nil {
+			return err
+		}
+		if err := tx.DeleteMessage(ctx, messageID); err != nil {
+			return err
+		}
+		if err := tx.TouchUsers(ctx, []int{held.UserID}); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, AuditMessageReject, "message", messageID, newAuditedMessage(held.GetMessagesQueryRow), nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The author's message count changed.
+	invalidateUser(held.UserID)
+	return nil
+}
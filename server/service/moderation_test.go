@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"main/moderation"
+	"main/queries"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModerateWithoutModerator(t *testing.T) {
+	verdict, err := moderate(context.Background(), "anything goes")
+	assert.NoError(t, err)
+	assert.Equal(t, moderation.Verdict{Action: moderation.Allow, Content: "anything goes"}, verdict)
+}
+
+func TestCreateMessageRejected(t *testing.T) {
+	ConfigureModeration(moderation.Chain{
+		moderation.NewWordList([]string{"darn"}, moderation.Mask),
+		moderation.NewWordList([]string{"scam"}, moderation.Reject),
+	})
+	t.Cleanup(func() { ConfigureModeration(nil) })
+
+	// Rejected messages are refused before anything is stored.
+	_, err := CreateMessage(context.Background(), queries.CreateMessageParams{UserID: 1, Content: "Darn, a scam"})
+	var rejectedErr *MessageRejectedError
+	assert.ErrorAs(t, err, &rejectedErr)
+	assert.Equal(t, []string{"blocked words: darn", "blocked words: scam"}, rejectedErr.Reasons)
+	assert.EqualError(t, err, "Message rejected by moderation: blocked words: darn; blocked words: scam")
+}
+
+type failingModerator struct{}
+
+func (failingModerator) Moderate(ctx context.Context, content string) (moderation.Verdict, error) {
+	return moderation.Verdict{}, errors.New("classifier down")
+}
+
+func TestCreateMessageModerationFailure(t *testing.T) {
+	ConfigureModeration(failingModerator{})
+	t.Cleanup(func() { ConfigureModeration(nil) })
+
+	_, err := CreateMessage(context.Background(), queries.CreateMessageParams{UserID: 1, Content: "hi"})
+	assert.EqualError(t, err, "classifier down")
+}
+
+func TestNewAuditedMessage(t *testing.T) {
+	row := queries.GetMessagesQueryRow{UserID: 2, Content: "hi", Held: true}
+	row.ParentID.Int32, row.ParentID.Valid = 7, true
+
+	parentID := 7
+	assert.Equal(t, auditedMessage{UserID: 2, Content: "hi", ParentID: &parentID, Held: true}, newAuditedMessage(row))
+
+	// Approving a held message records only the change to held.
+	approved := row
+	approved.Held = false
+	before, after, err := auditDiff(newAuditedMessage(row), newAuditedMessage(approved))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"held":true}`, string(before))
+	assert.JSONEq(t, `{}`, string(after))
+}
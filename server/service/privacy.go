@@ -0,0 +1,382 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"main/queries"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// ErasureConfirmationTTL is how long an erasure request waits for confirmation.
+	ErasureConfirmationTTL = 15 * time.Minute
+
+	// RedactedContent replaces the content of messages redacted by an erasure.
+	RedactedContent = "[redacted]"
+)
+
+// MessagePolicies are the valid values for an erasure's message policy:
+// redact keeps a user's messages with their content replaced, delete removes them.
+var MessagePolicies = []string{queries.MessagePolicyRedact, queries.MessagePolicyDelete}
+
+// defaultMessagePolicy applies to erasure requests that do not name a policy.
+var defaultMessagePolicy = queries.MessagePolicyRedact
+
+// ConfigureErasure sets the message policy of erasure requests that do not name one.
+func ConfigureErasure(messagePolicy string) error {
+	if !slices.Contains(MessagePolicies, messagePolicy) {
+		return invalid("Invalid message policy. Must be one of: " + strings.Join(MessagePolicies, ", "))
+	}
+	defaultMessagePolicy = messagePolicy
+	return nil
+}
+
+// PendingErasure is an erasure request awaiting confirmation. Token is only
+// available here; the database keeps its hash.
+type PendingErasure struct {
+	Request      queries.ErasureRequest
+	Token        string
+	MessageCount int32
+}
+
+// ErasureResult describes a completed erasure.
+type ErasureResult struct {
+	User          queries.GetUsersQueryRow
+	MessagePolicy string
+	// Messages is the number of messages redacted or deleted.
+	Messages int
+	// ArchivedMessages is the number of archived messages redacted or deleted.
+	ArchivedMessages int
+	ErasedAt         time.Time
+}
+
+// auditedErasure is the audited representation of an erasure request or
+// erasure. It deliberately holds no personal data.
+type auditedErasure struct {
+	MessagePolicy    string `json:"message_policy"`
+	Messages         *int   `json:"messages,omitempty"`
+	ArchivedMessages *int   `json:"archived_messages,omitempty"`
+}
+
+// RequestErasure starts erasing a user by recording an erasure request with
+// the given message policy (empty for the default). Nothing is erased until
+// ConfirmErasure is called with the returned token within
+// ErasureConfirmationTTL. Returns queries.ErrUserNotFound if there is no such user.
+func RequestErasure(ctx context.Context, userID int, messagePolicy string) (PendingErasure, error) {
+	if messagePolicy == "" {
+		messagePolicy = defaultMessagePolicy
+	}
+	if !slices.Contains(MessagePolicies, messagePolicy) {
+		return PendingErasure{}, invalid("Invalid message policy. Must be one of: " + strings.Join(MessagePolicies, ", "))
+	}
+
+	pending := PendingErasure{Token: newSecret()}
+	err := queries.WithTx(ctx, func(tx queries.Queries) error {
+		user, err := tx.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		pending.MessageCount = user.MessageCount
+
+		params := queries.CreateErasureRequestParams{
+			UserID:        userID,
+			MessagePolicy: messagePolicy,
+			TokenHash:     hashToken(pending.Token),
+			TTL:           ErasureConfirmationTTL,
+		}
+		if actor := ActorFrom(ctx); actor.UserID != 0 {
+			params.RequestedBy = &actor.UserID
+		}
+		pending.Request, err = tx.CreateErasureRequest(ctx, params)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, AuditUserEraseRequest, "user", userID, nil, auditedErasure{MessagePolicy: messagePolicy})
+	})
+	if err != nil {
+		return PendingErasure{}, err
+	}
+	return pending, nil
+}
+
+// ConfirmErasure erases a user given the token of a pending erasure request.
+// In one transaction it:
+//   - replaces the user's username, email and nickname with placeholders and
+//     removes their avatar, whose blobs are deleted after the transaction;
+//   - redacts or deletes their messages, live and archived, according to the
+//     request's policy, detaching the attachments of redacted messages so the
+//     purge_attachments job deletes them;
+//   - clears the snapshots in audit events on them and their messages;
+//   - reduces the payloads of outbox events about them to IDs;
+//   - records a user.erase audit event and publishes a user.erased event.
+func ConfirmErasure(ctx context.Context, userID int, token string) (ErasureResult, error) {
+	if token == "" {
+		return ErasureResult{}, invalid("Confirmation token is required")
+	}
+
+	var (
+		result ErasureResult
+		avatar pgtype.Text
+	)
+	err := queries.WithTx(ctx, func(tx queries.Queries) error {
+		request, err := tx.ConfirmErasureRequest(ctx, userID, hashToken(token))
+		if errors.Is(err, queries.ErrErasureRequestNotFound) {
+			return invalid("Invalid or expired confirmation token")
+		}
+		if err != nil {
+			return err
+		}
+		result = ErasureResult{MessagePolicy: request.MessagePolicy, ErasedAt: request.ConfirmedAt.Time}
+
+		// Audit events on messages are found through the messages, so redact them first.
+		if err := tx.RedactUserAuditEvents(ctx, userID); err != nil {
+			return err
+		}
+		if err := tx.RedactUserOutboxEvents(ctx, userID); err != nil {
+			return err
+		}
+		if request.MessagePolicy == queries.MessagePolicyDelete {
+			result.Messages, err = tx.DeleteUserMessages(ctx, userID)
+			if err == nil {
+				result.ArchivedMessages, err = tx.DeleteUserArchivedMessages(ctx, userID)
+			}
+		} else {
+			result.Messages, err = tx.RedactUserMessages(ctx, userID, RedactedContent)
+			if err == nil {
+				err = tx.DetachUserAttachments(ctx, userID)
+			}
+			if err == nil {
+				result.ArchivedMessages, err = tx.RedactUserArchivedMessages(ctx, userID, RedactedContent)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		before, err := tx.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		avatar = before.AvatarKey
+		result.User, err = tx.AnonymizeUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		err = recordAudit(ctx, tx, AuditUserErase, "user", userID, nil, auditedErasure{
+			MessagePolicy:    request.MessagePolicy,
+			Messages:         &result.Messages,
+			ArchivedMessages: &result.ArchivedMessages,
+		})
+		if err != nil {
+			return err
+		}
+		return publishEvent(ctx, tx, EventUserErased, UserErasedEventData{ID: userID, MessagePolicy: request.MessagePolicy})
+	})
+	if err != nil {
+		return ErasureResult{}, err
+	}
+
+	invalidateUser(userID)
+	if avatar.Valid {
+		deleteAvatar(ctx, avatar.String)
+	}
+	return result, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// dataExportAuditEvent is the representation of an audit event in a data export.
+type dataExportAuditEvent struct {
+	ID         int64           `json:"id"`
+	ActorID    *int32          `json:"actor_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   int             `json:"target_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	IP         *string         `json:"ip"`
+	RequestID  *string         `json:"request_id"`
+	CreatedAt  string          `json:"created_at"`
+}
+
+func newDataExportAuditEvent(row queries.AuditEvent) dataExportAuditEvent {
+	event := dataExportAuditEvent{
+		ID:         row.ID,
+		Action:     row.Action,
+		TargetType: row.TargetType,
+		TargetID:   row.TargetID,
+		Before:     row.Before,
+		After:      row.After,
+		CreatedAt:  row.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if row.ActorID.Valid {
+		event.ActorID = &row.ActorID.Int32
+	}
+	if row.IP.Valid {
+		event.IP = &row.IP.String
+	}
+	if row.RequestID.Valid {
+		event.RequestID = &row.RequestID.String
+	}
+	return event
+}
+
+// dataExportArchivedMessage is the representation of an archived message in
+// a data export.
+type dataExportArchivedMessage struct {
+	ID             int     `json:"id"`
+	Content        string  `json:"content"`
+	CreatedAt      string  `json:"created_at"`
+	ExpiresAt      *string `json:"expires_at,omitempty"`
+	ParentID       *int32  `json:"parent_id,omitempty"`
+	ConversationID int     `json:"conversation_id"`
+	Held           bool    `json:"held,omitempty"`
+	ArchivedAt     string  `json:"archived_at"`
+}
+
+func newDataExportArchivedMessage(row queries.ArchivedMessage) dataExportArchivedMessage {
+	message := dataExportArchivedMessage{
+		ID:             row.ID,
+		Content:        row.Content,
+		CreatedAt:      row.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		ConversationID: row.ConversationID,
+		Held:           row.Held,
+		ArchivedAt:     row.ArchivedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if row.ExpiresAt.Valid {
+		expiresAt := row.ExpiresAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		message.ExpiresAt = &expiresAt
+	}
+	if row.ParentID.Valid {
+		message.ParentID = &row.ParentID.Int32
+	}
+	return message
+}
+
+// ExportUserData writes a zip archive of everything held about a user to the
+// writer returned by open, which is only called once the user is known to
+// exist. The archive holds user.json (the profile), messages.json,
+// archived_messages.json (messages moved out by retention policies) and
+// audit.json (events made by the user or on them and their messages), read
+// from a single snapshot. The export itself is audited as user.data_export.
+// Returns queries.ErrUserNotFound if there is no such user.
+func ExportUserData(ctx context.Context, userID int, open func() io.Writer) error {
+	err := queries.WithTx(ctx, func(tx queries.Queries) error {
+		if _, err := tx.GetUser(ctx, userID); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, AuditUserDataExport, "user", userID, nil, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	return queries.WithTx(ctx, func(tx queries.Queries) error {
+		user, err := tx.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		archive := zip.NewWriter(open())
+		profile, err := archive.Create("user.json")
+		if err != nil {
+			return err
+		}
+		if err := json.NewEncoder(profile).Encode(newUserEventData(user)); err != nil {
+			return err
+		}
+
+		messages, err := newJSONArrayWriter(archive, "messages.json")
+		if err != nil {
+			return err
+		}
+		err = tx.ExportMessages(ctx, queries.MessageExportFilter{UserID: userID}, func(row queries.ExportedMessage) error {
+			return messages.write(newMessageEventData(row.GetMessagesQueryRow))
+		})
+		if err != nil {
+			return err
+		}
+		if err := messages.close(); err != nil {
+			return err
+		}
+
+		archived, err := newJSONArrayWriter(archive, "archived_messages.json")
+		if err != nil {
+			return err
+		}
+		err = tx.ExportUserArchivedMessages(ctx, userID, func(row queries.ArchivedMessage) error {
+			return archived.write(newDataExportArchivedMessage(row))
+		})
+		if err != nil {
+			return err
+		}
+		if err := archived.close(); err != nil {
+			return err
+		}
+
+		events, err := newJSONArrayWriter(archive, "audit.json")
+		if err != nil {
+			return err
+		}
+		err = tx.ExportUserAuditEvents(ctx, userID, func(row queries.AuditEvent) error {
+			return events.write(newDataExportAuditEvent(row))
+		})
+		if err != nil {
+			return err
+		}
+		if err := events.close(); err != nil {
+			return err
+		}
+
+		return archive.Close()
+	}, queries.WithIsolation(pgx.RepeatableRead), queries.WithReadOnly(), queries.WithMaxRetries(0))
+}
+
+// jsonArrayWriter streams values into a zip archive entry as a JSON array,
+// one element per line.
+type jsonArrayWriter struct {
+	w io.Writer
+	n int
+}
+
+func newJSONArrayWriter(archive *zip.Writer, name string) (*jsonArrayWriter, error) {
+	w, err := archive.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.WriteString(w, "[")
+	return &jsonArrayWriter{w: w}, err
+}
+
+func (a *jsonArrayWriter) write(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if a.n == 0 {
+		sep = "\n"
+	}
+	a.n++
+	_, err = io.WriteString(a.w, sep+string(b))
+	return err
+}
+
+func (a *jsonArrayWriter) close() error {
+	_, err := io.WriteString(a.w, "\n]\n")
+	return err
+}
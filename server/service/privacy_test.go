@@ -0,0 +1,108 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"main/queries"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErasureValidation(t *testing.T) {
+	_, err := RequestErasure(context.Background(), 1, "shred")
+	assert.EqualError(t, err, "Invalid message policy. Must be one of: redact, delete")
+
+	_, err = ConfirmErasure(context.Background(), 1, "")
+	assert.EqualError(t, err, "Confirmation token is required")
+}
+
+func TestConfigureErasure(t *testing.T) {
+	defer func() { defaultMessagePolicy = queries.MessagePolicyRedact }()
+
+	assert.Error(t, ConfigureErasure("shred"))
+	assert.Equal(t, queries.MessagePolicyRedact, defaultMessagePolicy)
+
+	assert.NoError(t, ConfigureErasure(queries.MessagePolicyDelete))
+	assert.Equal(t, queries.MessagePolicyDelete, defaultMessagePolicy)
+}
+
+func TestHashToken(t *testing.T) {
+	assert.Len(t, hashToken("token"), 64)
+	assert.Equal(t, hashToken("token"), hashToken("token"))
+	assert.NotEqual(t, hashToken("token"), hashToken("other"))
+}
+
+func TestJSONArrayWriter(t *testing.T) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	empty, err := newJSONArrayWriter(archive, "empty.json")
+	assert.NoError(t, err)
+	assert.NoError(t, empty.close())
+
+	items, err := newJSONArrayWriter(archive, "items.json")
+	assert.NoError(t, err)
+	assert.NoError(t, items.write(map[string]int{"a": 1}))
+	assert.NoError(t, items.write(map[string]int{"b": 2}))
+	assert.NoError(t, items.close())
+	assert.NoError(t, archive.Close())
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	contents := map[string]string{}
+	for _, f := range reader.File {
+		rc, _ := f.Open()
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[f.Name] = string(b)
+	}
+	assert.JSONEq(t, `[]`, contents["empty.json"])
+	assert.JSONEq(t, `[{"a":1},{"b":2}]`, contents["items.json"])
+}
+
+func TestNewDataExportAuditEvent(t *testing.T) {
+	event := newDataExportAuditEvent(queries.AuditEvent{
+		ID:         5,
+		ActorID:    pgtype.Int4{Int32: 2, Valid: true},
+		Action:     AuditUserUpdate,
+		TargetType: "user",
+		TargetID:   2,
+		After:      json.RawMessage(`{"nickname":"Jo"}`),
+		CreatedAt:  pgtype.Timestamptz{Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true},
+	})
+
+	b, err := json.Marshal(event)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"id": 5, "actor_id": 2, "action": "user.update", "target_type": "user", "target_id": 2,
+		"before": null, "after": {"nickname": "Jo"}, "ip": null, "request_id": null,
+		"created_at": "2025-01-02T03:04:05Z"
+	}`, string(b))
+}
+
+func TestNewDataExportArchivedMessage(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	message := newDataExportArchivedMessage(queries.ArchivedMessage{
+		ID:             7,
+		UserID:         2,
+		Content:        "old news",
+		CreatedAt:      pgtype.Timestamptz{Time: created, Valid: true},
+		ParentID:       pgtype.Int4{Int32: 3, Valid: true},
+		ConversationID: 1,
+		ArchivedAt:     pgtype.Timestamptz{Time: created.AddDate(1, 0, 0), Valid: true},
+	})
+
+	b, err := json.Marshal(message)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"id": 7, "content": "old news", "created_at": "2024-01-02T03:04:05Z",
+		"parent_id": 3, "conversation_id": 1, "archived_at": "2025-01-02T03:04:05Z"
+	}`, string(b))
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"main/queries"
+)
+
+// MaxEmojiBytes limits the length of a reaction's emoji, which is enough for
+// multi-codepoint sequences such as flags and skin-tone variants.
+const MaxEmojiBytes = 64
+
+// validateEmoji checks that emoji is a short token without spaces or control
+// characters. Any such token is accepted, so clients may also use shortcodes.
+func validateEmoji(emoji string) error {
+	if emoji == "" {
+		return invalid("Emoji is required")
+	}
+	if len(emoji) > MaxEmojiBytes {
+		return invalid("Emoji is limited to " + strconv.Itoa(MaxEmojiBytes) + " bytes")
+	}
+	if strings.ContainsFunc(emoji, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) {
+		return invalid("Emoji must not contain spaces or control characters")
+	}
+	return nil
+}
+
+// AddReaction records the reaction of the actor attached to ctx to a message,
+// auditing it if it is new, and returns the message's updated reaction
+// summaries. Reacting twice with the same emoji is not an error; added
+// reports whether the reaction is new. Returns queries.ErrMessageNotFound if
+// there is no such message.
+func AddReaction(ctx context.Context, messageID int, emoji string) (summaries []queries.ReactionSummary, added bool, err error) {
+	params, err := reactionParams(ctx, messageID, emoji)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = queries.WithTx(ctx, func(tx queries.Queries) error {
+		var err error
+		added, err = tx.AddReaction(ctx, params)
+		if err != nil || !added {
+			return err
+		}
+		return recordAudit(ctx, tx, AuditReactionAdd, "message", messageID, nil, auditedReaction{Emoji: emoji})
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	summaries, err = queries.GetReactionSummaries(ctx, []int{messageID}, params.UserID)
+	return summaries, added, err
+}
+
+// RemoveReaction deletes the reaction of the actor attached to ctx to a
+// message, recording it in the audit log. Returns queries.ErrReactionNotFound if they had not reacted with emoji.
+func RemoveReaction(ctx context.Context, messageID int, emoji string) error {
+	params, err := reactionParams(ctx, messageID, emoji)
+	if err != nil {
+		return err
+	}
+	return queries.WithTx(ctx, func(tx queries.Queries) error {
+		if err := tx.RemoveReaction(ctx, params); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, AuditReactionRemove, "message", messageID, auditedReaction{Emoji: emoji}, nil)
+	})
+}
+
+func reactionParams(ctx context.Context, messageID int, emoji string) (queries.ReactionParams, error) {
+	actor := ActorFrom(ctx)
+	if actor.UserID == 0 {
+		return queries.ReactionParams{}, invalid("Reactions require an acting user")
+	}
+	if err := validateEmoji(emoji); err != nil {
+		return queries.ReactionParams{}, err
+	}
+	return queries.ReactionParams{MessageID: messageID, UserID: actor.UserID, Emoji: emoji}, nil
+}
+
+// MessageReactions returns the reaction summaries of the given messages,
+// keyed by message ID, read in one query. ReactedByViewer refers to the
+// actor attached to ctx, and is false for every summary if there is none.
+func MessageReactions(ctx context.Context, messageIDs []int) (map[int][]queries.ReactionSummary, error) {
+	reactions := map[int][]queries.ReactionSummary{}
+	if len(messageIDs) == 0 {
+		return reactions, nil
+	}
+
+	summaries, err := queries.GetReactionSummaries(ctx, messageIDs, ActorFrom(ctx).UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, summary := range summaries {
+		reactions[summary.MessageID] = append(reactions[summary.MessageID], summary)
+	}
+	return reactions, nil
+}
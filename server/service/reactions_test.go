@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"main/queries"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateEmoji(t *testing.T) {
+	for _, emoji := range []string{"👍", "🇳🇿", "👩🏽‍💻", ":tada:"} {
+		assert.NoError(t, validateEmoji(emoji), emoji)
+	}
+
+	assert.EqualError(t, validateEmoji(""), "Emoji is required")
+	assert.EqualError(t, validateEmoji(strings.Repeat("👍", 17)), "Emoji is limited to 64 bytes")
+	assert.EqualError(t, validateEmoji("thumbs up"), "Emoji must not contain spaces or control characters")
+	assert.EqualError(t, validateEmoji("👍\n"), "Emoji must not contain spaces or control characters")
+}
+
+func TestReactionParams(t *testing.T) {
+	_, err := reactionParams(context.Background(), 1, "👍")
+	assert.EqualError(t, err, "Reactions require an acting user")
+
+	ctx := WithActor(context.Background(), Actor{UserID: 3})
+	_, err = reactionParams(ctx, 1, "")
+	assert.EqualError(t, err, "Emoji is required")
+
+	params, err := reactionParams(ctx, 1, "👍")
+	assert.NoError(t, err)
+	assert.Equal(t, queries.ReactionParams{MessageID: 1, UserID: 3, Emoji: "👍"}, params)
+}
+
+func TestMessageReactionsEmpty(t *testing.T) {
+	reactions, err := MessageReactions(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, reactions)
+}
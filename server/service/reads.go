@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+
+	"main/queries"
+)
+
+// ReadState is a user's read marker in a conversation with the number of
+// messages there after it.
+type ReadState struct {
+	Marker      queries.ReadMarker
+	UnreadCount int
+}
+
+// UnreadCounts is the number of messages a user has not read, in total and
+// in each conversation with any.
+type UnreadCounts struct {
+	Total         int
+	Conversations []queries.ConversationUnreadCount
+}
+
+// MarkRead marks every message in a conversation up to and including
+// messageID as read by the actor attached to ctx. Read markers only move
+// forward, so marking an older message read changes nothing. Returns
+// queries.ErrConversationNotFound or queries.ErrMessageNotFound if there is
+// no such conversation or message.
+func MarkRead(ctx context.Context, conversationID, messageID int) (ReadState, error) {
+	actor := ActorFrom(ctx)
+	if actor.UserID == 0 {
+		return ReadState{}, invalid("Marking messages read requires an acting user")
+	}
+	if messageID <= 0 {
+		return ReadState{}, invalid("Invalid message ID")
+	}
+
+	var state ReadState
+	err := queries.WithTx(ctx, func(tx queries.Queries) error {
+		if _, err := tx.GetConversation(ctx, conversationID); err != nil {
+			return err
+		}
+		message, err := tx.GetMessage(ctx, messageID)
+		if err != nil {
+			return err
+		}
+		if message.ConversationID != conversationID {
+			return invalid("Message is in a different conversation")
+		}
+
+		state.Marker, err = tx.MarkRead(ctx, actor.UserID, conversationID, messageID)
+		if err != nil {
+			return err
+		}
+		state.UnreadCount, err = tx.GetUnreadCount(ctx, actor.UserID, conversationID)
+		return err
+	})
+	if err != nil {
+		return ReadState{}, err
+	}
+	return state, nil
+}
+
+// GetUnreadCounts returns the number of messages by other users that userID
+// has not read, in total and per conversation.
+func GetUnreadCounts(ctx context.Context, userID int) (UnreadCounts, error) {
+	conversations, err := queries.GetUnreadCounts(ctx, userID)
+	if err != nil {
+		return UnreadCounts{}, err
+	}
+	counts := UnreadCounts{Conversations: conversations}
+	for _, c := range conversations {
+		counts.Total += c.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"main/moderation"
+	"main/queries"
+)
+
+// requireDatabase skips tests that need the database when there isn't one.
+func requireDatabase(t *testing.T) {
+	if os.Getenv("DB_CONNECTION_STRING") == "" {
+		t.Skip("DB_CONNECTION_STRING not set")
+	}
+}
+
+func TestMarkReadValidation(t *testing.T) {
+	_, err := MarkRead(context.Background(), 1, 1)
+	assert.EqualError(t, err, "Marking messages read requires an acting user")
+
+	_, err = MarkRead(WithActor(context.Background(), Actor{UserID: 1}), 1, 0)
+	assert.EqualError(t, err, "Invalid message ID")
+}
+
+func TestApprovedMessageBehindMarkerIsUnread(t *testing.T) {
+	requireDatabase(t)
+	ctx := context.Background()
+	ConfigureModeration(moderation.Chain{moderation.NewWordList([]string{"holdme"}, moderation.Hold)})
+	t.Cleanup(func() { ConfigureModeration(nil) })
+
+	suffix := time.Now().UnixNano()
+	newUser := func(name string) queries.GetUsersQueryRow {
+		user, err := CreateUser(ctx, queries.CreateUserParams{
+			Username: fmt.Sprintf("%s%d", name, suffix),
+			Email:    fmt.Sprintf("%s%d@example.com", name, suffix),
+			UserType: "UTYPE_USER",
+		})
+		require.NoError(t, err)
+		return user
+	}
+	author, reader := newUser("author"), newUser("reader")
+	conversation, err := CreateConversation(ctx, fmt.Sprintf("unread %d", suffix))
+	require.NoError(t, err)
+
+	post := func(content string) queries.GetMessagesQueryRow {
+		message, err := CreateMessage(ctx, queries.CreateMessageParams{UserID: author.ID, Content: content, ConversationID: conversation.ID})
+		require.NoError(t, err)
+		return message
+	}
+	held, visible := post("holdme"), post("hello")
+	require.True(t, held.Held)
+
+	readerCtx := WithActor(ctx, Actor{UserID: reader.ID})
+	state, err := MarkRead(readerCtx, conversation.ID, visible.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, state.UnreadCount)
+
+	_, err = ApproveMessage(ctx, held.ID)
+	require.NoError(t, err)
+	state, err = MarkRead(readerCtx, conversation.ID, visible.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, state.UnreadCount, "approved message behind the marker")
+
+	state, err = MarkRead(readerCtx, conversation.ID, held.ID)
+	require.NoError(t, err)
+	assert.Equal(t, visible.ID, state.Marker.LastReadMessageID)
+	assert.Equal(t, 0, state.UnreadCount)
+}
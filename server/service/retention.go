@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+
+	"main/queries"
+)
+
+const (
+	// RetentionDefaultScope names the global default retention policy, which
+	// applies to users whose type has no policy of its own.
+	RetentionDefaultScope = "default"
+
+	// RetentionConversationScopePrefix is followed by a conversation ID to
+	// name the retention policy for messages in that conversation.
+	RetentionConversationScopePrefix = "conversation:"
+
+	// retentionBatchSize is how many messages the sweeper removes per statement.
+	retentionBatchSize = 1000
+)
+
+// RetentionActions are the valid values for a retention policy's action.
+var RetentionActions = []string{queries.RetentionPurge, queries.RetentionArchive}
+
+// RetentionPolicy is a retention policy as set through SetRetentionPolicy.
+type RetentionPolicy struct {
+	// Scope is RetentionDefaultScope, a user type or
+	// RetentionConversationScopePrefix followed by a conversation ID.
+	Scope      string
+	MaxAgeDays int
+	Action     string
+}
+
+// SweepResult counts the messages removed by SweepMessages.
+type SweepResult struct {
+	// Expired is the number of messages deleted because their expires_at passed.
+	Expired int
+	// Purged and Archived are the numbers of messages deleted and archived by retention policies.
+	Purged   int
+	Archived int
+}
+
+// Total is the number of messages removed from public.messages.
+func (r SweepResult) Total() int {
+	return r.Expired + r.Purged + r.Archived
+}
+
+// ListRetentionPolicies returns every retention policy: the global default
+// first, then the user type policies, then the conversation policies.
+func ListRetentionPolicies(ctx context.Context) ([]queries.RetentionPolicy, error) {
+	return queries.GetRetentionPolicies(ctx)
+}
+
+// SetRetentionPolicy creates or replaces the retention policy for
+// policy.Scope, recording the change in the audit log. Returns
+// queries.ErrConversationNotFound if the scope names a missing conversation.
+func SetRetentionPolicy(ctx context.Context, policy RetentionPolicy) (queries.RetentionPolicy, error) {
+	userType, conversationID, err := parseRetentionScope(policy.Scope)
+	if err != nil {
+		return queries.RetentionPolicy{}, err
+	}
+	if policy.MaxAgeDays <= 0 {
+		return queries.RetentionPolicy{}, invalid("max_age_days must be positive")
+	}
+	if !slices.Contains(RetentionActions, policy.Action) {
+		return queries.RetentionPolicy{}, invalid("Invalid action. Must be one of: " + strings.Join(RetentionActions, ", "))
+	}
+
+	var saved queries.RetentionPolicy
+	err = queries.WithTx(ctx, func(tx queries.Queries) error {
+		if conversationID != nil {
+			if _, err := tx.GetConversation(ctx, *conversationID); err != nil {
+				return err
+			}
+		}
+		var err error
+		saved, err = tx.SetRetentionPolicy(ctx, queries.SetRetentionPolicyParams{
+			UserType:       userType,
+			ConversationID: conversationID,
+			MaxAgeDays:     policy.MaxAgeDays,
+			Action:         policy.Action,
+		})
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, AuditRetentionPolicySet, "retention_policy", saved.ID, nil, policy)
+	})
+	if err != nil {
+		return queries.RetentionPolicy{}, err
+	}
+	return saved, nil
+}
+
+// DeleteRetentionPolicy removes the retention policy for scope, recording it
+// in the audit log. Returns queries.ErrRetentionPolicyNotFound if there is none.
+func DeleteRetentionPolicy(ctx context.Context, scope string) error {
+	userType, conversationID, err := parseRetentionScope(scope)
+	if err != nil {
+		return err
+	}
+
+	return queries.WithTx(ctx, func(tx queries.Queries) error {
+		if err := tx.DeleteRetentionPolicy(ctx, userType, conversationID); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, AuditRetentionPolicyDelete, "retention_policy", 0, RetentionPolicy{Scope: scope}, nil)
+	})
+}
+
+// RetentionScope returns the scope naming policy, as accepted by
+// SetRetentionPolicy and DeleteRetentionPolicy.
+func RetentionScope(policy queries.RetentionPolicy) string {
+	switch {
+	case policy.ConversationID.Valid:
+		return RetentionConversationScopePrefix + strconv.Itoa(int(policy.ConversationID.Int32))
+	case policy.UserType.Valid:
+		return policy.UserType.String
+	default:
+		return RetentionDefaultScope
+	}
+}
+
+// parseRetentionScope returns the user type or the conversation ID a
+// retention policy scope names, or neither for the global default.
+func parseRetentionScope(scope string) (*string, *int, error) {
+	if scope == RetentionDefaultScope {
+		return nil, nil, nil
+	}
+	if id, ok := strings.CutPrefix(scope, RetentionConversationScopePrefix); ok {
+		conversationID, err := strconv.Atoi(id)
+		if err != nil || conversationID <= 0 {
+			return nil, nil, invalid("Invalid conversation ID in scope")
+		}
+		return nil, &conversationID, nil
+	}
+	if !slices.Contains(UserTypes, scope) {
+		return nil, nil, invalid("Invalid scope. Must be " + RetentionDefaultScope + ", " + RetentionConversationScopePrefix + "<id> or one of: " + strings.Join(UserTypes, ", "))
+	}
+	return &scope, nil, nil
+}
+
+// SweepMessages deletes expired messages, then purges or archives messages
+// that have outlived their retention policy, in batches of retentionBatchSize
+// so no transaction holds many locks. Each batch is recorded in the audit log
+// in the same transaction. It stops at the first error, returning the counts
+// so far. The cached entries of the authors of removed messages are
+// invalidated, as their message counts changed.
+func SweepMessages(ctx context.Context) (SweepResult, error) {
+	var result SweepResult
+	var authors []int
+	defer func() {
+		if len(authors) > 0 {
+			invalidateUsers(authors)
+		}
+	}()
+
+	sweeps := []struct {
+		count  *int
+		action string
+		sweep  func(tx queries.Queries) ([]queries.RemovedMessage, error)
+	}{
+		{&result.Expired, AuditMessageExpire, func(tx queries.Queries) ([]queries.RemovedMessage, error) {
+			return tx.DeleteExpiredMessages(ctx, retentionBatchSize)
+		}},
+		{&result.Purged, AuditMessagePurge, func(tx queries.Queries) ([]queries.RemovedMessage, error) {
+			return tx.ApplyRetentionPolicies(ctx, queries.RetentionPurge, retentionBatchSize)
+		}},
+		{&result.Archived, AuditMessageArchive, func(tx queries.Queries) ([]queries.RemovedMessage, error) {
+			return tx.ApplyRetentionPolicies(ctx, queries.RetentionArchive, retentionBatchSize)
+		}},
+	}
+	for _, s := range sweeps {
+		for {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+			var removed []queries.RemovedMessage
+			err := queries.WithTx(ctx, func(tx queries.Queries) error {
+				var err error
+				removed, err = s.sweep(tx)
+				if err != nil || len(removed) == 0 {
+					return err
+				}
+				sweep := auditedSweep{MessageIDs: make([]int, len(removed))}
+				for i, message := range removed {
+					sweep.MessageIDs[i] = message.ID
+				}
+				return recordAudit(ctx, tx, s.action, "message", 0, nil, sweep)
+			})
+			if err != nil {
+				return result, err
+			}
+			*s.count += len(removed)
+			for _, message := range removed {
+				authors = append(authors, message.UserID)
+			}
+			if len(removed) < retentionBatchSize {
+				break
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"main/queries"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetRetentionPolicyValidation(t *testing.T) {
+	cases := map[string]struct {
+		policy  RetentionPolicy
+		message string
+	}{
+		"scope":        {RetentionPolicy{Scope: "UTYPE_GUEST", MaxAgeDays: 30, Action: "purge"}, "Invalid scope. Must be default, conversation:<id> or one of: UTYPE_USER, UTYPE_ADMIN, UTYPE_MODERATOR"},
+		"conversation": {RetentionPolicy{Scope: "conversation:0", MaxAgeDays: 30, Action: "purge"}, "Invalid conversation ID in scope"},
+		"max age":      {RetentionPolicy{Scope: RetentionDefaultScope, MaxAgeDays: 0, Action: "purge"}, "max_age_days must be positive"},
+		"action":       {RetentionPolicy{Scope: "UTYPE_USER", MaxAgeDays: 30, Action: "shred"}, "Invalid action. Must be one of: purge, archive"},
+	}
+	for name, tc := range cases {
+		_, err := SetRetentionPolicy(context.Background(), tc.policy)
+		assert.EqualError(t, err, tc.message, name)
+	}
+
+	assert.EqualError(t, DeleteRetentionPolicy(context.Background(), "everyone"), "Invalid scope. Must be default, conversation:<id> or one of: UTYPE_USER, UTYPE_ADMIN, UTYPE_MODERATOR")
+}
+
+func TestParseRetentionScope(t *testing.T) {
+	userType, conversationID, err := parseRetentionScope(RetentionDefaultScope)
+	assert.NoError(t, err)
+	assert.Nil(t, userType)
+	assert.Nil(t, conversationID)
+
+	userType, conversationID, err = parseRetentionScope("UTYPE_MODERATOR")
+	assert.NoError(t, err)
+	assert.Equal(t, "UTYPE_MODERATOR", *userType)
+	assert.Nil(t, conversationID)
+
+	userType, conversationID, err = parseRetentionScope("conversation:7")
+	assert.NoError(t, err)
+	assert.Nil(t, userType)
+	assert.Equal(t, 7, *conversationID)
+
+	_, _, err = parseRetentionScope("conversation:x")
+	assert.EqualError(t, err, "Invalid conversation ID in scope")
+}
+
+func TestRetentionScope(t *testing.T) {
+	assert.Equal(t, "default", RetentionScope(queries.RetentionPolicy{}))
+	assert.Equal(t, "UTYPE_USER", RetentionScope(queries.RetentionPolicy{UserType: pgtype.Text{String: "UTYPE_USER", Valid: true}}))
+	assert.Equal(t, "conversation:3", RetentionScope(queries.RetentionPolicy{ConversationID: pgtype.Int4{Int32: 3, Valid: true}}))
+}
+
+func TestSweepResultTotal(t *testing.T) {
+	assert.Equal(t, 6, SweepResult{Expired: 1, Purged: 2, Archived: 3}.Total())
+}
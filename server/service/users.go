@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"main/queries"
+)
+
+const (
+	DefaultSearchLimit = 10
+	MaxSearchLimit     = 25
+)
+
+// UserTypes are the valid values for a user's type.
+var UserTypes = []string{"UTYPE_USER", "UTYPE_ADMIN", "UTYPE_MODERATOR"}
+
+// ListUsers returns all users with their message counts, from the user cache when possible.
+func ListUsers(ctx context.Context) ([]queries.GetUsersQueryRow, error) {
+	return cachedUsers(ctx, func() ([]queries.GetUsersQueryRow, error) {
+		return queries.GetUsers(ctx)
+	})
+}
+
+// SearchUsers returns users matching term, best matches first.
+// A limit of zero or less uses DefaultSearchLimit; limits are capped at MaxSearchLimit.
+func SearchUsers(ctx context.Context, term string, limit int) ([]queries.GetUsersQueryRow, error) {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		return nil, invalid("Search query is required")
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	return queries.SearchUsers(ctx, term, min(limit, MaxSearchLimit))
+}
+
+// GetUser returns a single user, or queries.ErrUserNotFound, from the user cache when possible.
+func GetUser(ctx context.Context, userID int) (queries.GetUsersQueryRow, error) {
+	return cachedUser(ctx, userID, func() (queries.GetUsersQueryRow, error) {
+		return queries.GetUser(ctx, userID)
+	})
+}
+
+// CreateUser validates and inserts a new user, recording it in the audit log
+// as made by the actor attached to ctx and publishing a user.created event.
+func CreateUser(ctx context.Context, params queries.CreateUserParams) (queries.GetUsersQueryRow, error) {
+	if err := validateNewUser(params); err != nil {
+		return queries.GetUsersQueryRow{}, err
+	}
+
+	var user queries.GetUsersQueryRow
+	err := queries.WithTx(ctx, func(tx queries.Queries) error {
+		var err error
+		user, err = createUser(ctx, tx, params)
+		return err
+	})
+	if err != nil {
+		return queries.GetUsersQueryRow{}, err
+	}
+
+	invalidateUser(0)
+	return user, nil
+}
+
+func validateNewUser(params queries.CreateUserParams) error {
+	if params.Username == "" {
+		return invalid("Username is required")
+	}
+	if params.Email == "" {
+		return invalid("Email is required")
+	}
+	if params.UserType == "" {
+		return invalid("User type is required")
+	}
+	return nil
+}
+
+// createUser inserts a validated user using tx, with its audit record and event.
+func createUser(ctx context.Context, tx queries.Queries, params queries.CreateUserParams) (queries.GetUsersQueryRow, error) {
+	user, err := tx.CreateUser(ctx, params)
+	if err != nil {
+		return queries.GetUsersQueryRow{}, err
+	}
+	if err := recordAudit(ctx, tx, AuditUserCreate, "user", user.ID, nil, newAuditedUser(user)); err != nil {
+		return queries.GetUsersQueryRow{}, err
+	}
+	if err := publishEvent(ctx, tx, EventUserCreated, newUserEventData(user)); err != nil {
+		return queries.GetUsersQueryRow{}, err
+	}
+	return user, nil
+}
+
+// UpdateUser validates and applies a partial update to a user, recording the
+// changed fields in the audit log as made by the actor attached to ctx and
+// publishing a user.updated event.
+func UpdateUser(ctx context.Context, userID int, params queries.UpdateUserParams) (queries.GetUsersQueryRow, error) {
+	if params.UserType != nil && !slices.Contains(UserTypes, *params.UserType) {
+		return queries.GetUsersQueryRow{}, invalid("Invalid user type. Must be one of: " + strings.Join(UserTypes, ", "))
+	}
+
+	var user queries.GetUsersQueryRow
+	err := queries.WithTx(ctx, func(tx queries.Queries) error {
+		before, err := tx.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		user, err = tx.UpdateUser(ctx, userID, params)
+		if err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, tx, AuditUserUpdate, "user", userID, newAuditedUser(before), newAuditedUser(user)); err != nil {
+			return err
+		}
+		return publishEvent(ctx, tx, EventUserUpdated, newUserEventData(user))
+	})
+	if err != nil {
+		return queries.GetUsersQueryRow{}, err
+	}
+
+	invalidateUser(userID)
+	return user, nil
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"main/queries"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateUserValidation(t *testing.T) {
+	cases := map[string]queries.CreateUserParams{
+		"Username is required":  {Email: "a@example.com", UserType: "UTYPE_USER"},
+		"Email is required":     {Username: "a", UserType: "UTYPE_USER"},
+		"User type is required": {Username: "a", Email: "a@example.com"},
+	}
+	for message, params := range cases {
+		_, err := CreateUser(context.Background(), params)
+		assert.EqualError(t, err, message)
+	}
+}
+
+func TestUpdateUserValidation(t *testing.T) {
+	userType := "UTYPE_ROOT"
+	_, err := UpdateUser(context.Background(), 1, queries.UpdateUserParams{UserType: &userType})
+
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "Invalid user type. Must be one of: UTYPE_USER, UTYPE_ADMIN, UTYPE_MODERATOR", validationErr.Message)
+}
+
+func TestSearchUsersValidation(t *testing.T) {
+	_, err := SearchUsers(context.Background(), " ", 5)
+	assert.EqualError(t, err, "Search query is required")
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"main/queries"
+	"main/webhooks"
+)
+
+const (
+	DefaultDeliveryLimit = 50
+	MaxDeliveryLimit     = 200
+
+	// EventWebhookTest is the type of sample deliveries sent by TestWebhook.
+	EventWebhookTest = "webhook.test"
+
+	minSecretLength = 16
+)
+
+// testDispatcher sends test deliveries immediately, outside the background poll.
+var testDispatcher = webhooks.NewDispatcher()
+
+// CreateWebhook validates and registers a webhook, recording it in the audit
+// log as made by the actor attached to ctx. No event types subscribes to all
+// of them; an empty secret is replaced by a random one.
+func CreateWebhook(ctx context.Context, params queries.CreateWebhookParams) (queries.Webhook, error) {
+	params.URL = strings.TrimSpace(params.URL)
+	if params.URL == "" {
+		return queries.Webhook{}, invalid("URL is required")
+	}
+	if u, err := url.Parse(params.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return queries.Webhook{}, invalid("URL must be an absolute http or https URL")
+	}
+
+	eventTypes := []string{}
+	for _, eventType := range params.EventTypes {
+		if !slices.Contains(EventTypes, eventType) {
+			return queries.Webhook{}, invalid("Unknown event type: " + eventType + ". Must be one of: " + strings.Join(EventTypes, ", "))
+		}
+		if !slices.Contains(eventTypes, eventType) {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	params.EventTypes = eventTypes
+
+	switch {
+	case params.Secret == "":
+		params.Secret = newSecret()
+	case len(params.Secret) < minSecretLength:
+		return queries.Webhook{}, invalid("Secret must be at least 16 characters")
+	}
+
+	var webhook queries.Webhook
+	err := queries.WithTx(ctx, func(tx queries.Queries) error {
+		var err error
+		webhook, err = tx.CreateWebhook(ctx, params)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, AuditWebhookCreate, "webhook", webhook.ID, nil, newAuditedWebhook(webhook))
+	})
+	return webhook, err
+}
+
+func newSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ListWebhooks returns all webhooks.
+func ListWebhooks(ctx context.Context) ([]queries.Webhook, error) {
+	return queries.GetWebhooks(ctx)
+}
+
+// DeleteWebhook removes a webhook and its deliveries, recording it in the
+// audit log, or returns queries.ErrWebhookNotFound.
+func DeleteWebhook(ctx context.Context, webhookID int) error {
+	return queries.WithTx(ctx, func(tx queries.Queries) error {
+		webhook, err := tx.DeleteWebhook(ctx, webhookID)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, AuditWebhookDelete, "webhook", webhookID, newAuditedWebhook(webhook), nil)
+	})
+}
+
+// TestWebhook sends a sample webhook.test delivery to a webhook right away
+// and returns it with the outcome of that first attempt. Like any delivery it
+// is retried later if the attempt failed.
+func TestWebhook(ctx context.Context, webhookID int) (queries.WebhookDelivery, error) {
+	payload, err := json.Marshal(map[string]any{
+		"webhook_id": webhookID,
+		"message":    "This is a test delivery.",
+	})
+	if err != nil {
+		return queries.WebhookDelivery{}, err
+	}
+
+	lease := testDispatcher.Client.Timeout + time.Minute
+	claimed, err := queries.CreateTestDelivery(ctx, webhookID, EventWebhookTest, payload, lease)
+	if err != nil {
+		return queries.WebhookDelivery{}, err
+	}
+	if err := testDispatcher.Deliver(ctx, claimed); err != nil {
+		return queries.WebhookDelivery{}, err
+	}
+
+	delivery, err := queries.GetWebhookDelivery(ctx, claimed.ID)
+	if err != nil {
+		return queries.WebhookDelivery{}, err
+	}
+	deliveries, err := withHistory(ctx, []queries.WebhookDelivery{delivery})
+	if err != nil {
+		return queries.WebhookDelivery{}, err
+	}
+	return deliveries[0], nil
+}
+
+// ListWebhookDeliveries returns a webhook's deliveries, newest first, with
+// their attempt history. A limit of zero or less uses DefaultDeliveryLimit;
+// limits are capped at MaxDeliveryLimit.
+func ListWebhookDeliveries(ctx context.Context, webhookID, limit int, beforeID int64) ([]queries.WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = DefaultDeliveryLimit
+	}
+	deliveries, err := queries.GetWebhookDeliveries(ctx, webhookID, min(limit, MaxDeliveryLimit), beforeID)
+	if err != nil {
+		return nil, err
+	}
+	return withHistory(ctx, deliveries)
+}
+
+// withHistory loads the attempts of all deliveries in one query.
+func withHistory(ctx context.Context, deliveries []queries.WebhookDelivery) ([]queries.WebhookDelivery, error) {
+	if len(deliveries) == 0 {
+		return deliveries, nil
+	}
+
+	ids := make([]int64, len(deliveries))
+	for i, delivery := range deliveries {
+		ids[i] = delivery.ID
+	}
+	attempts, err := queries.GetWebhookDeliveryAttempts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	attachHistory(deliveries, attempts)
+	return deliveries, nil
+}
+
+// attachHistory sets each delivery's History from attempts.
+func attachHistory(deliveries []queries.WebhookDelivery, attempts []queries.WebhookDeliveryAttempt) {
+	byDelivery := map[int64][]queries.WebhookDeliveryAttempt{}
+	for _, attempt := range attempts {
+		byDelivery[attempt.DeliveryID] = append(byDelivery[attempt.DeliveryID], attempt)
+	}
+	for i := range deliveries {
+		deliveries[i].History = byDelivery[deliveries[i].ID]
+	}
+}
+
+// ListDeadLetters returns webhook deliveries that ran out of attempts, newest first.
+// A limit of zero or less uses DefaultDeliveryLimit; limits are capped at MaxDeliveryLimit.
+func ListDeadLetters(ctx context.Context, limit int, beforeID int64) ([]queries.WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = DefaultDeliveryLimit
+	}
+	return queries.GetDeadWebhookDeliveries(ctx, min(limit, MaxDeliveryLimit), beforeID)
+}
+
+// RetryDeadLetter queues a dead delivery to be sent again with a fresh set of
+// attempts, recording it in the audit log, or returns
+// queries.ErrDeliveryNotFound.
+func RetryDeadLetter(ctx context.Context, deliveryID int64) (queries.WebhookDelivery, error) {
+	var delivery queries.WebhookDelivery
+	err := queries.WithTx(ctx, func(tx queries.Queries) error {
+		var err error
+		delivery, err = tx.RetryWebhookDelivery(ctx, deliveryID)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, AuditWebhookDeliveryRetry, "webhook_delivery", int(deliveryID), nil, nil)
+	})
+	return delivery, err
+}
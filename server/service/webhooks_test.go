@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"main/queries"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateWebhookValidation(t *testing.T) {
+	cases := map[string]queries.CreateWebhookParams{
+		"URL is required": {URL: "  "},
+		"URL must be an absolute http or https URL": {URL: "ftp://example.com/hook"},
+		"Unknown event type: user.deleted. Must be one of: user.created, user.updated, message.created, user.erased": {
+			URL:        "https://example.com/hook",
+			EventTypes: []string{"user.created", "user.deleted"},
+		},
+		"Secret must be at least 16 characters": {URL: "https://example.com/hook", Secret: "short"},
+	}
+	for message, params := range cases {
+		_, err := CreateWebhook(context.Background(), params)
+		assert.EqualError(t, err, message)
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	assert.Len(t, newSecret(), 64)
+	assert.NotEqual(t, newSecret(), newSecret())
+}
+
+func TestAttachHistory(t *testing.T) {
+	deliveries := []queries.WebhookDelivery{{ID: 1}, {ID: 2}}
+	attachHistory(deliveries, []queries.WebhookDeliveryAttempt{
+		{ID: 10, DeliveryID: 2, LatencyMS: 30},
+		{ID: 11, DeliveryID: 2, LatencyMS: 12},
+	})
+
+	assert.Empty(t, deliveries[0].History)
+	assert.Equal(t, []int32{30, 12}, []int32{deliveries[1].History[0].LatencyMS, deliveries[1].History[1].LatencyMS})
+}
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Local stores blobs as files under a directory. Its signed URLs point at
+// baseURL, where Local itself must be mounted as an http.Handler to serve them.
+type Local struct {
+	dir     string
+	baseURL string
+	secret  []byte
+	now     func() time.Time
+}
+
+// NewLocal returns a store keeping blobs under dir, which is created if
+// needed. Signed URLs are baseURL followed by the key, and are signed with
+// secret, which must be the same for every server sharing dir.
+func NewLocal(dir, baseURL string, secret []byte) (*Local, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("storage: local store needs a signing secret")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Local{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/"), secret: secret, now: time.Now}, nil
+}
+
+// path returns the file a key is stored in.
+func (s *Local) path(key string) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+func validKey(key string) bool {
+	return key != "" && !strings.HasPrefix(key, "/") && path.Clean(key) == key && !strings.HasPrefix(key, "../") && key != ".."
+}
+
+// Put writes the blob to a temporary file and renames it into place, so
+// readers never see a partial blob.
+func (s *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if size >= 0 && written != size {
+		return fmt.Errorf("storage: wrote %d bytes, expected %d", written, size)
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (s *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *Local) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// SignedURL returns baseURL/key with the expiry time and an HMAC-SHA256 of
+// the key and expiry as query parameters.
+func (s *Local) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	expires := strconv.FormatInt(s.now().Add(expiry).Unix(), 10)
+	query := url.Values{"expires": {expires}, "signature": {s.sign(key, expires)}}
+	return s.baseURL + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + query.Encode(), nil
+}
+
+func (s *Local) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ServeHTTP serves a blob requested through a URL from SignedURL. The
+// request path, relative to where the handler is mounted, is the key.
+// Responses:
+//   - 200: The blob, with Range support.
+//   - 403: The signature is missing, invalid or expired.
+//   - 404: The blob does not exist.
+func (s *Local) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	expires := r.URL.Query().Get("expires")
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !validKey(key) || s.now().Unix() > unix ||
+		!hmac.Equal([]byte(r.URL.Query().Get("signature")), []byte(s.sign(key, expires))) {
+		http.Error(w, "invalid or expired signature", http.StatusForbidden)
+		return
+	}
+
+	name, _ := s.path(key)
+	f, err := os.Open(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, path.Base(key), info.ModTime(), f)
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalPutGetDelete(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocal(t.TempDir(), "http://example.com/blobs", []byte("secret"))
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(ctx, "attachments/1/a.txt", strings.NewReader("hello"), 5, "text/plain"))
+	r, err := store.Get(ctx, "attachments/1/a.txt")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "hello", string(body))
+
+	assert.Error(t, store.Put(ctx, "attachments/1/b.txt", strings.NewReader("hello"), 4, "text/plain"))
+
+	require.NoError(t, store.Delete(ctx, "attachments/1/a.txt"))
+	_, err = store.Get(ctx, "attachments/1/a.txt")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, store.Delete(ctx, "attachments/1/a.txt"))
+}
+
+func TestLocalRejectsUnsafeKeys(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocal(t.TempDir(), "http://example.com/blobs", []byte("secret"))
+	require.NoError(t, err)
+
+	for _, key := range []string{"", "/etc/passwd", "../outside", "a/../../b", "a//b"} {
+		assert.Error(t, store.Put(ctx, key, strings.NewReader("x"), 1, "text/plain"), key)
+		_, err := store.SignedURL(ctx, key, time.Minute)
+		assert.Error(t, err, key)
+	}
+}
+
+func TestLocalSignedURL(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocal(t.TempDir(), "http://example.com/blobs/", []byte("secret"))
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	store.now = func() time.Time { return now }
+	require.NoError(t, store.Put(ctx, "attachments/1/a b.txt", strings.NewReader("hello"), 5, "text/plain"))
+
+	signed, err := store.SignedURL(ctx, "attachments/1/a b.txt", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(signed, "http://example.com/blobs/attachments/1/a%20b.txt?expires=1700000060&signature="), signed)
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		u, _ := url.Parse(target)
+		req := httptest.NewRequest(http.MethodGet, strings.TrimPrefix(u.RequestURI(), "/blobs"), nil)
+		w := httptest.NewRecorder()
+		store.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(signed)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hello", w.Body.String())
+
+	tampered := strings.Replace(signed, "a%20b.txt", "other.txt", 1)
+	assert.Equal(t, http.StatusForbidden, serve(tampered).Code)
+
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, http.StatusForbidden, serve(signed).Code)
+}
+
+func TestNewLocalNeedsSecret(t *testing.T) {
+	_, err := NewLocal(t.TempDir(), "http://example.com/blobs", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeS3 starts an in-process S3-compatible server and returns a store
+// using it, with a bucket created by NewS3.
+func newFakeS3(t *testing.T) *S3 {
+	t.Helper()
+	server := httptest.NewServer(gofakes3.New(s3mem.New()).Server())
+	t.Cleanup(server.Close)
+
+	store, err := NewS3(context.Background(), S3Config{
+		Endpoint:        strings.TrimPrefix(server.URL, "http://"),
+		Bucket:          "attachments",
+		Region:          "us-east-1",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+	})
+	require.NoError(t, err)
+	return store
+}
+
+func TestS3PutGetDelete(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeS3(t)
+
+	require.NoError(t, store.Put(ctx, "attachments/1/a.txt", strings.NewReader("hello"), 5, "text/plain"))
+	r, err := store.Get(ctx, "attachments/1/a.txt")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "hello", string(body))
+
+	require.NoError(t, store.Delete(ctx, "attachments/1/a.txt"))
+	_, err = store.Get(ctx, "attachments/1/a.txt")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestS3SignedURL(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeS3(t)
+	require.NoError(t, store.Put(ctx, "attachments/1/a.txt", strings.NewReader("hello"), 5, "text/plain"))
+
+	signed, err := store.SignedURL(ctx, "attachments/1/a.txt", time.Minute)
+	require.NoError(t, err)
+	assert.Contains(t, signed, "X-Amz-Signature=")
+
+	resp, err := http.Get(signed)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "hello", string(body))
+}
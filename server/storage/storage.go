@@ -0,0 +1,28 @@
+// Package storage stores blobs such as message attachments with pluggable
+// backends: a local directory (NewLocal) or an S3-compatible object store
+// (NewS3). Either can hand out signed URLs that let clients download a blob
+// for a limited time without further authorisation.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned when a blob does not exist.
+var ErrNotFound = errors.New("blob not found")
+
+// Store is a blob storage backend. Keys are slash-separated paths such as
+// "attachments/12/3f9c.png"; they must not start with a slash or contain "..".
+type Store interface {
+	// Put stores size bytes read from r under key, replacing any existing blob.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the blob stored under key, or returns ErrNotFound.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL from which the blob can be downloaded until expiry has passed.
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
@@ -0,0 +1,189 @@
+// Package webhooks delivers domain events from the outbox to registered
+// webhook URLs, signing each request and retrying failures with exponential
+// backoff until they succeed or are dead-lettered.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"main/queries"
+)
+
+// Store is the dispatcher's view of the outbox and delivery tables.
+type Store interface {
+	FanOut(ctx context.Context, limit int) (int, error)
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]queries.ClaimedWebhookDelivery, error)
+	Complete(ctx context.Context, deliveryID int64, statusCode int, latency time.Duration) error
+	Fail(ctx context.Context, params queries.FailWebhookDeliveryParams) error
+}
+
+type queriesStore struct{}
+
+func (queriesStore) FanOut(ctx context.Context, limit int) (int, error) {
+	return queries.FanOutOutboxEvents(ctx, limit)
+}
+func (queriesStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]queries.ClaimedWebhookDelivery, error) {
+	return queries.ClaimWebhookDeliveries(ctx, limit, lease)
+}
+func (queriesStore) Complete(ctx context.Context, deliveryID int64, statusCode int, latency time.Duration) error {
+	return queries.CompleteWebhookDelivery(ctx, deliveryID, statusCode, latency)
+}
+func (queriesStore) Fail(ctx context.Context, params queries.FailWebhookDeliveryParams) error {
+	return queries.FailWebhookDelivery(ctx, params)
+}
+
+// Envelope is the JSON body of every delivery.
+type Envelope struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt string          `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Dispatcher moves events from the outbox into per-webhook deliveries and sends them.
+type Dispatcher struct {
+	Store  Store
+	Client *http.Client
+	// BatchSize is how many events and deliveries are handled per poll.
+	BatchSize int
+	// MaxAttempts is how many times a delivery is tried before it is dead.
+	MaxAttempts int
+	// BaseBackoff is the delay before the first retry; it doubles per
+	// attempt up to MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Now returns the current time (defaults to time.Now).
+	Now func() time.Time
+}
+
+// NewDispatcher returns a dispatcher backed by the database with default settings.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		Store:       queriesStore{},
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		BatchSize:   50,
+		MaxAttempts: 8,
+		BaseBackoff: 10 * time.Second,
+		MaxBackoff:  time.Hour,
+		Now:         time.Now,
+	}
+}
+
+// Run polls every interval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.Poll(ctx); err != nil {
+			log.Printf("webhooks: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fans out new outbox events and sends the deliveries that are due.
+func (d *Dispatcher) Poll(ctx context.Context) error {
+	if _, err := d.Store.FanOut(ctx, d.BatchSize); err != nil {
+		return fmt.Errorf("fan out outbox events: %w", err)
+	}
+
+	// Claimed deliveries are retried after the lease if this process dies
+	// before recording the outcome, so it must outlast a full batch.
+	lease := d.Client.Timeout*time.Duration(d.BatchSize) + time.Minute
+	deliveries, err := d.Store.Claim(ctx, d.BatchSize, lease)
+	if err != nil {
+		return fmt.Errorf("claim deliveries: %w", err)
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := d.Deliver(ctx, delivery); err != nil {
+			log.Printf("webhooks: record delivery %d: %v", delivery.ID, err)
+		}
+	}
+	return nil
+}
+
+// Deliver sends a claimed delivery and records the outcome, scheduling a
+// retry or dead-lettering it on failure.
+func (d *Dispatcher) Deliver(ctx context.Context, delivery queries.ClaimedWebhookDelivery) error {
+	start := time.Now()
+	statusCode, err := d.send(ctx, delivery)
+	latency := time.Since(start)
+	if err == nil {
+		return d.Store.Complete(ctx, delivery.ID, statusCode, latency)
+	}
+
+	params := queries.FailWebhookDeliveryParams{
+		ID:         delivery.ID,
+		StatusCode: statusCode,
+		Error:      err.Error(),
+		Latency:    latency,
+	}
+	if int(delivery.Attempts) < d.MaxAttempts {
+		params.RetryAt = d.Now().Add(d.backoff(int(delivery.Attempts)))
+	}
+	return d.Store.Fail(ctx, params)
+}
+
+// send posts the signed envelope, treating any non-2xx response as a failure.
+func (d *Dispatcher) send(ctx context.Context, delivery queries.ClaimedWebhookDelivery) (int, error) {
+	body, err := json.Marshal(Envelope{
+		ID:        delivery.EventID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.EventCreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, d.Now(), body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay after the given number of attempts: BaseBackoff
+// doubled per attempt, capped at MaxBackoff, with up to 20% jitter so
+// failing endpoints are not retried in lockstep.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.BaseBackoff
+	for i := 1; i < attempts && delay < d.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, d.MaxBackoff)
+	return delay + time.Duration(rand.Int64N(int64(delay)/5+1))
+}
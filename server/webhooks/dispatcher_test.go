@@ -0,0 +1,111 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"main/queries"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStore struct {
+	claimed   []queries.ClaimedWebhookDelivery
+	completed map[int64]int
+	failed    []queries.FailWebhookDeliveryParams
+}
+
+func (s *fakeStore) FanOut(ctx context.Context, limit int) (int, error) { return 0, nil }
+func (s *fakeStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]queries.ClaimedWebhookDelivery, error) {
+	claimed := s.claimed
+	s.claimed = nil
+	return claimed, nil
+}
+func (s *fakeStore) Complete(ctx context.Context, deliveryID int64, statusCode int, latency time.Duration) error {
+	s.completed[deliveryID] = statusCode
+	return nil
+}
+func (s *fakeStore) Fail(ctx context.Context, params queries.FailWebhookDeliveryParams) error {
+	s.failed = append(s.failed, params)
+	return nil
+}
+
+func newTestDispatcher(store Store) *Dispatcher {
+	d := NewDispatcher()
+	d.Store = store
+	d.MaxAttempts = 3
+	d.Now = func() time.Time { return time.Unix(1700000000, 0) }
+	return d
+}
+
+func TestPollDeliversSignedEnvelope(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	store := &fakeStore{completed: map[int64]int{}, claimed: []queries.ClaimedWebhookDelivery{{
+		ID:             7,
+		EventID:        3,
+		Attempts:       1,
+		EventType:      "user.created",
+		Payload:        json.RawMessage(`{"id":1}`),
+		EventCreatedAt: pgtype.Timestamptz{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+		URL:            server.URL,
+		Secret:         "secret",
+	}}}
+	d := newTestDispatcher(store)
+
+	assert.NoError(t, d.Poll(context.Background()))
+	assert.Equal(t, map[int64]int{7: http.StatusNoContent}, store.completed)
+	assert.JSONEq(t, `{"id":3,"type":"user.created","created_at":"2025-01-01T00:00:00Z","data":{"id":1}}`, string(body))
+	assert.Equal(t, "user.created", got.Header.Get(EventHeader))
+	assert.Equal(t, "7", got.Header.Get(DeliveryHeader))
+	assert.True(t, Verify("secret", got.Header.Get(SignatureHeader), body, time.Minute, d.Now()))
+}
+
+func TestPollRetriesThenDeadLetters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	store := &fakeStore{completed: map[int64]int{}, claimed: []queries.ClaimedWebhookDelivery{
+		{ID: 1, Attempts: 1, URL: server.URL},
+		{ID: 2, Attempts: 3, URL: server.URL},
+	}}
+	d := newTestDispatcher(store)
+
+	assert.NoError(t, d.Poll(context.Background()))
+	assert.Empty(t, store.completed)
+	assert.Len(t, store.failed, 2)
+
+	retry := store.failed[0]
+	assert.Equal(t, http.StatusServiceUnavailable, retry.StatusCode)
+	assert.Equal(t, "unexpected status 503 Service Unavailable", retry.Error)
+	assert.False(t, retry.RetryAt.IsZero())
+
+	dead := store.failed[1]
+	assert.True(t, dead.RetryAt.IsZero(), "out of attempts")
+}
+
+func TestBackoff(t *testing.T) {
+	d := newTestDispatcher(&fakeStore{})
+	d.BaseBackoff, d.MaxBackoff = time.Second, time.Minute
+
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 10: time.Minute, 100: time.Minute} {
+		got := d.backoff(attempts)
+		assert.GreaterOrEqual(t, got, want)
+		assert.LessOrEqual(t, got, want+want/5)
+	}
+}
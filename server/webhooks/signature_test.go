@@ -0,0 +1,22 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":1}`)
+	signature := Sign("secret", now, body)
+
+	assert.Regexp(t, `^t=1700000000,v1=[0-9a-f]{64}$`, signature)
+	assert.True(t, Verify("secret", signature, body, 5*time.Minute, now.Add(time.Minute)))
+
+	assert.False(t, Verify("other", signature, body, 5*time.Minute, now), "wrong secret")
+	assert.False(t, Verify("secret", signature, []byte(`{"id":2}`), 5*time.Minute, now), "tampered body")
+	assert.False(t, Verify("secret", signature, body, 5*time.Minute, now.Add(time.Hour)), "replayed")
+	assert.False(t, Verify("secret", "v1=abc", body, 5*time.Minute, now), "malformed")
+}